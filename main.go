@@ -3,7 +3,6 @@ package main
 import (
 	"os"
 
-	"github.com/rancher/dynamiclistener/server"
 	"github.com/rancher/steve/pkg/debug"
 	stevecli "github.com/rancher/steve/pkg/server/cli"
 	"github.com/rancher/steve/pkg/version"
@@ -39,5 +38,5 @@ func run(_ *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	return s.ListenAndServe(ctx, config.HTTPSListenPort, config.HTTPListenPort, &server.ListenOpts{DisplayServerLogs: true})
+	return s.ListenAndServe(ctx, config.HTTPSListenPort, config.HTTPListenPort, config.ListenOpts())
 }
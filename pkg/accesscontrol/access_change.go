@@ -0,0 +1,340 @@
+package accesscontrol
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	rbacv1controllers "github.com/rancher/wrangler/v3/pkg/generated/controllers/rbac/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+const (
+	accessChangeHandlerName = "accesscontrol-access-change"
+	roleRefIndex            = "accesscontrol-roleref"
+
+	// accessChangePollInterval is how often the AccessSet of a user is checked for changes when the AccessSetLookup
+	// doesn't notify of them
+	accessChangePollInterval = 2 * time.Second
+)
+
+// AccessChange lists the subjects whose access may have changed after a change to a role or a binding. Service
+// accounts are listed in Users, with their user name.
+type AccessChange struct {
+	Users  []string
+	Groups []string
+}
+
+// Affects returns whether the access of the user, or of one of their groups, may have changed
+func (c AccessChange) Affects(user user.Info) bool {
+	if slices.Contains(c.Users, user.GetName()) {
+		return true
+	}
+	for _, group := range user.GetGroups() {
+		if slices.Contains(c.Groups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c AccessChange) empty() bool {
+	return len(c.Users) == 0 && len(c.Groups) == 0
+}
+
+// addSubjects adds the users, groups and service accounts of a binding
+func (c *AccessChange) addSubjects(subjects []rbacv1.Subject) {
+	for _, subject := range subjects {
+		switch {
+		case subjectIs(userKind, subject):
+			c.addUser(subject.Name)
+		case subjectIs(groupKind, subject):
+			c.addGroup(subject.Name)
+		case subjectIsServiceAccount(subject):
+			c.addUser(serviceaccount.MakeUsername(subject.Namespace, subject.Name))
+		}
+	}
+}
+
+func (c *AccessChange) addUser(name string) {
+	if !slices.Contains(c.Users, name) {
+		c.Users = append(c.Users, name)
+	}
+}
+
+func (c *AccessChange) addGroup(name string) {
+	if !slices.Contains(c.Groups, name) {
+		c.Groups = append(c.Groups, name)
+	}
+}
+
+// AccessChangeNotifier is implemented by AccessSetLookups pushing changes to the access of subjects as they happen
+type AccessChangeNotifier interface {
+	// OnAccessChange registers a callback called after every change to roles and bindings, until the context is done.
+	// Callbacks are called synchronously and must not block.
+	OnAccessChange(ctx context.Context, cb func(AccessChange))
+}
+
+// NotifyAccessChanges returns a channel receiving the new AccessSet of the user every time it changes. Changes are
+// pushed by AccessSetLookups implementing AccessChangeNotifier, and polled otherwise. The channel is closed once the
+// context is done.
+func NotifyAccessChanges(ctx context.Context, asl AccessSetLookup, user user.Info) <-chan *AccessSet {
+	as := asl.AccessFor(user)
+	result := make(chan *AccessSet)
+
+	// only one of changed and poll is set, the other one blocking forever
+	var (
+		changed chan struct{}
+		poll    <-chan time.Time
+	)
+	if notifier, ok := asl.(AccessChangeNotifier); ok {
+		changed = make(chan struct{}, 1)
+		notifier.OnAccessChange(ctx, func(change AccessChange) {
+			if !change.Affects(user) {
+				return
+			}
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+	} else {
+		ticker := time.NewTicker(accessChangePollInterval)
+		poll = ticker.C
+		go func() {
+			<-ctx.Done()
+			ticker.Stop()
+		}()
+	}
+
+	go func() {
+		defer close(result)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+			case <-poll:
+			}
+
+			newAs := asl.AccessFor(user)
+			if newAs.ID == as.ID {
+				continue
+			}
+			as = newAs
+			select {
+			case result <- as:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return result
+}
+
+// observedObject is the last seen state of a role or binding, used to find the subjects it granted access to once
+// it's updated or deleted
+type observedObject struct {
+	resourceVersion string
+	labels          map[string]string
+	subjects        []rbacv1.Subject
+}
+
+// accessChangeHandler finds the subjects affected by changes to roles and bindings
+type accessChangeHandler struct {
+	crCache  rbacv1controllers.ClusterRoleCache
+	crbCache rbacv1controllers.ClusterRoleBindingCache
+	rbCache  rbacv1controllers.RoleBindingCache
+	notify   func(AccessChange)
+
+	lock     sync.Mutex
+	observed map[string]observedObject
+}
+
+// registerAccessChangeHandler adds handlers to the RBAC controllers, calling notify with the subjects affected by every
+// change
+func registerAccessChangeHandler(ctx context.Context, rbac rbacv1controllers.Interface, notify func(AccessChange)) {
+	h := &accessChangeHandler{
+		crCache:  rbac.ClusterRole().Cache(),
+		crbCache: rbac.ClusterRoleBinding().Cache(),
+		rbCache:  rbac.RoleBinding().Cache(),
+		notify:   notify,
+		observed: map[string]observedObject{},
+	}
+	h.crbCache.AddIndexer(roleRefIndex, func(crb *rbacv1.ClusterRoleBinding) ([]string, error) {
+		return []string{roleRefKey(crb.RoleRef, "")}, nil
+	})
+	h.rbCache.AddIndexer(roleRefIndex, func(rb *rbacv1.RoleBinding) ([]string, error) {
+		return []string{roleRefKey(rb.RoleRef, rb.Namespace)}, nil
+	})
+
+	rbac.ClusterRole().OnChange(ctx, accessChangeHandlerName, h.onClusterRole)
+	rbac.Role().OnChange(ctx, accessChangeHandlerName, h.onRole)
+	rbac.ClusterRoleBinding().OnChange(ctx, accessChangeHandlerName, h.onClusterRoleBinding)
+	rbac.RoleBinding().OnChange(ctx, accessChangeHandlerName, h.onRoleBinding)
+}
+
+// roleRefKey identifies the role referenced by a binding. Roles are namespaced, ClusterRoles aren't.
+func roleRefKey(ref rbacv1.RoleRef, namespace string) string {
+	if ref.Kind == roleKind {
+		return roleKind + ":" + namespace + "/" + ref.Name
+	}
+	return ref.Kind + ":" + ref.Name
+}
+
+// observe records the state of an object, nil once deleted, and returns its previous state. changed is false if the
+// object was already seen with the same resourceVersion, as on resyncs.
+func (h *accessChangeHandler) observe(key string, obj metav1.Object, subjects []rbacv1.Subject) (previous observedObject, changed bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	previous, seen := h.observed[key]
+	if obj == nil {
+		delete(h.observed, key)
+		return previous, seen
+	}
+	if seen && previous.resourceVersion == obj.GetResourceVersion() {
+		return previous, false
+	}
+	h.observed[key] = observedObject{
+		resourceVersion: obj.GetResourceVersion(),
+		labels:          obj.GetLabels(),
+		subjects:        subjects,
+	}
+	return previous, true
+}
+
+func (h *accessChangeHandler) onClusterRole(key string, obj *rbacv1.ClusterRole) (*rbacv1.ClusterRole, error) {
+	var meta metav1.Object
+	if obj != nil {
+		meta = obj
+	}
+	previous, changed := h.observe(clusterRoleKind+"/"+key, meta, nil)
+	if !changed {
+		return obj, nil
+	}
+
+	var change AccessChange
+	h.addRoleSubjects(&change, roleRefKey(rbacv1.RoleRef{Kind: clusterRoleKind, Name: key}, ""))
+	// ClusterRoles aggregating this one, before or after the change, get its rules
+	for _, aggregated := range h.aggregatingClusterRoles(previous.labels) {
+		h.addRoleSubjects(&change, roleRefKey(rbacv1.RoleRef{Kind: clusterRoleKind, Name: aggregated}, ""))
+	}
+	if obj != nil {
+		for _, aggregated := range h.aggregatingClusterRoles(obj.Labels) {
+			h.addRoleSubjects(&change, roleRefKey(rbacv1.RoleRef{Kind: clusterRoleKind, Name: aggregated}, ""))
+		}
+	}
+	h.notifyChange(change)
+	return obj, nil
+}
+
+func (h *accessChangeHandler) onRole(key string, obj *rbacv1.Role) (*rbacv1.Role, error) {
+	var meta metav1.Object
+	if obj != nil {
+		meta = obj
+	}
+	if _, changed := h.observe(roleKind+"/"+key, meta, nil); !changed {
+		return obj, nil
+	}
+
+	var change AccessChange
+	h.addRoleSubjects(&change, roleKind+":"+key)
+	h.notifyChange(change)
+	return obj, nil
+}
+
+func (h *accessChangeHandler) onClusterRoleBinding(key string, obj *rbacv1.ClusterRoleBinding) (*rbacv1.ClusterRoleBinding, error) {
+	var (
+		meta     metav1.Object
+		subjects []rbacv1.Subject
+	)
+	if obj != nil {
+		meta, subjects = obj, obj.Subjects
+	}
+	previous, changed := h.observe("ClusterRoleBinding/"+key, meta, subjects)
+	if !changed {
+		return obj, nil
+	}
+
+	var change AccessChange
+	change.addSubjects(previous.subjects)
+	change.addSubjects(subjects)
+	h.notifyChange(change)
+	return obj, nil
+}
+
+func (h *accessChangeHandler) onRoleBinding(key string, obj *rbacv1.RoleBinding) (*rbacv1.RoleBinding, error) {
+	var (
+		meta     metav1.Object
+		subjects []rbacv1.Subject
+	)
+	if obj != nil {
+		meta, subjects = obj, obj.Subjects
+	}
+	previous, changed := h.observe("RoleBinding/"+key, meta, subjects)
+	if !changed {
+		return obj, nil
+	}
+
+	var change AccessChange
+	change.addSubjects(previous.subjects)
+	change.addSubjects(subjects)
+	h.notifyChange(change)
+	return obj, nil
+}
+
+// addRoleSubjects adds the subjects of all the bindings referencing a role
+func (h *accessChangeHandler) addRoleSubjects(change *AccessChange, roleRef string) {
+	crbs, err := h.crbCache.GetByIndex(roleRefIndex, roleRef)
+	if err == nil {
+		for _, crb := range crbs {
+			change.addSubjects(crb.Subjects)
+		}
+	}
+	rbs, err := h.rbCache.GetByIndex(roleRefIndex, roleRef)
+	if err == nil {
+		for _, rb := range rbs {
+			change.addSubjects(rb.Subjects)
+		}
+	}
+}
+
+// aggregatingClusterRoles returns the names of the ClusterRoles whose aggregation rule selects a ClusterRole with the
+// given labels
+func (h *accessChangeHandler) aggregatingClusterRoles(roleLabels map[string]string) []string {
+	if len(roleLabels) == 0 {
+		return nil
+	}
+	clusterRoles, err := h.crCache.List(labels.Everything())
+	if err != nil {
+		return nil
+	}
+
+	var result []string
+	for _, clusterRole := range clusterRoles {
+		if clusterRole.AggregationRule == nil {
+			continue
+		}
+		for _, selector := range clusterRole.AggregationRule.ClusterRoleSelectors {
+			selector, err := metav1.LabelSelectorAsSelector(&selector)
+			if err == nil && selector.Matches(labels.Set(roleLabels)) {
+				result = append(result, clusterRole.Name)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func (h *accessChangeHandler) notifyChange(change AccessChange) {
+	if !change.empty() {
+		h.notify(change)
+	}
+}
@@ -0,0 +1,235 @@
+package accesscontrol
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/sync/singleflight"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func Test_accessChangeHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	crCache := fake.NewMockNonNamespacedCacheInterface[*rbacv1.ClusterRole](ctrl)
+	crbCache := fake.NewMockNonNamespacedCacheInterface[*rbacv1.ClusterRoleBinding](ctrl)
+	rbCache := fake.NewMockCacheInterface[*rbacv1.RoleBinding](ctrl)
+
+	viewRef := rbacv1.RoleRef{APIGroup: rbacGroup, Kind: clusterRoleKind, Name: "view"}
+	secretsRef := rbacv1.RoleRef{APIGroup: rbacGroup, Kind: roleKind, Name: "secrets"}
+	crbCache.EXPECT().GetByIndex(roleRefIndex, gomock.Any()).DoAndReturn(func(_, key string) ([]*rbacv1.ClusterRoleBinding, error) {
+		if key == "ClusterRole:view" {
+			return []*rbacv1.ClusterRoleBinding{
+				makeCRB("viewers", viewRef, []rbacv1.Subject{{APIGroup: rbacGroup, Kind: groupKind, Name: "auditors"}}),
+			}, nil
+		}
+		return nil, nil
+	}).AnyTimes()
+	rbCache.EXPECT().GetByIndex(roleRefIndex, gomock.Any()).DoAndReturn(func(_, key string) ([]*rbacv1.RoleBinding, error) {
+		switch key {
+		case "ClusterRole:view":
+			return []*rbacv1.RoleBinding{
+				makeRB("dev", "view", viewRef, []rbacv1.Subject{{APIGroup: rbacGroup, Kind: userKind, Name: "alice"}}),
+			}, nil
+		case "Role:prod/secrets":
+			return []*rbacv1.RoleBinding{
+				makeRB("prod", "secrets", secretsRef, []rbacv1.Subject{{Kind: svcAccountKind, Name: "cleaner", Namespace: "prod"}}),
+			}, nil
+		}
+		return nil, nil
+	}).AnyTimes()
+	crCache.EXPECT().List(labels.Everything()).Return([]*rbacv1.ClusterRole{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "view"},
+			AggregationRule: &rbacv1.AggregationRule{
+				ClusterRoleSelectors: []metav1.LabelSelector{
+					{MatchLabels: map[string]string{"rbac.authorization.k8s.io/aggregate-to-view": "true"}},
+				},
+			},
+		},
+	}, nil).AnyTimes()
+
+	var changes []AccessChange
+	h := &accessChangeHandler{
+		crCache:  crCache,
+		crbCache: crbCache,
+		rbCache:  rbCache,
+		notify: func(change AccessChange) {
+			changes = append(changes, change)
+		},
+		observed: map[string]observedObject{},
+	}
+	expectChange := func(t *testing.T, want ...AccessChange) {
+		t.Helper()
+		if !reflect.DeepEqual(changes, want) {
+			t.Errorf("Unexpected changes:\ngot  %+v\nwant %+v", changes, want)
+		}
+		changes = nil
+	}
+	objectMeta := func(name, resourceVersion string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, ResourceVersion: resourceVersion}
+	}
+
+	t.Run("role", func(t *testing.T) {
+		role := &rbacv1.Role{ObjectMeta: objectMeta("secrets", "1")}
+		_, _ = h.onRole("prod/secrets", role)
+		expectChange(t, AccessChange{Users: []string{"system:serviceaccount:prod:cleaner"}})
+
+		// resync
+		_, _ = h.onRole("prod/secrets", role)
+		expectChange(t)
+
+		_, _ = h.onRole("prod/secrets", nil)
+		expectChange(t, AccessChange{Users: []string{"system:serviceaccount:prod:cleaner"}})
+	})
+	t.Run("aggregated cluster role", func(t *testing.T) {
+		clusterRole := &rbacv1.ClusterRole{ObjectMeta: objectMeta("view-widgets", "1")}
+		clusterRole.Labels = map[string]string{"rbac.authorization.k8s.io/aggregate-to-view": "true"}
+		_, _ = h.onClusterRole("view-widgets", clusterRole)
+		expectChange(t, AccessChange{Users: []string{"alice"}, Groups: []string{"auditors"}})
+
+		// no longer aggregated, the labels of the previous version still apply
+		clusterRole = &rbacv1.ClusterRole{ObjectMeta: objectMeta("view-widgets", "2")}
+		_, _ = h.onClusterRole("view-widgets", clusterRole)
+		expectChange(t, AccessChange{Users: []string{"alice"}, Groups: []string{"auditors"}})
+
+		// unbound and not aggregated
+		clusterRole = &rbacv1.ClusterRole{ObjectMeta: objectMeta("view-widgets", "3")}
+		_, _ = h.onClusterRole("view-widgets", clusterRole)
+		expectChange(t)
+	})
+	t.Run("binding", func(t *testing.T) {
+		crb := makeCRB("admins", viewRef, []rbacv1.Subject{{APIGroup: rbacGroup, Kind: userKind, Name: "alice"}})
+		crb.ResourceVersion = "1"
+		_, _ = h.onClusterRoleBinding("admins", crb)
+		expectChange(t, AccessChange{Users: []string{"alice"}})
+
+		// bob replaces alice
+		crb = makeCRB("admins", viewRef, []rbacv1.Subject{{APIGroup: rbacGroup, Kind: userKind, Name: "bob"}, {APIGroup: rbacGroup, Kind: groupKind, Name: "admins"}})
+		crb.ResourceVersion = "2"
+		_, _ = h.onClusterRoleBinding("admins", crb)
+		expectChange(t, AccessChange{Users: []string{"alice", "bob"}, Groups: []string{"admins"}})
+
+		_, _ = h.onClusterRoleBinding("admins", nil)
+		expectChange(t, AccessChange{Users: []string{"bob"}, Groups: []string{"admins"}})
+
+		rb := makeRB("dev", "view", viewRef, []rbacv1.Subject{{APIGroup: rbacGroup, Kind: groupKind, Name: "devs"}})
+		rb.ResourceVersion = "1"
+		_, _ = h.onRoleBinding("dev/view", rb)
+		expectChange(t, AccessChange{Groups: []string{"devs"}})
+	})
+}
+
+func TestAccessStore_accessChanged(t *testing.T) {
+	alice := &user.DefaultInfo{Name: "alice", Groups: []string{"devs"}}
+	bob := &user.DefaultInfo{Name: "bob"}
+	store := &AccessStore{
+		usersPolicyRules: &policyRulesMock{
+			roleRefs: map[string]subjectGrants{
+				"alice": {clusterRoleBindings: []roleRef{{roleName: "view", resourceVersion: "1"}}},
+				"bob":   {clusterRoleBindings: []roleRef{{roleName: "edit", resourceVersion: "1"}}},
+			},
+		},
+		groupsPolicyRules:   &policyRulesMock{},
+		cache:               cache.NewLRUExpireCache(10),
+		userKeys:            cache.NewLRUExpireCache(10),
+		concurrentAccessFor: new(singleflight.Group),
+		listeners:           map[int]func(AccessChange){},
+	}
+	aliceAccess := store.AccessFor(alice)
+	bobAccess := store.AccessFor(bob)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var notified []AccessChange
+	store.OnAccessChange(ctx, func(change AccessChange) {
+		notified = append(notified, change)
+	})
+
+	change := AccessChange{Groups: []string{"devs"}}
+	store.accessChanged(change)
+	if _, ok := store.cache.Get(aliceAccess.ID); ok {
+		t.Error("AccessSet of an affected user was not purged")
+	}
+	if _, ok := store.cache.Get(bobAccess.ID); !ok {
+		t.Error("AccessSet of an unaffected user was purged")
+	}
+	if !reflect.DeepEqual(notified, []AccessChange{change}) {
+		t.Errorf("Unexpected notifications: %+v", notified)
+	}
+
+	cancel()
+	if err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, time.Second, true, func(context.Context) (bool, error) {
+		store.listenersLock.RLock()
+		defer store.listenersLock.RUnlock()
+		return len(store.listeners) == 0, nil
+	}); err != nil {
+		t.Error("Listener was not removed once its context was done")
+	}
+}
+
+func TestNotifyAccessChanges(t *testing.T) {
+	alice := &user.DefaultInfo{Name: "alice", Groups: []string{"devs"}}
+	id := "1"
+	var lock sync.Mutex
+	store := &accessSetLookupFunc{
+		accessFor: func(user.Info) *AccessSet {
+			lock.Lock()
+			defer lock.Unlock()
+			return &AccessSet{ID: id}
+		},
+		AccessStore: AccessStore{listeners: map[int]func(AccessChange){}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := NotifyAccessChanges(ctx, store, alice)
+
+	lock.Lock()
+	id = "2"
+	lock.Unlock()
+	store.accessChanged(AccessChange{Users: []string{"bob"}})
+	select {
+	case as := <-changes:
+		t.Fatalf("Unexpected notification for an unaffected user: %v", as.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	store.accessChanged(AccessChange{Groups: []string{"devs"}})
+	select {
+	case as := <-changes:
+		if as.ID != "2" {
+			t.Errorf("Unexpected AccessSet: got %v, want 2", as.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Change of access was not notified")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-changes:
+		if ok {
+			t.Error("Unexpected notification")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Channel was not closed once the context was done")
+	}
+}
+
+// accessSetLookupFunc is an AccessStore notifying of changes, with a custom AccessFor
+type accessSetLookupFunc struct {
+	AccessStore
+	accessFor func(user.Info) *AccessSet
+}
+
+func (a *accessSetLookupFunc) AccessFor(user user.Info) *AccessSet {
+	return a.accessFor(user)
+}
@@ -66,3 +66,10 @@ func AccessSetFromAPIRequest(req *types.APIRequest) *AccessSet {
 	}
 	return nil
 }
+
+// IsAdmin returns whether the user of the request is allowed every verb on every resource, as with the
+// cluster-admin ClusterRole
+func IsAdmin(apiOp *types.APIRequest) bool {
+	accessSet := AccessSetFromAPIRequest(apiOp)
+	return accessSet != nil && accessSet.Grants(All, schema.GroupResource{Group: All, Resource: All}, All, All)
+}
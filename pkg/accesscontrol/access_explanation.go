@@ -0,0 +1,108 @@
+package accesscontrol
+
+import (
+	"slices"
+	"sort"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+// AccessExplainer explains the access of users, as granted by the AccessSets they are given
+type AccessExplainer interface {
+	ExplainAccess(user user.Info, verb string, gr schema.GroupResource, namespace, name string) AccessExplanation
+	WhoCan(verb string, gr schema.GroupResource, namespace, name string) []AccessGrant
+}
+
+// grantedSubjectsLookup finds the subjects granted an access by any binding
+type grantedSubjectsLookup interface {
+	grantedSubjects(verb string, gr schema.GroupResource, namespace, name string) []AccessGrant
+}
+
+// AccessExplanation is the decision for a verb on a resource, with the rules granting it
+type AccessExplanation struct {
+	Allowed bool
+	Grants  []AccessGrant
+}
+
+// AccessGrant is a rule granting access to a subject through a binding
+type AccessGrant struct {
+	// SubjectKind is User, Group or ServiceAccount. When explaining the access of a user, it is User for the user
+	// itself, including service accounts, or Group for one of their groups.
+	SubjectKind      string
+	SubjectName      string
+	SubjectNamespace string
+	BindingKind      string
+	BindingName      string
+	BindingNamespace string
+	RoleKind         string
+	RoleName         string
+	Rule             rbacv1.PolicyRule
+}
+
+// ExplainAccess returns whether the user is allowed the verb on the given resource, according to the AccessSet
+// returned by AccessFor, and every rule granting it to the user or to one of their groups
+func (l *AccessStore) ExplainAccess(user user.Info, verb string, gr schema.GroupResource, namespace, name string) AccessExplanation {
+	result := AccessExplanation{
+		Allowed: l.AccessFor(user).Grants(verb, gr, namespace, name),
+	}
+
+	groups := slices.Clone(user.GetGroups())
+	sort.Strings(groups)
+
+	info := l.userGrantsFor(user)
+	result.Grants = info.user.grantsFor(userKind, user.GetName(), verb, gr, namespace, name)
+	for i, group := range info.groups {
+		result.Grants = append(result.Grants, group.grantsFor(groupKind, groups[i], verb, gr, namespace, name)...)
+	}
+	return result
+}
+
+// grantsFor returns the rules granting the verb on the given resource to a subject, each rule being evaluated as
+// when building AccessSets
+func (b subjectGrants) grantsFor(subjectKind, subjectName, verb string, gr schema.GroupResource, namespace, name string) []AccessGrant {
+	var result []AccessGrant
+	add := func(bindingKind, bindingNamespace string, ref roleRef) {
+		for _, rule := range ref.rules {
+			if !ruleGrants(ref.kind, bindingNamespace, rule, verb, gr, namespace, name) {
+				continue
+			}
+			grant := AccessGrant{
+				SubjectKind: subjectKind,
+				SubjectName: subjectName,
+				BindingKind: bindingKind,
+				BindingName: ref.bindingName,
+				RoleKind:    ref.roleRefKind,
+				RoleName:    ref.roleName,
+				Rule:        rule,
+			}
+			if bindingNamespace != All {
+				grant.BindingNamespace = bindingNamespace
+			}
+			result = append(result, grant)
+		}
+	}
+
+	for _, binding := range b.roleBindings {
+		add("RoleBinding", binding.namespace, binding)
+	}
+	for _, binding := range b.clusterRoleBindings {
+		add("ClusterRoleBinding", All, binding)
+	}
+	return result
+}
+
+// WhoCan returns the rules granting the verb on the given resource to any user, group or service account, with the
+// bindings granting them
+func (l *AccessStore) WhoCan(verb string, gr schema.GroupResource, namespace, name string) []AccessGrant {
+	return l.bindings.grantedSubjects(verb, gr, namespace, name)
+}
+
+// ruleGrants returns whether a rule of a role granted in the given namespace, or in all namespaces, grants the verb on
+// the given resource, the rule being evaluated as when building AccessSets
+func ruleGrants(kind, bindingNamespace string, rule rbacv1.PolicyRule, verb string, gr schema.GroupResource, namespace, name string) bool {
+	accessSet := new(AccessSet)
+	addAccess(accessSet, bindingNamespace, roleRef{kind: kind, rules: []rbacv1.PolicyRule{rule}})
+	return accessSet.Grants(verb, gr, namespace, name)
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"slices"
 	"sort"
+	"sync"
 	"time"
 
+	"github.com/rancher/steve/pkg/metrics"
 	v1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/rbac/v1"
 	"golang.org/x/sync/singleflight"
 	"k8s.io/apimachinery/pkg/util/cache"
@@ -33,19 +35,38 @@ type accessStoreCache interface {
 type AccessStore struct {
 	usersPolicyRules    policyRules
 	groupsPolicyRules   policyRules
+	bindings            grantedSubjectsLookup
 	cache               accessStoreCache
 	concurrentAccessFor *singleflight.Group
+
+	// userKeys maps the users to the cache key of their last AccessSet, so it can be purged when their access changes
+	userKeys *cache.LRUExpireCache
+
+	listenersLock sync.RWMutex
+	listeners     map[int]func(AccessChange)
+	listenerID    int
+}
+
+// userKey is the cache key of the last AccessSet of a user, and the groups it was computed for
+type userKey struct {
+	key    string
+	groups []string
 }
 
-func NewAccessStore(_ context.Context, cacheResults bool, rbac v1.Interface) *AccessStore {
+func NewAccessStore(ctx context.Context, cacheResults bool, rbac v1.Interface) *AccessStore {
+	usersPolicyRules := newPolicyRuleIndex(true, rbac)
 	as := &AccessStore{
-		usersPolicyRules:    newPolicyRuleIndex(true, rbac),
+		usersPolicyRules:    usersPolicyRules,
 		groupsPolicyRules:   newPolicyRuleIndex(false, rbac),
+		bindings:            usersPolicyRules,
 		concurrentAccessFor: new(singleflight.Group),
+		listeners:           map[int]func(AccessChange){},
 	}
 	if cacheResults {
 		as.cache = cache.NewLRUExpireCache(50)
+		as.userKeys = cache.NewLRUExpireCache(1000)
 	}
+	registerAccessChangeHandler(ctx, rbac, as.accessChanged)
 	return as
 }
 
@@ -56,12 +77,17 @@ func (l *AccessStore) AccessFor(user user.Info) *AccessSet {
 	}
 
 	cacheKey := info.hash()
+	if l.userKeys != nil {
+		l.userKeys.Add(user.GetName(), userKey{key: cacheKey, groups: user.GetGroups()}, 24*time.Hour)
+	}
 
 	res, _, _ := l.concurrentAccessFor.Do(cacheKey, func() (interface{}, error) {
 		if val, ok := l.cache.Get(cacheKey); ok {
+			metrics.RecordAccessSetCacheResult(true)
 			as, _ := val.(*AccessSet)
 			return as, nil
 		}
+		metrics.RecordAccessSetCacheResult(false)
 
 		result := l.newAccessSet(info)
 		result.ID = cacheKey
@@ -72,7 +98,54 @@ func (l *AccessStore) AccessFor(user user.Info) *AccessSet {
 	return res.(*AccessSet)
 }
 
+// OnAccessChange registers a callback called with the subjects affected by every change to roles and bindings, until
+// the context is done
+func (l *AccessStore) OnAccessChange(ctx context.Context, cb func(AccessChange)) {
+	l.listenersLock.Lock()
+	id := l.listenerID
+	l.listenerID++
+	l.listeners[id] = cb
+	l.listenersLock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		l.listenersLock.Lock()
+		delete(l.listeners, id)
+		l.listenersLock.Unlock()
+	}()
+}
+
+// accessChanged purges the cached AccessSets of the affected users, which would otherwise stay in the cache until
+// they expire, and notifies the listeners
+func (l *AccessStore) accessChanged(change AccessChange) {
+	metrics.IncAccessSetInvalidations()
+	if l.userKeys != nil {
+		for _, name := range l.userKeys.Keys() {
+			val, ok := l.userKeys.Get(name)
+			if !ok {
+				continue
+			}
+			last := val.(userKey)
+			if change.Affects(&user.DefaultInfo{Name: name.(string), Groups: last.groups}) {
+				l.cache.Remove(last.key)
+				l.userKeys.Remove(name)
+			}
+		}
+	}
+
+	l.listenersLock.RLock()
+	defer l.listenersLock.RUnlock()
+	for _, cb := range l.listeners {
+		cb(change)
+	}
+}
+
 func (l *AccessStore) newAccessSet(info userGrants) *AccessSet {
+	start := time.Now()
+	defer func() {
+		metrics.RecordAccessSetComputeTime(float64(time.Since(start).Milliseconds()))
+	}()
+
 	result := info.user.toAccessSet()
 	for _, group := range info.groups {
 		result.Merge(group.toAccessSet())
@@ -81,7 +154,9 @@ func (l *AccessStore) newAccessSet(info userGrants) *AccessSet {
 }
 
 func (l *AccessStore) PurgeUserData(id string) {
-	l.cache.Remove(id)
+	if l.cache != nil {
+		l.cache.Remove(id)
+	}
 }
 
 // userGrantsFor retrieves all the access information for a user
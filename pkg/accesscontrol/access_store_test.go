@@ -1,6 +1,7 @@
 package accesscontrol
 
 import (
+	"reflect"
 	"slices"
 	"sync"
 	"testing"
@@ -339,3 +340,108 @@ func TestAccessStore_AccessFor_concurrent(t *testing.T) {
 		t.Errorf("Unexpected number of calls to cache.Set(): got %d, want %d", got, want)
 	}
 }
+
+func TestAccessStore_ExplainAccess(t *testing.T) {
+	testUser := &user.DefaultInfo{
+		Name:   "test-user",
+		Groups: []string{"users", "devs"},
+	}
+	readDeployments := rbacv1.PolicyRule{
+		Verbs:     []string{"get", "list"},
+		APIGroups: []string{appsv1.GroupName},
+		Resources: []string{"deployments"},
+	}
+	editDeployments := rbacv1.PolicyRule{
+		Verbs:     []string{"*"},
+		APIGroups: []string{appsv1.GroupName},
+		Resources: []string{"deployments"},
+	}
+	readConfigMaps := rbacv1.PolicyRule{
+		Verbs:     []string{"get"},
+		APIGroups: []string{corev1.GroupName},
+		Resources: []string{"configmaps"},
+	}
+	store := &AccessStore{
+		concurrentAccessFor: new(singleflight.Group),
+		usersPolicyRules: &policyRulesMock{
+			roleRefs: map[string]subjectGrants{
+				testUser.Name: {
+					clusterRoleBindings: []roleRef{
+						{
+							bindingName: "read-deployments", roleName: "deployment-reader", roleRefKind: clusterRoleKind,
+							kind: clusterRoleKind, rules: []rbacv1.PolicyRule{readConfigMaps, readDeployments},
+						},
+					},
+				},
+			},
+		},
+		groupsPolicyRules: &policyRulesMock{
+			roleRefs: map[string]subjectGrants{
+				"devs": {
+					roleBindings: []roleRef{
+						{
+							bindingName: "dev-edit", namespace: "dev", roleName: "edit", roleRefKind: clusterRoleKind,
+							kind: roleKind, rules: []rbacv1.PolicyRule{editDeployments},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		verb        string
+		namespace   string
+		wantAllowed bool
+		wantGrants  []AccessGrant
+	}{
+		{
+			name:        "granted to the user and a group",
+			verb:        "list",
+			namespace:   "dev",
+			wantAllowed: true,
+			wantGrants: []AccessGrant{
+				{
+					SubjectKind: userKind, SubjectName: "test-user",
+					BindingKind: "ClusterRoleBinding", BindingName: "read-deployments",
+					RoleKind: clusterRoleKind, RoleName: "deployment-reader", Rule: readDeployments,
+				},
+				{
+					SubjectKind: groupKind, SubjectName: "devs",
+					BindingKind: "RoleBinding", BindingName: "dev-edit", BindingNamespace: "dev",
+					RoleKind: clusterRoleKind, RoleName: "edit", Rule: editDeployments,
+				},
+			},
+		},
+		{
+			name:        "granted to a group in a namespace",
+			verb:        "delete",
+			namespace:   "dev",
+			wantAllowed: true,
+			wantGrants: []AccessGrant{
+				{
+					SubjectKind: groupKind, SubjectName: "devs",
+					BindingKind: "RoleBinding", BindingName: "dev-edit", BindingNamespace: "dev",
+					RoleKind: clusterRoleKind, RoleName: "edit", Rule: editDeployments,
+				},
+			},
+		},
+		{
+			name:      "not granted",
+			verb:      "delete",
+			namespace: "prod",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := store.ExplainAccess(testUser, tt.verb, appsv1.Resource("deployments"), tt.namespace, "web")
+			if got.Allowed != tt.wantAllowed {
+				t.Errorf("Unexpected decision: got %v, want %v", got.Allowed, tt.wantAllowed)
+			}
+			if !reflect.DeepEqual(got.Grants, tt.wantGrants) {
+				t.Errorf("Unexpected grants:\ngot  %+v\nwant %+v", got.Grants, tt.wantGrants)
+			}
+		})
+	}
+}
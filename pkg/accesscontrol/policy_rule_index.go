@@ -5,6 +5,7 @@ import (
 
 	rbacv1controllers "github.com/rancher/wrangler/v3/pkg/generated/controllers/rbac/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apiserver/pkg/authentication/serviceaccount"
 )
@@ -186,6 +187,8 @@ func (p *policyRuleIndex) getRoleRefs(subjectName string) subjectGrants {
 	for x, crb := range crbs {
 		rules, resourceVersion := p.getRules(All, crb.RoleRef)
 		clusterRoleBindings[x] = roleRef{
+			bindingName:     crb.Name,
+			roleRefKind:     crb.RoleRef.Kind,
 			roleName:        crb.RoleRef.Name,
 			resourceVersion: resourceVersion,
 			rules:           rules,
@@ -198,6 +201,8 @@ func (p *policyRuleIndex) getRoleRefs(subjectName string) subjectGrants {
 	for x, rb := range rbs {
 		rules, resourceVersion := p.getRules(rb.Namespace, rb.RoleRef)
 		roleBindings[x] = roleRef{
+			bindingName:     rb.Name,
+			roleRefKind:     rb.RoleRef.Kind,
 			roleName:        rb.RoleRef.Name,
 			namespace:       rb.Namespace,
 			resourceVersion: resourceVersion,
@@ -211,3 +216,61 @@ func (p *policyRuleIndex) getRoleRefs(subjectName string) subjectGrants {
 		clusterRoleBindings: clusterRoleBindings,
 	}
 }
+
+// grantedSubjects returns the rules of all the cached bindings granting the verb on the given resource, once for each
+// subject of the binding, sorted by binding
+func (p *policyRuleIndex) grantedSubjects(verb string, gr schema.GroupResource, namespace, name string) []AccessGrant {
+	var result []AccessGrant
+	add := func(bindingKind, bindingNamespace, bindingName string, roleRef rbacv1.RoleRef, subjects []rbacv1.Subject) {
+		kind := roleKind
+		if bindingNamespace == All {
+			kind = clusterRoleKind
+		}
+		rules, _ := p.getRules(bindingNamespace, roleRef)
+		for _, rule := range rules {
+			if !ruleGrants(kind, bindingNamespace, rule, verb, gr, namespace, name) {
+				continue
+			}
+			for _, subject := range subjects {
+				grant := AccessGrant{
+					SubjectKind:      subject.Kind,
+					SubjectName:      subject.Name,
+					SubjectNamespace: subject.Namespace,
+					BindingKind:      bindingKind,
+					BindingName:      bindingName,
+					RoleKind:         roleRef.Kind,
+					RoleName:         roleRef.Name,
+					Rule:             rule,
+				}
+				if bindingNamespace != All {
+					grant.BindingNamespace = bindingNamespace
+				}
+				result = append(result, grant)
+			}
+		}
+	}
+
+	crbs, err := p.crbCache.List(labels.Everything())
+	if err == nil {
+		sort.Slice(crbs, func(i, j int) bool {
+			return crbs[i].Name < crbs[j].Name
+		})
+		for _, crb := range crbs {
+			add("ClusterRoleBinding", All, crb.Name, crb.RoleRef, crb.Subjects)
+		}
+	}
+
+	rbs, err := p.rbCache.List("", labels.Everything())
+	if err == nil {
+		sort.Slice(rbs, func(i, j int) bool {
+			if rbs[i].Namespace != rbs[j].Namespace {
+				return rbs[i].Namespace < rbs[j].Namespace
+			}
+			return rbs[i].Name < rbs[j].Name
+		})
+		for _, rb := range rbs {
+			add("RoleBinding", rb.Namespace, rb.Name, rb.RoleRef, rb.Subjects)
+		}
+	}
+	return result
+}
@@ -5,8 +5,12 @@ import (
 	"slices"
 	"testing"
 
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"go.uber.org/mock/gomock"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -386,3 +390,109 @@ func Test_addResourceAccess(t *testing.T) {
 		})
 	}
 }
+
+func Test_policyRuleIndex_grantedSubjects(t *testing.T) {
+	deleteSecrets := rbacv1.PolicyRule{
+		Verbs:     []string{"delete"},
+		APIGroups: []string{""},
+		Resources: []string{"secrets"},
+	}
+	readSecrets := rbacv1.PolicyRule{
+		Verbs:     []string{"get", "list"},
+		APIGroups: []string{""},
+		Resources: []string{"secrets"},
+	}
+	editAll := rbacv1.PolicyRule{
+		Verbs:     []string{"*"},
+		APIGroups: []string{"*"},
+		Resources: []string{"*"},
+	}
+	adminRef := rbacv1.RoleRef{APIGroup: rbacGroup, Kind: clusterRoleKind, Name: "admin"}
+	secretsRef := rbacv1.RoleRef{APIGroup: rbacGroup, Kind: roleKind, Name: "secrets"}
+	readerRef := rbacv1.RoleRef{APIGroup: rbacGroup, Kind: clusterRoleKind, Name: "reader"}
+
+	ctrl := gomock.NewController(t)
+	crCache := fake.NewMockNonNamespacedCacheInterface[*rbacv1.ClusterRole](ctrl)
+	rCache := fake.NewMockCacheInterface[*rbacv1.Role](ctrl)
+	crbCache := fake.NewMockNonNamespacedCacheInterface[*rbacv1.ClusterRoleBinding](ctrl)
+	rbCache := fake.NewMockCacheInterface[*rbacv1.RoleBinding](ctrl)
+
+	crCache.EXPECT().Get("admin").Return(&rbacv1.ClusterRole{Rules: []rbacv1.PolicyRule{editAll}}, nil).AnyTimes()
+	crCache.EXPECT().Get("reader").Return(&rbacv1.ClusterRole{Rules: []rbacv1.PolicyRule{readSecrets}}, nil).AnyTimes()
+	rCache.EXPECT().Get("prod", "secrets").Return(&rbacv1.Role{Rules: []rbacv1.PolicyRule{readSecrets, deleteSecrets}}, nil).AnyTimes()
+	// the role bound in dev doesn't exist
+	rCache.EXPECT().Get("dev", "secrets").Return(nil, apierrors.NewNotFound(rbacv1.Resource("roles"), "secrets")).AnyTimes()
+	crbCache.EXPECT().List(labels.Everything()).Return([]*rbacv1.ClusterRoleBinding{
+		makeCRB("readers", readerRef, []rbacv1.Subject{{Kind: groupKind, Name: "auditors"}}),
+		makeCRB("admins", adminRef, []rbacv1.Subject{{Kind: userKind, Name: "alice"}}),
+	}, nil).AnyTimes()
+	rbCache.EXPECT().List("", labels.Everything()).Return([]*rbacv1.RoleBinding{
+		makeRB("prod", "secrets", secretsRef, []rbacv1.Subject{
+			{Kind: userKind, Name: "bob"},
+			{Kind: svcAccountKind, Name: "cleaner", Namespace: "prod"},
+		}),
+		makeRB("dev", "secrets", secretsRef, []rbacv1.Subject{{Kind: userKind, Name: "carol"}}),
+	}, nil).AnyTimes()
+
+	index := &policyRuleIndex{
+		crCache:  crCache,
+		rCache:   rCache,
+		crbCache: crbCache,
+		rbCache:  rbCache,
+	}
+
+	tests := []struct {
+		name      string
+		verb      string
+		namespace string
+		want      []AccessGrant
+	}{
+		{
+			name:      "delete in a namespace",
+			verb:      "delete",
+			namespace: "prod",
+			want: []AccessGrant{
+				{
+					SubjectKind: userKind, SubjectName: "alice",
+					BindingKind: "ClusterRoleBinding", BindingName: "admins",
+					RoleKind: clusterRoleKind, RoleName: "admin", Rule: editAll,
+				},
+				{
+					SubjectKind: userKind, SubjectName: "bob",
+					BindingKind: "RoleBinding", BindingName: "secrets", BindingNamespace: "prod",
+					RoleKind: roleKind, RoleName: "secrets", Rule: deleteSecrets,
+				},
+				{
+					SubjectKind: svcAccountKind, SubjectName: "cleaner", SubjectNamespace: "prod",
+					BindingKind: "RoleBinding", BindingName: "secrets", BindingNamespace: "prod",
+					RoleKind: roleKind, RoleName: "secrets", Rule: deleteSecrets,
+				},
+			},
+		},
+		{
+			name:      "list in another namespace",
+			verb:      "list",
+			namespace: "dev",
+			want: []AccessGrant{
+				{
+					SubjectKind: userKind, SubjectName: "alice",
+					BindingKind: "ClusterRoleBinding", BindingName: "admins",
+					RoleKind: clusterRoleKind, RoleName: "admin", Rule: editAll,
+				},
+				{
+					SubjectKind: groupKind, SubjectName: "auditors",
+					BindingKind: "ClusterRoleBinding", BindingName: "readers",
+					RoleKind: clusterRoleKind, RoleName: "reader", Rule: readSecrets,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := index.grantedSubjects(tt.verb, schema.GroupResource{Resource: "secrets"}, tt.namespace, "")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unexpected grants:\ngot  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -20,9 +20,12 @@ type subjectGrants struct {
 	clusterRoleBindings []roleRef
 }
 
-// roleRef contains information from a Role or ClusterRole
+// roleRef contains information from a Role or ClusterRole, and from the binding referencing it. kind is the kind of
+// role granted by the binding, ClusterRole for ClusterRoleBindings and Role for RoleBindings, while roleRefKind is the
+// kind of the referenced role.
 type roleRef struct {
 	namespace, roleName, resourceVersion, kind string
+	bindingName, roleRefKind                   string
 	rules                                      []rbacv1.PolicyRule
 }
 
@@ -21,6 +21,10 @@ const (
 )
 
 func ListenAndServe(ctx context.Context, url string, caCert []byte, token string, handler http.Handler) {
+	listenAndServe(ctx, url, caCert, token, handler, nil)
+}
+
+func listenAndServe(ctx context.Context, url string, caCert []byte, token string, handler http.Handler, status *Status) {
 	dialer := websocket.Dialer{
 		Proxy:            http.ProxyFromEnvironment,
 		HandshakeTimeout: HandshakeTimeOut,
@@ -46,7 +50,7 @@ func ListenAndServe(ctx context.Context, url string, caCert []byte, token string
 	headers.Add("Authorization", "Bearer "+token)
 
 	for {
-		err := serve(ctx, dialer, url, headers, handler)
+		err := serve(ctx, dialer, url, headers, handler, status)
 		if err != nil {
 			logrus.Errorf("Failed to dial steve aggregation server: %v", err)
 		}
@@ -58,7 +62,7 @@ func ListenAndServe(ctx context.Context, url string, caCert []byte, token string
 	}
 }
 
-func serve(ctx context.Context, dialer websocket.Dialer, url string, headers http.Header, handler http.Handler) error {
+func serve(ctx context.Context, dialer websocket.Dialer, url string, headers http.Header, handler http.Handler, status *Status) error {
 	url = strings.Replace(url, "http://", "ws://", 1)
 	url = strings.Replace(url, "https://", "wss://", 1)
 
@@ -93,6 +97,9 @@ func serve(ctx context.Context, dialer websocket.Dialer, url string, headers htt
 	session := remotedialer.NewClientSessionWithDialer(allowAll, conn, listener.Dial)
 	defer session.Close()
 
+	status.addConnection(1)
+	defer status.addConnection(-1)
+
 	_, err = session.Serve(ctx)
 	return err
 }
@@ -0,0 +1,44 @@
+package aggregation
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+// Status tracks the state of the aggregation tunnel. It implements healthz.HealthChecker and fails while a tunnel was
+// configured but is not connected.
+type Status struct {
+	configured atomic.Bool
+	// connections is a counter rather than a boolean since the tunnel of a previous configuration may only be closed
+	// after the new one is established
+	connections atomic.Int32
+}
+
+func (s *Status) Name() string {
+	return "aggregation"
+}
+
+func (s *Status) Check(_ *http.Request) error {
+	if s.configured.Load() && !s.Connected() {
+		return errors.New("aggregation tunnel not connected")
+	}
+	return nil
+}
+
+// Connected returns whether the aggregation tunnel is currently established
+func (s *Status) Connected() bool {
+	return s.connections.Load() > 0
+}
+
+func (s *Status) setConfigured() {
+	if s != nil {
+		s.configured.Store(true)
+	}
+}
+
+func (s *Status) addConnection(delta int32) {
+	if s != nil {
+		s.connections.Add(delta)
+	}
+}
@@ -0,0 +1,33 @@
+package aggregation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus(t *testing.T) {
+	status := &Status{}
+	assert.Equal(t, "aggregation", status.Name())
+	assert.NoError(t, status.Check(nil), "unconfigured aggregation should be ready")
+
+	status.setConfigured()
+	assert.Error(t, status.Check(nil), "configured but disconnected aggregation should not be ready")
+
+	status.addConnection(1)
+	assert.True(t, status.Connected())
+	assert.NoError(t, status.Check(nil))
+
+	// a new tunnel is established before the previous one is closed
+	status.addConnection(1)
+	status.addConnection(-1)
+	assert.NoError(t, status.Check(nil))
+
+	status.addConnection(-1)
+	assert.False(t, status.Connected())
+	assert.Error(t, status.Check(nil))
+
+	var nilStatus *Status
+	nilStatus.setConfigured()
+	nilStatus.addConnection(1)
+}
@@ -11,6 +11,11 @@ import (
 )
 
 func Watch(ctx context.Context, controller v1.SecretController, secretNamespace, secretName string, httpHandler http.Handler) {
+	WatchWithStatus(ctx, controller, secretNamespace, secretName, httpHandler, nil)
+}
+
+// WatchWithStatus is like Watch, additionally reporting the state of the aggregation tunnel to the given Status
+func WatchWithStatus(ctx context.Context, controller v1.SecretController, secretNamespace, secretName string, httpHandler http.Handler, status *Status) {
 	if secretNamespace == "" || secretName == "" {
 		return
 	}
@@ -19,6 +24,7 @@ func Watch(ctx context.Context, controller v1.SecretController, secretNamespace,
 		handler:   httpHandler,
 		namespace: secretNamespace,
 		name:      secretName,
+		status:    status,
 	}
 	controller.OnChange(ctx, "aggregation-controller", h.OnSecret)
 }
@@ -32,6 +38,7 @@ type handler struct {
 	token  string
 	ctx    context.Context
 	cancel func()
+	status *Status
 }
 
 func (h *handler) OnSecret(key string, secret *corev1.Secret) (*corev1.Secret, error) {
@@ -60,7 +67,8 @@ func (h *handler) OnSecret(key string, secret *corev1.Secret) (*corev1.Secret, e
 	}
 
 	ctx, cancel := context.WithCancel(h.ctx)
-	go ListenAndServe(ctx, url, caCert, token, h.handler)
+	h.status.setConfigured()
+	go listenAndServe(ctx, url, caCert, token, h.handler, h.status)
 
 	h.url = url
 	h.caCert = caCert
@@ -0,0 +1,339 @@
+/*
+Package audit records the requests served through the /v1 API: who made them, on which resource, with which verb,
+action or link, and their outcome. Requests and responses bodies are optionally recorded, with the data of Secrets and
+the fields of the redaction policy redacted.
+*/
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/auth"
+	"github.com/rancher/steve/pkg/redaction"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// maxBodySize is the size above which request and response bodies aren't recorded
+const maxBodySize = 64 * 1024
+
+// secretsPolicy redacts the data of Secrets, which is never recorded
+var secretsPolicy = mustPolicy([]redaction.Rule{
+	{
+		Kind: "Secret",
+		Paths: []string{
+			".data",
+			".stringData",
+			".metadata.annotations['kubectl.kubernetes.io/last-applied-configuration']",
+		},
+	},
+})
+
+func mustPolicy(rules []redaction.Rule) *redaction.Policy {
+	policy, err := redaction.NewPolicy(rules)
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}
+
+// Event is the record of a request
+type Event struct {
+	AuditID   string    `json:"auditID"`
+	Level     Level     `json:"level"`
+	Timestamp time.Time `json:"timestamp"`
+	// User is the user the request was made as, Impersonator the authenticated user impersonating them, if any
+	User         UserInfo  `json:"user"`
+	Impersonator *UserInfo `json:"impersonator,omitempty"`
+	SourceIP     string    `json:"sourceIP,omitempty"`
+	UserAgent    string    `json:"userAgent,omitempty"`
+
+	Method     string `json:"method"`
+	RequestURI string `json:"requestURI"`
+	Verb       string `json:"verb"`
+	Schema     string `json:"schema,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Action     string `json:"action,omitempty"`
+	Link       string `json:"link,omitempty"`
+
+	ResponseCode int `json:"responseCode"`
+	// LatencyMS is the time taken to serve the request in milliseconds, the duration of the connection for watches
+	LatencyMS float64 `json:"latencyMs"`
+
+	RequestObject  map[string]interface{} `json:"requestObject,omitempty"`
+	ResponseObject map[string]interface{} `json:"responseObject,omitempty"`
+}
+
+// UserInfo identifies a user in an Event
+type UserInfo struct {
+	Username string              `json:"username"`
+	UID      string              `json:"uid,omitempty"`
+	Groups   []string            `json:"groups,omitempty"`
+	Extra    map[string][]string `json:"extra,omitempty"`
+}
+
+func newUserInfo(info user.Info) UserInfo {
+	return UserInfo{
+		Username: info.GetName(),
+		UID:      info.GetUID(),
+		Groups:   info.GetGroups(),
+		Extra:    info.GetExtra(),
+	}
+}
+
+// Sink stores events. Write must not block the request being recorded.
+type Sink interface {
+	Write(event *Event)
+}
+
+// Logger records requests to a sink according to a policy
+type Logger struct {
+	policy    Policy
+	sink      Sink
+	redaction *redaction.Policy
+	maxLevel  Level
+}
+
+// NewLogger returns a Logger writing the events chosen by the policy to the sink. Bodies are redacted with the
+// redaction policy, which can be nil, in addition to the data of Secrets.
+func NewLogger(policy Policy, sink Sink, redactionPolicy *redaction.Policy) (*Logger, error) {
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	if sink == nil {
+		return nil, fmt.Errorf("audit sink is required")
+	}
+	return &Logger{
+		policy:    policy,
+		sink:      sink,
+		redaction: redactionPolicy,
+		maxLevel:  policy.maxLevel(),
+	}, nil
+}
+
+// Start begins recording a request. It returns the ResponseWriter and the request to serve it with, and a function to
+// call with the API request once it's served. The API request can be nil if the request was rejected before being
+// parsed. A nil Logger records nothing.
+func (l *Logger) Start(rw http.ResponseWriter, req *http.Request) (http.ResponseWriter, *http.Request, func(*types.APIRequest)) {
+	if l == nil || l.maxLevel == LevelNone {
+		return rw, req, func(*types.APIRequest) {}
+	}
+
+	start := time.Now()
+	recorder := &responseRecorder{ResponseWriter: rw, captureBody: l.maxLevel == LevelRequestResponse}
+	var requestBody *limitedBuffer
+	if !l.maxLevel.Less(LevelRequest) && req.Body != nil && req.Body != http.NoBody {
+		requestBody = &limitedBuffer{}
+		req.Body = &teeReadCloser{Reader: io.TeeReader(req.Body, requestBody), Closer: req.Body}
+	}
+
+	return recorder, req, func(apiOp *types.APIRequest) {
+		l.finish(start, req, recorder, requestBody, apiOp)
+	}
+}
+
+func (l *Logger) finish(start time.Time, req *http.Request, recorder *responseRecorder, requestBody *limitedBuffer, apiOp *types.APIRequest) {
+	event := &Event{
+		Timestamp:    start.UTC(),
+		SourceIP:     sourceIP(req),
+		UserAgent:    req.UserAgent(),
+		Method:       req.Method,
+		RequestURI:   req.RequestURI,
+		Verb:         verb(req, apiOp),
+		ResponseCode: recorder.statusCode(),
+		LatencyMS:    float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	ctx := req.Context()
+	var gvk schema.GroupVersionKind
+	if apiOp != nil {
+		ctx = apiOp.Context()
+		event.Schema = apiOp.Type
+		event.Namespace = apiOp.Namespace
+		event.Name = apiOp.Name
+		event.Action = apiOp.Action
+		event.Link = apiOp.Link
+		if apiOp.Schema != nil {
+			gvk = attributes.GVK(apiOp.Schema)
+		}
+	}
+
+	event.Level = l.policy.LevelFor(event.Schema, event.Verb)
+	if event.Level == LevelNone {
+		return
+	}
+	if info, ok := request.UserFrom(ctx); ok {
+		event.User = newUserInfo(info)
+	}
+	if info, ok := auth.ImpersonatorFrom(ctx); ok {
+		impersonator := newUserInfo(info)
+		event.Impersonator = &impersonator
+	}
+	if !event.Level.Less(LevelRequest) && requestBody != nil {
+		event.RequestObject = l.decode(gvk, requestBody)
+	}
+	if event.Level == LevelRequestResponse && recorder.body != nil {
+		event.ResponseObject = l.decode(gvk, recorder.body)
+	}
+
+	event.AuditID = uuid.New()
+	l.sink.Write(event)
+}
+
+// decode returns the redacted JSON object of a body, or nil if it isn't one or it was too large to be recorded
+func (l *Logger) decode(gvk schema.GroupVersionKind, body *limitedBuffer) map[string]interface{} {
+	if body.truncated || body.Len() == 0 {
+		return nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(body.Bytes(), &obj); err != nil {
+		return nil
+	}
+
+	redact := func(obj map[string]interface{}) {
+		secretsPolicy.Redact(gvk, obj)
+		l.redaction.Redact(gvk, obj)
+	}
+	// collections list the objects in data
+	if items, ok := obj["data"].([]interface{}); ok && obj["type"] == "collection" {
+		for _, item := range items {
+			if item, ok := item.(map[string]interface{}); ok {
+				redact(item)
+			}
+		}
+		return obj
+	}
+	redact(obj)
+	return obj
+}
+
+// verb returns the verb of a request: get, list, watch, create, update, patch, delete or action
+func verb(req *http.Request, apiOp *types.APIRequest) string {
+	if apiOp != nil && apiOp.Action != "" {
+		return "action"
+	}
+	switch req.Method {
+	case http.MethodGet:
+		if strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+			return "watch"
+		}
+		if apiOp != nil && apiOp.Name == "" {
+			return "list"
+		}
+		return "get"
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		return "delete"
+	}
+	return strings.ToLower(req.Method)
+}
+
+// sourceIP returns the address of the peer of the connection. Headers such as X-Forwarded-For are set by the
+// client and are not trusted, as they would let it record any address in the audit log.
+func sourceIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// limitedBuffer keeps up to maxBodySize bytes, discarding the rest
+type limitedBuffer struct {
+	bytes.Buffer
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.truncated {
+		return len(p), nil
+	}
+	if b.Len()+len(p) > maxBodySize {
+		b.truncated = true
+		b.Reset()
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// responseRecorder records the status code, and optionally the body, of a response
+type responseRecorder struct {
+	http.ResponseWriter
+	captureBody bool
+	code        int
+	body        *limitedBuffer
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	if r.captureBody {
+		if r.body == nil {
+			r.body = &limitedBuffer{}
+		}
+		r.body.Write(p)
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+func (r *responseRecorder) statusCode() int {
+	if r.code == 0 {
+		return http.StatusOK
+	}
+	return r.code
+}
+
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets watches upgrade the connection to a websocket
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer doesn't support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		r.code = http.StatusSwitchingProtocols
+		r.captureBody = false
+	}
+	return conn, rw, err
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,263 @@
+package audit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/auth"
+	"github.com/rancher/steve/pkg/redaction"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+type fakeSink struct {
+	events []*Event
+}
+
+func (f *fakeSink) Write(event *Event) {
+	f.events = append(f.events, event)
+}
+
+func TestPolicyLevelFor(t *testing.T) {
+	policy := Policy{
+		Level: LevelRequest,
+		Rules: []PolicyRule{
+			{Level: LevelNone, Resources: []string{"schema", "count"}},
+			{Level: LevelRequestResponse, Resources: []string{"secret"}, Verbs: []string{"create", "update"}},
+			{Level: LevelMetadata, Verbs: []string{"get", "list", "watch"}},
+		},
+	}
+
+	tests := []struct {
+		schemaID string
+		verb     string
+		want     Level
+	}{
+		{schemaID: "schema", verb: "list", want: LevelNone},
+		{schemaID: "count", verb: "watch", want: LevelNone},
+		{schemaID: "secret", verb: "create", want: LevelRequestResponse},
+		{schemaID: "secret", verb: "get", want: LevelMetadata},
+		{schemaID: "secret", verb: "delete", want: LevelRequest},
+		{schemaID: "pod", verb: "patch", want: LevelRequest},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, policy.LevelFor(test.schemaID, test.verb), "%s %s", test.verb, test.schemaID)
+	}
+	assert.Equal(t, LevelRequestResponse, policy.maxLevel())
+	assert.Equal(t, LevelMetadata, Policy{}.LevelFor("pod", "get"))
+}
+
+func TestNewLoggerErrors(t *testing.T) {
+	_, err := NewLogger(Policy{Level: "Everything"}, &fakeSink{}, nil)
+	assert.Error(t, err)
+	_, err = NewLogger(Policy{Rules: []PolicyRule{{Level: ""}}}, &fakeSink{}, nil)
+	assert.Error(t, err)
+	_, err = NewLogger(Policy{}, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestLogger(t *testing.T) {
+	secretSchema := &types.APISchema{
+		Schema: &schemas.Schema{
+			ID:         "secret",
+			Attributes: map[string]interface{}{"version": "v1", "kind": "Secret"},
+		},
+	}
+	configMapSchema := &types.APISchema{
+		Schema: &schemas.Schema{
+			ID:         "configmap",
+			Attributes: map[string]interface{}{"version": "v1", "kind": "ConfigMap"},
+		},
+	}
+	redactionPolicy, err := redaction.NewPolicy([]redaction.Rule{{Kind: "ConfigMap", Paths: []string{".data.token"}}})
+	require.NoError(t, err)
+	policy := Policy{
+		Rules: []PolicyRule{
+			{Level: LevelNone, Resources: []string{"schema"}},
+			{Level: LevelRequestResponse, Verbs: []string{"create", "list"}},
+		},
+	}
+
+	alice := &user.DefaultInfo{Name: "alice", Groups: []string{"devs"}}
+	admin := &user.DefaultInfo{Name: "admin"}
+
+	tests := []struct {
+		name      string
+		method    string
+		url       string
+		body      string
+		apiOp     *types.APIRequest
+		code      int
+		response  string
+		wantEvent *Event
+	}{
+		{
+			name:   "metadata",
+			method: http.MethodGet,
+			url:    "/v1/secrets/default/db",
+			apiOp:  &types.APIRequest{Type: "secret", Namespace: "default", Name: "db", Schema: secretSchema},
+			code:   http.StatusOK,
+			wantEvent: &Event{
+				Level:        LevelMetadata,
+				Verb:         "get",
+				Schema:       "secret",
+				Namespace:    "default",
+				Name:         "db",
+				ResponseCode: http.StatusOK,
+			},
+		},
+		{
+			name:   "request and response with secret data",
+			method: http.MethodPost,
+			url:    "/v1/secrets",
+			body:   `{"metadata":{"name":"db","namespace":"default"},"data":{"password":"c2VjcmV0"}}`,
+			apiOp:  &types.APIRequest{Type: "secret", Schema: secretSchema},
+			code:   http.StatusCreated,
+			response: `{"id":"default/db","type":"secret","metadata":{"name":"db","namespace":"default"},` +
+				`"data":{"password":"c2VjcmV0"}}`,
+			wantEvent: &Event{
+				Level:        LevelRequestResponse,
+				Verb:         "create",
+				Schema:       "secret",
+				ResponseCode: http.StatusCreated,
+				RequestObject: map[string]interface{}{
+					"metadata": map[string]interface{}{"name": "db", "namespace": "default"},
+					"data":     map[string]interface{}{"password": redaction.Redacted},
+				},
+				ResponseObject: map[string]interface{}{
+					"id":       "default/db",
+					"type":     "secret",
+					"metadata": map[string]interface{}{"name": "db", "namespace": "default"},
+					"data":     map[string]interface{}{"password": redaction.Redacted},
+				},
+			},
+		},
+		{
+			name:     "collection redacted by the redaction policy",
+			method:   http.MethodGet,
+			url:      "/v1/configmaps",
+			apiOp:    &types.APIRequest{Type: "configmap", Schema: configMapSchema},
+			code:     http.StatusOK,
+			response: `{"type":"collection","data":[{"id":"default/app","data":{"token":"abc","mode":"debug"}}]}`,
+			wantEvent: &Event{
+				Level:        LevelRequestResponse,
+				Verb:         "list",
+				Schema:       "configmap",
+				ResponseCode: http.StatusOK,
+				ResponseObject: map[string]interface{}{
+					"type": "collection",
+					"data": []interface{}{
+						map[string]interface{}{
+							"id":   "default/app",
+							"data": map[string]interface{}{"token": redaction.Redacted, "mode": "debug"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:   "action",
+			method: http.MethodPost,
+			url:    "/v1/cluster?action=apply",
+			body:   "not json",
+			apiOp:  &types.APIRequest{Type: "cluster", Action: "apply"},
+			code:   http.StatusOK,
+			wantEvent: &Event{
+				Level:        LevelMetadata,
+				Verb:         "action",
+				Schema:       "cluster",
+				Action:       "apply",
+				ResponseCode: http.StatusOK,
+			},
+		},
+		{
+			name:   "not parsed",
+			method: http.MethodGet,
+			url:    "/v1/pods?impersonate-user=bob",
+			code:   http.StatusForbidden,
+			wantEvent: &Event{
+				Level:        LevelMetadata,
+				Verb:         "get",
+				ResponseCode: http.StatusForbidden,
+			},
+		},
+		{
+			name:   "excluded",
+			method: http.MethodGet,
+			url:    "/v1/schemas",
+			apiOp:  &types.APIRequest{Type: "schema"},
+			code:   http.StatusOK,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sink := &fakeSink{}
+			logger, err := NewLogger(policy, sink, redactionPolicy)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(test.method, test.url, strings.NewReader(test.body))
+			req = req.WithContext(request.WithUser(req.Context(), alice))
+			rw := httptest.NewRecorder()
+
+			auditedRW, auditedReq, done := logger.Start(rw, req)
+			if test.apiOp != nil {
+				// the request is served as another user, as with impersonation
+				ctx := auth.WithImpersonator(request.WithUser(auditedReq.Context(), alice), admin)
+				test.apiOp.Request = auditedReq.WithContext(ctx)
+				_, _ = auditedReq.Body.Read(make([]byte, 1024))
+			}
+			auditedRW.WriteHeader(test.code)
+			_, _ = auditedRW.Write([]byte(test.response))
+			done(test.apiOp)
+
+			if test.wantEvent == nil {
+				assert.Empty(t, sink.events)
+				return
+			}
+			require.Len(t, sink.events, 1)
+			event := sink.events[0]
+			assert.NotEmpty(t, event.AuditID)
+			assert.Equal(t, "alice", event.User.Username)
+			assert.Equal(t, []string{"devs"}, event.User.Groups)
+			if test.apiOp != nil {
+				require.NotNil(t, event.Impersonator)
+				assert.Equal(t, "admin", event.Impersonator.Username)
+			} else {
+				assert.Nil(t, event.Impersonator)
+			}
+			assert.Equal(t, test.method, event.Method)
+			assert.Equal(t, test.url, event.RequestURI)
+			assert.Equal(t, "192.0.2.1", event.SourceIP)
+
+			want := *test.wantEvent
+			want.AuditID, want.Timestamp, want.LatencyMS = event.AuditID, event.Timestamp, event.LatencyMS
+			want.User, want.Impersonator = event.User, event.Impersonator
+			want.Method, want.RequestURI, want.SourceIP = event.Method, event.RequestURI, event.SourceIP
+			assert.Equal(t, &want, event)
+			assert.Equal(t, test.response, rw.Body.String())
+		})
+	}
+}
+
+func TestSourceIPIgnoresForwardedFor(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/pods", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 192.0.2.1")
+	assert.Equal(t, "192.0.2.1", sourceIP(req))
+}
+
+func TestNilLogger(t *testing.T) {
+	var logger *Logger
+	req := httptest.NewRequest(http.MethodGet, "/v1/pods", nil)
+	rw := httptest.NewRecorder()
+	gotRW, gotReq, done := logger.Start(rw, req)
+	assert.Equal(t, rw, gotRW)
+	assert.Equal(t, req, gotReq)
+	done(nil)
+}
@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Level is the amount of details recorded for a request
+type Level string
+
+const (
+	// LevelNone doesn't record the request
+	LevelNone Level = "None"
+	// LevelMetadata records the user, the target and the outcome of the request
+	LevelMetadata Level = "Metadata"
+	// LevelRequest also records the request body
+	LevelRequest Level = "Request"
+	// LevelRequestResponse also records the response body
+	LevelRequestResponse Level = "RequestResponse"
+)
+
+var levels = []Level{LevelNone, LevelMetadata, LevelRequest, LevelRequestResponse}
+
+// Less returns whether l records fewer details than other
+func (l Level) Less(other Level) bool {
+	return slices.Index(levels, l) < slices.Index(levels, other)
+}
+
+func (l Level) valid() bool {
+	return slices.Contains(levels, l)
+}
+
+// Policy chooses the level of the requests to record
+type Policy struct {
+	// Level applies to the requests matching no rule. It defaults to Metadata.
+	Level Level `json:"level,omitempty"`
+	// Rules are evaluated in order, the first matching rule giving the level of a request
+	Rules []PolicyRule `json:"rules,omitempty"`
+}
+
+// PolicyRule sets the level of the requests made on some schemas with some verbs
+type PolicyRule struct {
+	Level Level `json:"level"`
+	// Resources are the IDs of the schemas matched by the rule, eg. "secret" or "apps.deployment". All the schemas are
+	// matched if empty.
+	Resources []string `json:"resources,omitempty"`
+	// Verbs are the verbs matched by the rule: get, list, watch, create, update, patch, delete or action. All the verbs
+	// are matched if empty.
+	Verbs []string `json:"verbs,omitempty"`
+}
+
+// LoadPolicy reads a policy from a YAML or JSON file
+func LoadPolicy(path string) (Policy, error) {
+	var policy Policy
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return policy, err
+	}
+	if err := yaml.Unmarshal(content, &policy); err != nil {
+		return policy, fmt.Errorf("parsing audit policy from %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+func (p Policy) validate() error {
+	if p.Level != "" && !p.Level.valid() {
+		return fmt.Errorf("invalid audit level %q", p.Level)
+	}
+	for i, rule := range p.Rules {
+		if !rule.Level.valid() {
+			return fmt.Errorf("invalid audit level %q in rule %d", rule.Level, i)
+		}
+	}
+	return nil
+}
+
+// LevelFor returns the level of a request made on a schema with a verb
+func (p Policy) LevelFor(schemaID, verb string) Level {
+	for _, rule := range p.Rules {
+		if len(rule.Resources) > 0 && !slices.Contains(rule.Resources, schemaID) {
+			continue
+		}
+		if len(rule.Verbs) > 0 && !slices.Contains(rule.Verbs, verb) {
+			continue
+		}
+		return rule.Level
+	}
+	return p.defaultLevel()
+}
+
+func (p Policy) defaultLevel() Level {
+	if p.Level == "" {
+		return LevelMetadata
+	}
+	return p.Level
+}
+
+// maxLevel returns the highest level any request can be recorded at
+func (p Policy) maxLevel() Level {
+	result := p.defaultLevel()
+	for _, rule := range p.Rules {
+		if result.Less(rule.Level) {
+			result = rule.Level
+		}
+	}
+	return result
+}
@@ -0,0 +1,195 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	defaultWebhookBatchSize     = 100
+	defaultWebhookBatchInterval = time.Second
+	defaultWebhookBufferSize    = 10000
+	webhookTimeout              = 10 * time.Second
+)
+
+// FileSinkOptions configures a FileSink
+type FileSinkOptions struct {
+	// Path is the file the events are written to, one JSON object per line
+	Path string
+	// MaxSize is the size in megabytes above which the file is rotated, 100 if zero
+	MaxSize int
+	// MaxBackups is the number of rotated files to keep, all of them if zero
+	MaxBackups int
+	// MaxAge is the number of days to keep rotated files for, forever if zero
+	MaxAge int
+	// Compress compresses the rotated files with gzip
+	Compress bool
+}
+
+// FileSink writes events to a file rotated once it reaches its maximum size
+type FileSink struct {
+	lock   sync.Mutex
+	writer io.WriteCloser
+}
+
+// NewFileSink returns a FileSink for the given options
+func NewFileSink(opts FileSinkOptions) *FileSink {
+	return &FileSink{
+		writer: &lumberjack.Logger{
+			Filename:   opts.Path,
+			MaxSize:    opts.MaxSize,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAge,
+			Compress:   opts.Compress,
+		},
+	}
+}
+
+func (f *FileSink) Write(event *Event) {
+	line, err := json.Marshal(event)
+	if err != nil {
+		logrus.Errorf("failed to encode audit event: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if _, err := f.writer.Write(line); err != nil {
+		logrus.Errorf("failed to write audit event: %v", err)
+	}
+}
+
+// Close closes the file
+func (f *FileSink) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.writer.Close()
+}
+
+// WebhookSinkOptions configures a WebhookSink
+type WebhookSinkOptions struct {
+	// URL is the endpoint receiving the events, POSTed as JSON arrays
+	URL string
+	// BatchSize is the maximum number of events per request, 100 if zero
+	BatchSize int
+	// BatchInterval is the maximum time events are buffered for before being sent, one second if zero
+	BatchInterval time.Duration
+	// BufferSize is the number of events buffered while requests are in flight, 10000 if zero. Events are dropped
+	// once the buffer is full.
+	BufferSize int
+	// Client sends the requests, a client with a 10 seconds timeout if nil
+	Client *http.Client
+}
+
+// WebhookSink sends events in batches to a webhook
+type WebhookSink struct {
+	opts   WebhookSinkOptions
+	events chan *Event
+	done   chan struct{}
+}
+
+// NewWebhookSink returns a WebhookSink sending events until the context is done, after flushing the buffered events
+func NewWebhookSink(ctx context.Context, opts WebhookSinkOptions) (*WebhookSink, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("audit webhook URL is required")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultWebhookBatchSize
+	}
+	if opts.BatchInterval <= 0 {
+		opts.BatchInterval = defaultWebhookBatchInterval
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaultWebhookBufferSize
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: webhookTimeout}
+	}
+
+	w := &WebhookSink{
+		opts:   opts,
+		events: make(chan *Event, opts.BufferSize),
+		done:   make(chan struct{}),
+	}
+	go w.run(ctx)
+	return w, nil
+}
+
+func (w *WebhookSink) Write(event *Event) {
+	select {
+	case w.events <- event:
+	default:
+		logrus.Warnf("audit webhook buffer is full, dropping event %s", event.AuditID)
+	}
+}
+
+// Done returns a channel closed once the buffered events were sent after the context is done
+func (w *WebhookSink) Done() <-chan struct{} {
+	return w.done
+}
+
+func (w *WebhookSink) run(ctx context.Context) {
+	defer close(w.done)
+	ticker := time.NewTicker(w.opts.BatchInterval)
+	defer ticker.Stop()
+
+	var batch []*Event
+	flush := func() {
+		if len(batch) > 0 {
+			w.send(batch)
+			batch = nil
+		}
+	}
+	for {
+		select {
+		case event := <-w.events:
+			batch = append(batch, event)
+			if len(batch) >= w.opts.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+			for {
+				select {
+				case event := <-w.events:
+					batch = append(batch, event)
+					if len(batch) >= w.opts.BatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (w *WebhookSink) send(batch []*Event) {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		logrus.Errorf("failed to encode audit events: %v", err)
+		return
+	}
+	resp, err := w.opts.Client.Post(w.opts.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logrus.Errorf("failed to send %d audit events: %v", len(batch), err)
+		return
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		logrus.Errorf("failed to send %d audit events: webhook returned %s", len(batch), resp.Status)
+	}
+}
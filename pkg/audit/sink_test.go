@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink := NewFileSink(FileSinkOptions{Path: path})
+	sink.Write(&Event{AuditID: "1", Verb: "get"})
+	sink.Write(&Event{AuditID: "2", Verb: "delete"})
+	require.NoError(t, sink.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		ids = append(ids, event.AuditID)
+	}
+	assert.Equal(t, []string{"1", "2"}, ids)
+}
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		lock    sync.Mutex
+		batches [][]string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		var events []Event
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&events))
+		var ids []string
+		for _, event := range events {
+			ids = append(ids, event.AuditID)
+		}
+		lock.Lock()
+		batches = append(batches, ids)
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sink, err := NewWebhookSink(ctx, WebhookSinkOptions{URL: server.URL, BatchSize: 2, BatchInterval: time.Hour})
+	require.NoError(t, err)
+	for _, id := range []string{"1", "2", "3"} {
+		sink.Write(&Event{AuditID: id})
+	}
+	// the last event is sent once the context is done
+	cancel()
+	select {
+	case <-sink.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Buffered events were not sent once the context was done")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, [][]string{{"1", "2"}, {"3"}}, batches)
+}
+
+func TestNewWebhookSinkRequiresURL(t *testing.T) {
+	_, err := NewWebhookSink(context.Background(), WebhookSinkOptions{})
+	assert.Error(t, err)
+}
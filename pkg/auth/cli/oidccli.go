@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rancher/steve/pkg/auth"
+	"github.com/urfave/cli/v2"
+)
+
+type OIDCConfig struct {
+	IssuerURL         string
+	ClientID          string
+	JWKSFile          string
+	JWKSURL           string
+	CAFile            string
+	UsernameClaim     string
+	UsernamePrefix    string
+	GroupsClaim       string
+	GroupsPrefix      string
+	SigningAlgorithms cli.StringSlice
+}
+
+// Enabled returns whether OIDC authentication is configured
+func (o *OIDCConfig) Enabled() bool {
+	return o.IssuerURL != ""
+}
+
+func (o *OIDCConfig) OIDCAuthenticator() (auth.Authenticator, error) {
+	if !o.Enabled() {
+		return nil, nil
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	if o.CAFile != "" {
+		ca, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in %s", o.CAFile)
+		}
+		client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}
+	}
+
+	return auth.NewOIDCAuthenticator(auth.OIDCOptions{
+		IssuerURL:         o.IssuerURL,
+		ClientID:          o.ClientID,
+		JWKSFile:          o.JWKSFile,
+		JWKSURL:           o.JWKSURL,
+		UsernameClaim:     o.UsernameClaim,
+		UsernamePrefix:    o.UsernamePrefix,
+		GroupsClaim:       o.GroupsClaim,
+		GroupsPrefix:      o.GroupsPrefix,
+		SigningAlgorithms: o.SigningAlgorithms.Value(),
+		HTTPClient:        client,
+	})
+}
+
+func (o *OIDCConfig) OIDCMiddleware() (auth.Middleware, error) {
+	authenticator, err := o.OIDCAuthenticator()
+	if err != nil || authenticator == nil {
+		return nil, err
+	}
+	return auth.ToMiddleware(authenticator), nil
+}
+
+func OIDCFlags(config *OIDCConfig) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "oidc-issuer-url",
+			EnvVars:     []string{"OIDC_ISSUER_URL"},
+			Destination: &config.IssuerURL,
+		},
+		&cli.StringFlag{
+			Name:        "oidc-client-id",
+			EnvVars:     []string{"OIDC_CLIENT_ID"},
+			Destination: &config.ClientID,
+		},
+		&cli.StringFlag{
+			Name:        "oidc-jwks-file",
+			EnvVars:     []string{"OIDC_JWKS_FILE"},
+			Destination: &config.JWKSFile,
+		},
+		&cli.StringFlag{
+			Name:        "oidc-jwks-url",
+			EnvVars:     []string{"OIDC_JWKS_URL"},
+			Destination: &config.JWKSURL,
+		},
+		&cli.StringFlag{
+			Name:        "oidc-ca-file",
+			EnvVars:     []string{"OIDC_CA_FILE"},
+			Destination: &config.CAFile,
+		},
+		&cli.StringFlag{
+			Name:        "oidc-username-claim",
+			EnvVars:     []string{"OIDC_USERNAME_CLAIM"},
+			Value:       "sub",
+			Destination: &config.UsernameClaim,
+		},
+		&cli.StringFlag{
+			Name:        "oidc-username-prefix",
+			EnvVars:     []string{"OIDC_USERNAME_PREFIX"},
+			Destination: &config.UsernamePrefix,
+		},
+		&cli.StringFlag{
+			Name:        "oidc-groups-claim",
+			EnvVars:     []string{"OIDC_GROUPS_CLAIM"},
+			Destination: &config.GroupsClaim,
+		},
+		&cli.StringFlag{
+			Name:        "oidc-groups-prefix",
+			EnvVars:     []string{"OIDC_GROUPS_PREFIX"},
+			Destination: &config.GroupsPrefix,
+		},
+		&cli.StringSliceFlag{
+			Name:        "oidc-signing-algs",
+			EnvVars:     []string{"OIDC_SIGNING_ALGS"},
+			Destination: &config.SigningAlgorithms,
+		},
+	}
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/rancher/steve/pkg/auth"
+	"github.com/urfave/cli/v2"
+)
+
+type StaticConfig struct {
+	// ClientCAFile is the CA bundle verifying client certificates
+	ClientCAFile string
+	// TokenAuthFile is the CSV file listing static bearer tokens
+	TokenAuthFile string
+}
+
+// Authenticators returns the client certificate and token file authenticators that are configured
+func (s *StaticConfig) Authenticators(ctx context.Context) ([]auth.Authenticator, error) {
+	var result []auth.Authenticator
+	if s.ClientCAFile != "" {
+		certAuth, err := auth.NewClientCertAuthenticator(ctx, s.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, certAuth)
+	}
+	if s.TokenAuthFile != "" {
+		tokenAuth, err := auth.NewTokenFileAuthenticator(ctx, s.TokenAuthFile)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, tokenAuth)
+	}
+	return result, nil
+}
+
+func StaticFlags(config *StaticConfig) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "client-ca-file",
+			EnvVars:     []string{"CLIENT_CA_FILE"},
+			Destination: &config.ClientCAFile,
+		},
+		&cli.StringFlag{
+			Name:        "token-auth-file",
+			EnvVars:     []string{"TOKEN_AUTH_FILE"},
+			Destination: &config.TokenAuthFile,
+		},
+	}
+}
@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"context"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+type impersonatorKey struct{}
+
+// WithImpersonator returns a copy of the context recording the authenticated user making a request as another user
+func WithImpersonator(ctx context.Context, impersonator user.Info) context.Context {
+	return context.WithValue(ctx, impersonatorKey{}, impersonator)
+}
+
+// ImpersonatorFrom returns the authenticated user making a request as the user of the context, if any
+func ImpersonatorFrom(ctx context.Context) (user.Info, bool) {
+	impersonator, ok := ctx.Value(impersonatorKey{}).(user.Info)
+	return impersonator, ok
+}
@@ -0,0 +1,305 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"golang.org/x/sync/singleflight"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+const (
+	// oidcKeysMinRefreshInterval limits how often the JWKS is reloaded when a token is signed by an unknown key, or
+	// retried when it couldn't be loaded
+	oidcKeysMinRefreshInterval = 10 * time.Second
+	oidcHTTPTimeout            = 10 * time.Second
+)
+
+// OIDCOptions configures the validation of OIDC ID tokens
+type OIDCOptions struct {
+	// IssuerURL must match the iss claim of the tokens. Unless JWKSFile or JWKSURL is set, the JWKS is found with the
+	// OpenID discovery document of the issuer.
+	IssuerURL string
+	// ClientID must be one of the audiences in the aud claim of the tokens
+	ClientID string
+	// JWKSFile is the path of a file with the JWKS used to verify tokens, for offline use
+	JWKSFile string
+	// JWKSURL is the URL of the JWKS used to verify tokens
+	JWKSURL string
+	// UsernameClaim is the claim giving the user name, sub by default. If it is email, the email_verified claim must
+	// not be false.
+	UsernameClaim string
+	// UsernamePrefix is prepended to user names
+	UsernamePrefix string
+	// GroupsClaim is the claim giving the groups of the user, as a string or a list of strings. No group is mapped if
+	// empty.
+	GroupsClaim string
+	// GroupsPrefix is prepended to group names
+	GroupsPrefix string
+	// SigningAlgorithms lists the accepted signature algorithms, RS256 by default
+	SigningAlgorithms []string
+	// HTTPClient fetches the discovery document and the JWKS, a client with a 10 seconds timeout if nil
+	HTTPClient *http.Client
+}
+
+type oidcAuthenticator struct {
+	opts       OIDCOptions
+	algorithms []jose.SignatureAlgorithm
+	loadKeys   func(ctx context.Context) (*jose.JSONWebKeySet, error)
+	loadGroup  singleflight.Group
+
+	lock     sync.Mutex
+	keys     *jose.JSONWebKeySet
+	loadErr  error
+	lastLoad time.Time
+}
+
+// NewOIDCAuthenticator returns an Authenticator validating the OIDC ID tokens given as bearer tokens. Requests without
+// a bearer token, or with one that isn't a JWT, aren't authenticated, without error.
+func NewOIDCAuthenticator(opts OIDCOptions) (Authenticator, error) {
+	if opts.IssuerURL == "" {
+		return nil, fmt.Errorf("OIDC issuer URL is required")
+	}
+	if opts.ClientID == "" {
+		return nil, fmt.Errorf("OIDC client ID is required")
+	}
+	if opts.UsernameClaim == "" {
+		opts.UsernameClaim = "sub"
+	}
+	if len(opts.SigningAlgorithms) == 0 {
+		opts.SigningAlgorithms = []string{string(jose.RS256)}
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: oidcHTTPTimeout}
+	}
+
+	o := &oidcAuthenticator{opts: opts}
+	for _, alg := range opts.SigningAlgorithms {
+		o.algorithms = append(o.algorithms, jose.SignatureAlgorithm(alg))
+	}
+
+	switch {
+	case opts.JWKSFile != "":
+		o.loadKeys = o.keysFromFile
+		// a missing or invalid file is a configuration error, reported right away
+		keys, err := o.loadKeys(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		o.keys, o.lastLoad = keys, time.Now()
+	case opts.JWKSURL != "":
+		o.loadKeys = func(ctx context.Context) (*jose.JSONWebKeySet, error) {
+			return o.keysFromURL(ctx, opts.JWKSURL)
+		}
+	default:
+		o.loadKeys = o.keysFromDiscovery
+	}
+	return o, nil
+}
+
+// NewOIDCMiddleware returns a Middleware authenticating requests with OIDC ID tokens
+func NewOIDCMiddleware(opts OIDCOptions) (Middleware, error) {
+	auth, err := NewOIDCAuthenticator(opts)
+	if err != nil {
+		return nil, err
+	}
+	return ToMiddleware(auth), nil
+}
+
+type oidcClaims struct {
+	jwt.Claims
+	EmailVerified *bool `json:"email_verified,omitempty"`
+}
+
+func (o *oidcAuthenticator) Authenticate(req *http.Request) (user.Info, bool, error) {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || strings.Count(token, ".") != 2 {
+		return nil, false, nil
+	}
+	parsed, err := jwt.ParseSigned(token, o.algorithms)
+	if err != nil {
+		return nil, false, nil
+	}
+	// tokens of other issuers, such as service account tokens, are left to the other authenticators without loading
+	// the keys
+	var unverified jwt.Claims
+	if err := parsed.UnsafeClaimsWithoutVerification(&unverified); err != nil || unverified.Issuer != o.opts.IssuerURL {
+		return nil, false, nil
+	}
+
+	var keyID string
+	if len(parsed.Headers) > 0 {
+		keyID = parsed.Headers[0].KeyID
+	}
+	keys, err := o.keysFor(req.Context(), keyID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var (
+		claims    oidcClaims
+		allClaims map[string]interface{}
+	)
+	if err := parsed.Claims(keys, &claims, &allClaims); err != nil {
+		return nil, false, fmt.Errorf("verifying OIDC token: %w", err)
+	}
+	if err := claims.ValidateWithLeeway(jwt.Expected{
+		Issuer:      o.opts.IssuerURL,
+		AnyAudience: jwt.Audience{o.opts.ClientID},
+	}, jwt.DefaultLeeway); err != nil {
+		return nil, false, fmt.Errorf("validating OIDC token: %w", err)
+	}
+	if claims.Expiry == nil {
+		return nil, false, fmt.Errorf("validating OIDC token: missing exp claim")
+	}
+
+	return o.userInfo(claims, allClaims)
+}
+
+// userInfo maps the claims of a valid token to a user
+func (o *oidcAuthenticator) userInfo(claims oidcClaims, allClaims map[string]interface{}) (user.Info, bool, error) {
+	username, ok := allClaims[o.opts.UsernameClaim].(string)
+	if !ok || username == "" {
+		return nil, false, fmt.Errorf("OIDC token has no %s claim", o.opts.UsernameClaim)
+	}
+	if o.opts.UsernameClaim == "email" && claims.EmailVerified != nil && !*claims.EmailVerified {
+		return nil, false, fmt.Errorf("OIDC token email %s is not verified", username)
+	}
+
+	info := &user.DefaultInfo{
+		Name: o.opts.UsernamePrefix + username,
+		UID:  claims.Subject,
+	}
+	if o.opts.GroupsClaim != "" {
+		switch groups := allClaims[o.opts.GroupsClaim].(type) {
+		case string:
+			info.Groups = append(info.Groups, o.opts.GroupsPrefix+groups)
+		case []interface{}:
+			for _, group := range groups {
+				group, ok := group.(string)
+				if !ok {
+					return nil, false, fmt.Errorf("OIDC token claim %s is not a list of strings", o.opts.GroupsClaim)
+				}
+				info.Groups = append(info.Groups, o.opts.GroupsPrefix+group)
+			}
+		}
+	}
+	info.Groups = append(info.Groups, user.AllAuthenticated)
+	return info, true, nil
+}
+
+// keysFor returns the JWKS, reloading it if it's not loaded yet or doesn't have the key of a token. Concurrent
+// requests share a single reload, done without holding the lock.
+func (o *oidcAuthenticator) keysFor(ctx context.Context, keyID string) (*jose.JSONWebKeySet, error) {
+	keys, ok, err := o.cachedKeys(keyID)
+	if ok {
+		return keys, err
+	}
+	// the reload is shared, so it isn't canceled with the request that started it
+	_, _, _ = o.loadGroup.Do("keys", func() (interface{}, error) {
+		o.reloadKeys(context.WithoutCancel(ctx))
+		return nil, nil
+	})
+	keys, _, err = o.cachedKeys(keyID)
+	return keys, err
+}
+
+// cachedKeys returns the loaded JWKS, whether it can be used without reloading it, and the error of the last load if
+// none could be loaded
+func (o *oidcAuthenticator) cachedKeys(keyID string) (*jose.JSONWebKeySet, bool, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	var err error
+	if o.keys == nil && o.loadErr != nil {
+		err = fmt.Errorf("loading OIDC JWKS: %w", o.loadErr)
+	}
+	if o.keys != nil && (keyID == "" || len(o.keys.Key(keyID)) > 0) {
+		return o.keys, true, nil
+	}
+	return o.keys, !o.lastLoad.IsZero() && time.Since(o.lastLoad) < oidcKeysMinRefreshInterval, err
+}
+
+// reloadKeys loads the JWKS, keeping the previous one if it fails
+func (o *oidcAuthenticator) reloadKeys(ctx context.Context) {
+	keys, err := o.loadKeys(ctx)
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.lastLoad = time.Now()
+	if err != nil {
+		o.loadErr = err
+		return
+	}
+	o.keys, o.loadErr = keys, nil
+}
+
+func (o *oidcAuthenticator) keysFromFile(context.Context) (*jose.JSONWebKeySet, error) {
+	content, err := os.ReadFile(o.opts.JWKSFile)
+	if err != nil {
+		return nil, err
+	}
+	keys := &jose.JSONWebKeySet{}
+	if err := json.Unmarshal(content, keys); err != nil {
+		return nil, fmt.Errorf("parsing JWKS from %s: %w", o.opts.JWKSFile, err)
+	}
+	return keys, nil
+}
+
+func (o *oidcAuthenticator) keysFromURL(ctx context.Context, url string) (*jose.JSONWebKeySet, error) {
+	keys := &jose.JSONWebKeySet{}
+	if err := o.getJSON(ctx, url, keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// keysFromDiscovery loads the JWKS found in the OpenID discovery document of the issuer
+func (o *oidcAuthenticator) keysFromDiscovery(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := o.getJSON(ctx, strings.TrimSuffix(o.opts.IssuerURL, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.Issuer != o.opts.IssuerURL {
+		return nil, fmt.Errorf("discovered issuer %s doesn't match %s", discovery.Issuer, o.opts.IssuerURL)
+	}
+	if discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document of %s has no jwks_uri", o.opts.IssuerURL)
+	}
+	return o.keysFromURL(ctx, discovery.JWKSURI)
+}
+
+func (o *oidcAuthenticator) getJSON(ctx context.Context, url string, dest interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := o.opts.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	if err := json.Unmarshal(body, dest); err != nil {
+		return fmt.Errorf("parsing %s: %w", url, err)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+const testIssuer = "https://sso.example.com"
+
+type testSigner struct {
+	key *rsa.PrivateKey
+	kid string
+}
+
+func newTestSigner(t *testing.T, kid string) *testSigner {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return &testSigner{key: key, kid: kid}
+}
+
+func (s *testSigner) jwks(t *testing.T) []byte {
+	keys := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &s.key.PublicKey, KeyID: s.kid, Algorithm: string(jose.RS256), Use: "sig"}}}
+	content, err := json.Marshal(keys)
+	require.NoError(t, err)
+	return content
+}
+
+func (s *testSigner) sign(t *testing.T, claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: s.key}, (&jose.SignerOptions{}).WithHeader("kid", s.kid))
+	require.NoError(t, err)
+	token, err := jwt.Signed(signer).Claims(claims).Serialize()
+	require.NoError(t, err)
+	return token
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":    testIssuer,
+		"aud":    []string{"steve", "other"},
+		"sub":    "1234",
+		"email":  "alice@example.com",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"iat":    time.Now().Unix(),
+		"groups": []string{"devs", "ops"},
+	}
+}
+
+func authenticateToken(a Authenticator, token string) (user.Info, bool, error) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/pods", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return a.Authenticate(req)
+}
+
+func TestOIDCAuthenticator(t *testing.T) {
+	signer := newTestSigner(t, "key-1")
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(jwksFile, signer.jwks(t), 0600))
+
+	a, err := NewOIDCAuthenticator(OIDCOptions{
+		IssuerURL:      testIssuer,
+		ClientID:       "steve",
+		JWKSFile:       jwksFile,
+		UsernameClaim:  "email",
+		UsernamePrefix: "oidc:",
+		GroupsClaim:    "groups",
+		GroupsPrefix:   "oidc:",
+	})
+	require.NoError(t, err)
+
+	withClaims := func(change func(claims map[string]interface{})) string {
+		claims := validClaims()
+		change(claims)
+		return signer.sign(t, claims)
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantUser user.Info
+		wantOK   bool
+		wantErr  bool
+	}{
+		{
+			name:  "valid token",
+			token: signer.sign(t, validClaims()),
+			wantUser: &user.DefaultInfo{
+				Name:   "oidc:alice@example.com",
+				UID:    "1234",
+				Groups: []string{"oidc:devs", "oidc:ops", user.AllAuthenticated},
+			},
+			wantOK: true,
+		},
+		{
+			name: "single group",
+			token: withClaims(func(claims map[string]interface{}) {
+				claims["groups"] = "devs"
+			}),
+			wantUser: &user.DefaultInfo{
+				Name:   "oidc:alice@example.com",
+				UID:    "1234",
+				Groups: []string{"oidc:devs", user.AllAuthenticated},
+			},
+			wantOK: true,
+		},
+		{
+			name:  "no token",
+			token: "",
+		},
+		{
+			name:  "not a JWT",
+			token: "kubeconfig-u-abc:xyz",
+		},
+		{
+			// tokens of other issuers are left to the other authenticators
+			name: "other issuer",
+			token: withClaims(func(claims map[string]interface{}) {
+				claims["iss"] = "https://other.example.com"
+			}),
+		},
+		{
+			name: "wrong audience",
+			token: withClaims(func(claims map[string]interface{}) {
+				claims["aud"] = "other"
+			}),
+			wantErr: true,
+		},
+		{
+			name: "expired",
+			token: withClaims(func(claims map[string]interface{}) {
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			}),
+			wantErr: true,
+		},
+		{
+			name: "no expiry",
+			token: withClaims(func(claims map[string]interface{}) {
+				delete(claims, "exp")
+			}),
+			wantErr: true,
+		},
+		{
+			name: "unverified email",
+			token: withClaims(func(claims map[string]interface{}) {
+				claims["email_verified"] = false
+			}),
+			wantErr: true,
+		},
+		{
+			name: "missing username claim",
+			token: withClaims(func(claims map[string]interface{}) {
+				delete(claims, "email")
+			}),
+			wantErr: true,
+		},
+		{
+			name:    "unknown signer",
+			token:   newTestSigner(t, "key-1").sign(t, validClaims()),
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			info, ok, err := authenticateToken(a, test.token)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.False(t, ok)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.wantOK, ok)
+			assert.Equal(t, test.wantUser, info)
+		})
+	}
+}
+
+func TestOIDCAuthenticatorDiscovery(t *testing.T) {
+	signer := newTestSigner(t, "key-1")
+	var jwksRequests atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(rw).Encode(map[string]string{"issuer": server.URL, "jwks_uri": server.URL + "/keys"})
+		case "/keys":
+			jwksRequests.Add(1)
+			_, _ = rw.Write(signer.jwks(t))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	a, err := NewOIDCAuthenticator(OIDCOptions{IssuerURL: server.URL, ClientID: "steve"})
+	require.NoError(t, err)
+
+	claims := validClaims()
+	claims["iss"] = server.URL
+	info, ok, err := authenticateToken(a, signer.sign(t, claims))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "1234", info.GetName())
+
+	// keys are cached, and only reloaded for unknown keys once in a while
+	_, _, _ = authenticateToken(a, signer.sign(t, claims))
+	_, _, err = authenticateToken(a, newTestSigner(t, "key-2").sign(t, claims))
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), jwksRequests.Load())
+}
+
+func TestOIDCAuthenticatorKeysUnavailable(t *testing.T) {
+	var jwksRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		jwksRequests.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	a, err := NewOIDCAuthenticator(OIDCOptions{IssuerURL: testIssuer, ClientID: "steve", JWKSURL: server.URL})
+	require.NoError(t, err)
+	token := newTestSigner(t, "key-1").sign(t, validClaims())
+
+	// concurrent requests share a single load, and a failed load isn't retried right away
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok, err := authenticateToken(a, token)
+			assert.Error(t, err)
+			assert.False(t, ok)
+		}()
+	}
+	wg.Wait()
+	_, _, err = authenticateToken(a, token)
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), jwksRequests.Load())
+}
+
+func TestOIDCAuthenticatorOtherIssuer(t *testing.T) {
+	var jwksRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		jwksRequests.Add(1)
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	a, err := NewOIDCAuthenticator(OIDCOptions{IssuerURL: testIssuer, ClientID: "steve", JWKSURL: server.URL})
+	require.NoError(t, err)
+
+	// such as a service account token, signed by a key unknown to the issuer
+	claims := validClaims()
+	claims["iss"] = "https://kubernetes.default.svc.cluster.local"
+	info, ok, err := authenticateToken(a, newTestSigner(t, "service-accounts").sign(t, claims))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, info)
+	assert.Equal(t, int32(0), jwksRequests.Load())
+}
+
+func TestNewOIDCAuthenticatorErrors(t *testing.T) {
+	_, err := NewOIDCAuthenticator(OIDCOptions{ClientID: "steve"})
+	assert.Error(t, err)
+	_, err = NewOIDCAuthenticator(OIDCOptions{IssuerURL: testIssuer})
+	assert.Error(t, err)
+	_, err = NewOIDCAuthenticator(OIDCOptions{IssuerURL: testIssuer, ClientID: "steve", JWKSFile: filepath.Join(t.TempDir(), "missing.json")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	x509request "k8s.io/apiserver/pkg/authentication/request/x509"
+	"k8s.io/apiserver/pkg/authentication/token/tokenfile"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
+)
+
+// TokenFileReloadInterval is how often the token file is checked for changes
+var TokenFileReloadInterval = 10 * time.Second
+
+// FromRequestAuthenticator returns an Authenticator for a Kubernetes request authenticator. The authenticated users
+// are added to the system:authenticated group.
+func FromRequestAuthenticator(auth authenticator.Request) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) (user.Info, bool, error) {
+		resp, ok, err := auth.AuthenticateRequest(req)
+		if !ok || err != nil || resp == nil {
+			return nil, false, err
+		}
+		return withAuthenticatedGroup(resp.User), true, nil
+	})
+}
+
+func withAuthenticatedGroup(info user.Info) user.Info {
+	if slices.Contains(info.GetGroups(), user.AllAuthenticated) {
+		return info
+	}
+	return &user.DefaultInfo{
+		Name:   info.GetName(),
+		UID:    info.GetUID(),
+		Groups: append(slices.Clone(info.GetGroups()), user.AllAuthenticated),
+		Extra:  info.GetExtra(),
+	}
+}
+
+// NewClientCertAuthenticator returns an Authenticator verifying the client certificates of requests against the CA
+// bundle in caFile, following the Kubernetes conventions: the common name is the user name and the organizations are
+// the groups. The CA bundle is reloaded whenever the file changes, until the context is done. Client certificates must
+// be requested by the TLS listener, with [crypto/tls.RequestClientCert].
+func NewClientCertAuthenticator(ctx context.Context, caFile string) (Authenticator, error) {
+	ca, err := dynamiccertificates.NewDynamicCAContentFromFile("client-ca", caFile)
+	if err != nil {
+		return nil, err
+	}
+	go ca.Run(ctx, 1)
+	return FromRequestAuthenticator(x509request.NewDynamic(ca.VerifyOptions, x509request.CommonNameUserConversion)), nil
+}
+
+// tokenFileAuth authenticates bearer tokens listed in a CSV file, reloaded on change
+type tokenFileAuth struct {
+	path string
+
+	lock    sync.RWMutex
+	tokens  *tokenfile.TokenAuthenticator
+	modTime time.Time
+	size    int64
+}
+
+// NewTokenFileAuthenticator returns an Authenticator for the static bearer tokens listed in a CSV file, in the format of
+// the Kubernetes token file: token,user,uid,"group1,group2". The file is reloaded whenever it changes, until the
+// context is done. Changes making the file invalid are ignored.
+func NewTokenFileAuthenticator(ctx context.Context, path string) (Authenticator, error) {
+	t := &tokenFileAuth{path: path}
+	if _, err := t.reload(); err != nil {
+		return nil, err
+	}
+	go func() {
+		ticker := time.NewTicker(TokenFileReloadInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if reloaded, err := t.reload(); err != nil {
+					logrus.Errorf("failed to reload token file %s: %v", path, err)
+				} else if reloaded {
+					logrus.Infof("reloaded token file %s", path)
+				}
+			}
+		}
+	}()
+	return t, nil
+}
+
+// reload loads the tokens if the file changed since it was last loaded
+func (t *tokenFileAuth) reload() (bool, error) {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return false, err
+	}
+	t.lock.RLock()
+	unchanged := t.tokens != nil && info.ModTime().Equal(t.modTime) && info.Size() == t.size
+	t.lock.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	tokens, err := tokenfile.NewCSV(t.path)
+	if err != nil {
+		return false, err
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.tokens, t.modTime, t.size = tokens, info.ModTime(), info.Size()
+	return true, nil
+}
+
+func (t *tokenFileAuth) Authenticate(req *http.Request) (user.Info, bool, error) {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, false, nil
+	}
+
+	t.lock.RLock()
+	tokens := t.tokens
+	t.lock.RUnlock()
+
+	resp, ok, err := tokens.AuthenticateToken(req.Context(), token)
+	if !ok || err != nil || resp == nil {
+		return nil, false, err
+	}
+	return withAuthenticatedGroup(resp.User), true, nil
+}
+
+// NewUnionAuthenticator returns an Authenticator trying the given authenticators in order, until one of them
+// authenticates the request. The errors of the others are only returned if none does.
+func NewUnionAuthenticator(authenticators ...Authenticator) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) (user.Info, bool, error) {
+		var errs []error
+		for _, auth := range authenticators {
+			info, ok, err := auth.Authenticate(req)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if ok {
+				return info, true, nil
+			}
+		}
+		return nil, false, utilerrors.NewAggregate(errs)
+	})
+}
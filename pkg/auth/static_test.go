@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func TestClientCertAuthenticator(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	newCA := func() (*x509.Certificate, *ecdsa.PrivateKey) {
+		return newTestCertificate(t, &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "steve-client-ca"},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			KeyUsage:              x509.KeyUsageCertSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}, nil, nil)
+	}
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "ci-bot", Organization: []string{"ci", "deployers"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	ca, caKey := newCA()
+	client, _ := newTestCertificate(t, clientTemplate, ca, caKey)
+	otherCA, otherCAKey := newCA()
+	untrusted, _ := newTestCertificate(t, clientTemplate, otherCA, otherCAKey)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0600))
+	a, err := NewClientCertAuthenticator(ctx, caFile)
+	require.NoError(t, err)
+
+	authenticateCert := func(cert *x509.Certificate) (user.Info, bool, error) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/pods", nil)
+		if cert != nil {
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		}
+		return a.Authenticate(req)
+	}
+
+	info, ok, err := authenticateCert(client)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "ci-bot", info.GetName())
+	assert.Equal(t, []string{"ci", "deployers", user.AllAuthenticated}, info.GetGroups())
+
+	_, ok, err = authenticateCert(untrusted)
+	assert.Error(t, err)
+	assert.False(t, ok)
+
+	_, ok, err = authenticateCert(nil)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestTokenFileAuthenticator(t *testing.T) {
+	oldInterval := TokenFileReloadInterval
+	TokenFileReloadInterval = 10 * time.Millisecond
+	defer func() { TokenFileReloadInterval = oldInterval }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "tokens.csv")
+	require.NoError(t, os.WriteFile(path, []byte("token1,ci-bot,1,\"ci,deployers\"\n"), 0600))
+	a, err := NewTokenFileAuthenticator(ctx, path)
+	require.NoError(t, err)
+
+	info, ok, err := authenticateToken(a, "token1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, &user.DefaultInfo{Name: "ci-bot", UID: "1", Groups: []string{"ci", "deployers", user.AllAuthenticated}}, info)
+
+	_, ok, err = authenticateToken(a, "token2")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// token1 is revoked, token2 added
+	require.NoError(t, os.WriteFile(path, []byte("token2,monitor,2\n"), 0600))
+	require.NoError(t, os.Chtimes(path, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	err = wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		_, ok, _ := authenticateToken(a, "token2")
+		return ok, nil
+	})
+	require.NoError(t, err, "token file was not reloaded")
+	_, ok, _ = authenticateToken(a, "token1")
+	assert.False(t, ok)
+
+	// an invalid file keeps the previous tokens
+	require.NoError(t, os.WriteFile(path, []byte("token3\n"), 0600))
+	require.NoError(t, os.Chtimes(path, time.Now().Add(2*time.Minute), time.Now().Add(2*time.Minute)))
+	time.Sleep(50 * time.Millisecond)
+	_, ok, _ = authenticateToken(a, "token2")
+	assert.True(t, ok)
+
+	_, err = NewTokenFileAuthenticator(ctx, filepath.Join(t.TempDir(), "missing.csv"))
+	assert.Error(t, err)
+}
+
+func TestUnionAuthenticator(t *testing.T) {
+	failing := AuthenticatorFunc(func(*http.Request) (user.Info, bool, error) {
+		return nil, false, errors.New("invalid token")
+	})
+	anonymous := AuthenticatorFunc(func(*http.Request) (user.Info, bool, error) {
+		return nil, false, nil
+	})
+	admin := AuthenticatorFunc(AlwaysAdmin)
+	req := httptest.NewRequest(http.MethodGet, "/v1/pods", nil)
+
+	info, ok, err := NewUnionAuthenticator(failing, anonymous, admin).Authenticate(req)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "admin", info.GetName())
+
+	_, ok, err = NewUnionAuthenticator(anonymous, failing).Authenticate(req)
+	assert.EqualError(t, err, "invalid token")
+	assert.False(t, ok)
+
+	_, ok, err = NewUnionAuthenticator(anonymous).Authenticate(req)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
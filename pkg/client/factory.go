@@ -152,6 +152,12 @@ func (p *Factory) K8sInterface(ctx *types.APIRequest) (kubernetes.Interface, err
 	return kubernetes.NewForConfig(cfg)
 }
 
+// RESTConfig returns the configuration of the clients acting as the user making the request, for the requests that
+// can't be made with the typed or dynamic clients, such as streaming ones
+func (p *Factory) RESTConfig(ctx *types.APIRequest) (*rest.Config, error) {
+	return setupConfig(ctx, p.clientCfg, p.impersonate)
+}
+
 func (p *Factory) AdminK8sInterface() (kubernetes.Interface, error) {
 	return kubernetes.NewForConfig(p.clientCfg)
 }
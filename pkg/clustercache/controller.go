@@ -2,6 +2,8 @@ package clustercache
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
@@ -16,6 +18,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/server/healthz"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
@@ -59,6 +62,11 @@ type clusterCache struct {
 	addHandlers    cancelCollection
 	removeHandlers cancelCollection
 	changeHandlers cancelCollection
+
+	// syncing holds the watchers OnSchemas is waiting on. It is guarded by its own
+	// lock as OnSchemas holds the main one for the whole wait.
+	syncing     []*watcher
+	syncingLock sync.Mutex
 }
 
 func NewClusterCache(ctx context.Context, dynamicClient dynamic.Interface) ClusterCache {
@@ -176,6 +184,15 @@ func (h *clusterCache) OnSchemas(schemas *schema.Collection) error {
 		}
 	}
 
+	h.syncingLock.Lock()
+	h.syncing = toWait
+	h.syncingLock.Unlock()
+	defer func() {
+		h.syncingLock.Lock()
+		h.syncing = nil
+		h.syncingLock.Unlock()
+	}()
+
 	for _, w := range toWait {
 		ctx, cancel := context.WithTimeout(w.ctx, 15*time.Minute)
 		if !cache.WaitForCacheSync(ctx.Done(), w.informer.HasSynced) {
@@ -190,6 +207,35 @@ func (h *clusterCache) OnSchemas(schemas *schema.Collection) error {
 	return nil
 }
 
+// unsynced returns the GVKs of the informers OnSchemas is still waiting on
+func (h *clusterCache) unsynced() []schema2.GroupVersionKind {
+	h.syncingLock.Lock()
+	defer h.syncingLock.Unlock()
+
+	var result []schema2.GroupVersionKind
+	for _, w := range h.syncing {
+		if !w.informer.HasSynced() {
+			result = append(result, w.gvk)
+		}
+	}
+	return result
+}
+
+// NewReadyCheck returns a health check failing while the given ClusterCache has informers that have not completed
+// their initial sync. ClusterCache implementations other than the one returned by NewClusterCache always pass.
+func NewReadyCheck(ccache ClusterCache) healthz.HealthChecker {
+	return healthz.NamedCheck("clustercache", func(_ *http.Request) error {
+		c, ok := ccache.(*clusterCache)
+		if !ok {
+			return nil
+		}
+		if unsynced := c.unsynced(); len(unsynced) > 0 {
+			return fmt.Errorf("informers not synced: %v", unsynced)
+		}
+		return nil
+	})
+}
+
 func (h *clusterCache) Get(gvk schema2.GroupVersionKind, namespace, name string) (interface{}, bool, error) {
 	h.RLock()
 	defer h.RUnlock()
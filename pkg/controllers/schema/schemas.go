@@ -2,6 +2,8 @@ package schema
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -19,6 +21,7 @@ import (
 	authorizationv1 "k8s.io/api/authorization/v1"
 	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/server/healthz"
 	"k8s.io/client-go/discovery"
 	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
 	apiv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
@@ -48,6 +51,8 @@ type handler struct {
 	crdClient apiextcontrollerv1.CustomResourceDefinitionClient
 	ssar      authorizationv1client.SelfSubjectAccessReviewInterface
 	handler   SchemasHandlerFunc
+	// synced is set once schemas have been successfully refreshed for the first time
+	synced atomic.Bool
 }
 
 func Register(ctx context.Context,
@@ -57,7 +62,7 @@ func Register(ctx context.Context,
 	apiService v1.APIServiceController,
 	ssar authorizationv1client.SelfSubjectAccessReviewInterface,
 	schemasHandler SchemasHandlerFunc,
-	schemas *schema2.Collection) {
+	schemas *schema2.Collection) healthz.HealthChecker {
 
 	h := &handler{
 		ctx:       ctx,
@@ -71,6 +76,13 @@ func Register(ctx context.Context,
 
 	apiService.OnChange(ctx, "schema", h.OnChangeAPIService)
 	crd.OnChange(ctx, "schema", h.OnChangeCRD)
+
+	return healthz.NamedCheck("schemas", func(_ *http.Request) error {
+		if !h.synced.Load() {
+			return errors.New("schemas not synced")
+		}
+		return nil
+	})
 }
 
 func (h *handler) OnChangeCRD(key string, crd *apiextv1.CustomResourceDefinition) (*apiextv1.CustomResourceDefinition, error) {
@@ -189,9 +201,12 @@ func (h *handler) refreshAll(ctx context.Context) error {
 
 	h.schemas.Reset(filteredSchemas)
 	if h.handler != nil {
-		return h.handler.OnSchemas(h.schemas)
+		if err := h.handler.OnSchemas(h.schemas); err != nil {
+			return err
+		}
 	}
 
+	h.synced.Store(true)
 	return nil
 }
 
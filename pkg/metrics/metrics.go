@@ -19,6 +19,9 @@ const (
 	resourceLabel = "resource"
 	methodLabel   = "method"
 	codeLabel     = "code"
+	resultLabel   = "result"
+	categoryLabel = "category"
+	reasonLabel   = "reason"
 )
 
 var (
@@ -47,6 +50,58 @@ var (
 			Help:      "Request times in ms for k8s proxy store",
 		},
 		[]string{resourceLabel, methodLabel, codeLabel})
+	SQLCachePrewarmTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "sql_cache",
+			Name:      "prewarm_total",
+			Help:      "Total count of SQL cache pre-warming attempts",
+		},
+		[]string{resourceLabel, resultLabel})
+	SQLCachePrewarmTime = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "sql_cache",
+			Name:      "prewarm_time",
+			Help:      "Time in ms to pre-warm the SQL cache of a type",
+		},
+		[]string{resourceLabel})
+	SQLCachePrewarmPending = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: "sql_cache",
+			Name:      "prewarm_pending",
+			Help:      "Number of types waiting for their SQL cache to be pre-warmed",
+		})
+	AccessSetCacheTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "access_set",
+			Name:      "cache_total",
+			Help:      "Total count of access set lookups, by cache hit or miss",
+		},
+		[]string{resultLabel})
+	AccessSetComputeTime = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Subsystem: "access_set",
+			Name:      "compute_time",
+			Help:      "Time in ms to compute the access set of a user",
+		})
+	AccessSetInvalidationsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: "access_set",
+			Name:      "invalidations_total",
+			Help:      "Total count of changes to roles and bindings invalidating the access sets of their subjects",
+		})
+	RateLimitRejectedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "rate_limit",
+			Name:      "rejected_total",
+			Help:      "Total count of /v1 requests rejected with 429, by request category and reason",
+		},
+		[]string{categoryLabel, reasonLabel})
+	RateLimitListQueueTime = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Subsystem: "rate_limit",
+			Name:      "list_queue_time",
+			Help:      "Time in ms list requests waited in the fair queue before being served",
+		})
 )
 
 func (m MetricLogger) IncTotalResponses(err error) {
@@ -98,3 +153,72 @@ func (m MetricLogger) getAPIErrorCode(err error) string {
 	}
 	return "500"
 }
+
+func RecordSQLCachePrewarm(resource string, err error, val float64) {
+	if prometheusMetrics {
+		result := "success"
+		if err != nil {
+			result = "error"
+		}
+		SQLCachePrewarmTotal.With(
+			prometheus.Labels{
+				resourceLabel: resource,
+				resultLabel:   result,
+			},
+		).Inc()
+		SQLCachePrewarmTime.With(
+			prometheus.Labels{
+				resourceLabel: resource,
+			},
+		).Observe(val)
+	}
+}
+
+func SetSQLCachePrewarmPending(pending int) {
+	if prometheusMetrics {
+		SQLCachePrewarmPending.Set(float64(pending))
+	}
+}
+
+func RecordAccessSetCacheResult(hit bool) {
+	if prometheusMetrics {
+		result := "miss"
+		if hit {
+			result = "hit"
+		}
+		AccessSetCacheTotal.With(
+			prometheus.Labels{
+				resultLabel: result,
+			},
+		).Inc()
+	}
+}
+
+func RecordAccessSetComputeTime(val float64) {
+	if prometheusMetrics {
+		AccessSetComputeTime.Observe(val)
+	}
+}
+
+func IncAccessSetInvalidations() {
+	if prometheusMetrics {
+		AccessSetInvalidationsTotal.Inc()
+	}
+}
+
+func IncRateLimitRejected(category, reason string) {
+	if prometheusMetrics {
+		RateLimitRejectedTotal.With(
+			prometheus.Labels{
+				categoryLabel: category,
+				reasonLabel:   reason,
+			},
+		).Inc()
+	}
+}
+
+func RecordRateLimitListQueueTime(val float64) {
+	if prometheusMetrics {
+		RateLimitListQueueTime.Observe(val)
+	}
+}
@@ -14,5 +14,13 @@ func init() {
 		prometheus.MustRegister(ProxyTotalResponses)
 		prometheus.MustRegister(K8sClientResponseTime)
 		prometheus.MustRegister(ProxyStoreResponseTime)
+		prometheus.MustRegister(SQLCachePrewarmTotal)
+		prometheus.MustRegister(SQLCachePrewarmTime)
+		prometheus.MustRegister(SQLCachePrewarmPending)
+		prometheus.MustRegister(AccessSetCacheTotal)
+		prometheus.MustRegister(AccessSetComputeTime)
+		prometheus.MustRegister(AccessSetInvalidationsTotal)
+		prometheus.MustRegister(RateLimitRejectedTotal)
+		prometheus.MustRegister(RateLimitListQueueTime)
 	}
 }
@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+var (
+	readOnlyVerbs = sets.New("get", "list", "watch", "head")
+	// connectSubresources are read with GET, but run commands in pods or reach into pods, services and nodes
+	connectSubresources = sets.New("exec", "attach", "portforward", "proxy")
+
+	requestInfoFactory = &request.RequestInfoFactory{
+		APIPrefixes:          sets.NewString("api", "apis"),
+		GrouplessAPIPrefixes: sets.NewString("api"),
+	}
+)
+
+// ReadOnlyHandler only lets through the requests reading from the Kubernetes API: gets, lists and watches of resources
+// other than connect subresources such as pods/exec, and the discovery endpoints. Other requests are rejected with a
+// 405 Method Not Allowed, or a 403 Forbidden for connect subresources.
+func ReadOnlyHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		info, err := requestInfoFactory.NewRequestInfo(req)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !readOnlyVerbs.Has(info.Verb) {
+			http.Error(rw, "the server is read-only", http.StatusMethodNotAllowed)
+			return
+		}
+		if info.IsResourceRequest && connectSubresources.Has(info.Subresource) {
+			http.Error(rw, "the server is read-only", http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(rw, req)
+	})
+}
@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{name: "list", method: http.MethodGet, path: "/api/v1/namespaces/default/pods", want: http.StatusOK},
+		{name: "get", method: http.MethodGet, path: "/apis/apps/v1/namespaces/default/deployments/web", want: http.StatusOK},
+		{name: "watch", method: http.MethodGet, path: "/api/v1/pods?watch=true", want: http.StatusOK},
+		{name: "logs", method: http.MethodGet, path: "/api/v1/namespaces/default/pods/web/log", want: http.StatusOK},
+		{name: "discovery", method: http.MethodGet, path: "/apis", want: http.StatusOK},
+		{name: "version", method: http.MethodHead, path: "/version", want: http.StatusOK},
+		{name: "create", method: http.MethodPost, path: "/api/v1/namespaces/default/pods", want: http.StatusMethodNotAllowed},
+		{name: "update", method: http.MethodPut, path: "/apis/apps/v1/namespaces/default/deployments/web", want: http.StatusMethodNotAllowed},
+		{name: "patch", method: http.MethodPatch, path: "/api/v1/nodes/node1", want: http.StatusMethodNotAllowed},
+		{name: "delete", method: http.MethodDelete, path: "/api/v1/namespaces/default/pods/web", want: http.StatusMethodNotAllowed},
+		{name: "delete collection", method: http.MethodDelete, path: "/api/v1/namespaces/default/pods", want: http.StatusMethodNotAllowed},
+		{name: "exec", method: http.MethodGet, path: "/api/v1/namespaces/default/pods/web/exec?command=sh", want: http.StatusForbidden},
+		{name: "attach", method: http.MethodGet, path: "/api/v1/namespaces/default/pods/web/attach", want: http.StatusForbidden},
+		{name: "node proxy", method: http.MethodGet, path: "/api/v1/nodes/node1/proxy/pods", want: http.StatusForbidden},
+	}
+	handler := ReadOnlyHandler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, httptest.NewRequest(test.method, test.path, nil))
+			assert.Equal(t, test.want, rw.Code)
+		})
+	}
+}
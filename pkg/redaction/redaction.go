@@ -0,0 +1,255 @@
+/*
+Package redaction hides sensitive fields of Kubernetes objects, such as the data of Secrets, from users who aren't
+allowed to reveal them.
+*/
+package redaction
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// RevealVerb is the verb exempting users from the redaction of the fields of a resource
+	RevealVerb = "reveal"
+	// Redacted replaces the redacted values
+	Redacted = "[redacted]"
+)
+
+// Rule selects fields to redact from the objects of a type
+type Rule struct {
+	// Group, Version and Kind select the type. An empty version matches all the versions of the type.
+	Group   string `json:"group,omitempty"`
+	Version string `json:"version,omitempty"`
+	Kind    string `json:"kind"`
+	// Paths are JSONPath expressions selecting the fields to redact, eg. ".data",
+	// ".spec.containers[*].env[*].value" or ".metadata.annotations['example.com/token']". All the values in the
+	// selected fields are redacted, keeping the keys of maps and the length of lists.
+	Paths []string `json:"paths"`
+	// Pattern, if set, is a regular expression restricting the redaction to the string values it matches
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// Policy redacts the fields selected by a set of rules
+type Policy struct {
+	rules []rule
+}
+
+type rule struct {
+	gvk     schema.GroupVersionKind
+	paths   [][]string
+	pattern *regexp.Regexp
+}
+
+// wildcard matches all the keys of a map or all the items of a list in a path
+const wildcard = "*"
+
+// NewPolicy returns a policy for the given rules, or an error if a path or pattern is invalid
+func NewPolicy(rules []Rule) (*Policy, error) {
+	policy := &Policy{}
+	for _, r := range rules {
+		if r.Kind == "" {
+			return nil, fmt.Errorf("redaction rule for group %q has no kind", r.Group)
+		}
+		compiled := rule{
+			gvk: schema.GroupVersionKind{Group: r.Group, Version: r.Version, Kind: r.Kind},
+		}
+		for _, path := range r.Paths {
+			parsed, err := parsePath(path)
+			if err != nil {
+				return nil, fmt.Errorf("redaction rule for %s: %w", r.Kind, err)
+			}
+			compiled.paths = append(compiled.paths, parsed)
+		}
+		if r.Pattern != "" {
+			pattern, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("redaction rule for %s: invalid pattern: %w", r.Kind, err)
+			}
+			compiled.pattern = pattern
+		}
+		policy.rules = append(policy.rules, compiled)
+	}
+	return policy, nil
+}
+
+// LoadRules reads a list of rules from a YAML or JSON file
+func LoadRules(path string) ([]Rule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := yaml.Unmarshal(content, &rules); err != nil {
+		return nil, fmt.Errorf("parsing redaction rules from %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+func (r rule) matches(gvk schema.GroupVersionKind) bool {
+	return r.gvk.Group == gvk.Group && r.gvk.Kind == gvk.Kind && (r.gvk.Version == "" || r.gvk.Version == gvk.Version)
+}
+
+// Matches returns whether objects of the given type have fields to redact
+func (p *Policy) Matches(gvk schema.GroupVersionKind) bool {
+	if p == nil {
+		return false
+	}
+	for _, r := range p.rules {
+		if r.matches(gvk) {
+			return true
+		}
+	}
+	return false
+}
+
+// Overlaps returns whether a field of the objects of the given type, as indexed by the SQL cache, contains or is
+// contained in a redacted field. Lists are not part of the indexed fields, so wildcards may also match no field.
+func (p *Policy) Overlaps(gvk schema.GroupVersionKind, field []string) bool {
+	if p == nil {
+		return false
+	}
+	for _, r := range p.rules {
+		if !r.matches(gvk) {
+			continue
+		}
+		for _, path := range r.paths {
+			if overlaps(path, field) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func overlaps(path, field []string) bool {
+	if len(path) == 0 || len(field) == 0 {
+		return true
+	}
+	if path[0] == wildcard {
+		// a wildcard only matches the last field if it ends the path, as it's usually the items of a list
+		return overlaps(path[1:], field) || ((len(field) > 1 || len(path) == 1) && overlaps(path[1:], field[1:]))
+	}
+	return path[0] == field[0] && overlaps(path[1:], field[1:])
+}
+
+// Redact replaces the values of the fields selected by the rules for the given type in obj
+func (p *Policy) Redact(gvk schema.GroupVersionKind, obj map[string]interface{}) {
+	if p == nil {
+		return
+	}
+	for _, r := range p.rules {
+		if !r.matches(gvk) {
+			continue
+		}
+		for _, path := range r.paths {
+			redactPath(obj, path, r.pattern)
+		}
+	}
+}
+
+// redactPath redacts the values found at path under the given value, which must be a map or a list
+func redactPath(value interface{}, path []string, pattern *regexp.Regexp) {
+	if len(path) == 0 {
+		return
+	}
+	key, rest := path[0], path[1:]
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if key != wildcard && k != key {
+				continue
+			}
+			if len(rest) == 0 {
+				v[k] = redactValue(child, pattern)
+			} else {
+				redactPath(child, rest, pattern)
+			}
+		}
+	case []interface{}:
+		if key != wildcard {
+			return
+		}
+		for i, child := range v {
+			if len(rest) == 0 {
+				v[i] = redactValue(child, pattern)
+			} else {
+				redactPath(child, rest, pattern)
+			}
+		}
+	}
+}
+
+// redactValue returns the value with all its leaves redacted, or only the string ones matching the pattern if given
+func redactValue(value interface{}, pattern *regexp.Regexp) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			v[k] = redactValue(child, pattern)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redactValue(child, pattern)
+		}
+		return v
+	case nil:
+		return nil
+	case string:
+		if pattern != nil && !pattern.MatchString(v) {
+			return v
+		}
+		return Redacted
+	default:
+		if pattern != nil {
+			return v
+		}
+		return Redacted
+	}
+}
+
+// parsePath parses a JSONPath expression made of fields, as ".name" or "['name']", and of wildcards, as "[*]" or ".*"
+func parsePath(path string) ([]string, error) {
+	expr := strings.TrimSpace(path)
+	expr = strings.TrimSuffix(strings.TrimPrefix(expr, "{"), "}")
+	if expr == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	var result []string
+	for len(expr) > 0 {
+		switch {
+		case strings.HasPrefix(expr, "[*]"):
+			result = append(result, wildcard)
+			expr = expr[3:]
+		case strings.HasPrefix(expr, "['"), strings.HasPrefix(expr, `["`):
+			quote := expr[1:2]
+			end := strings.Index(expr[2:], quote+"]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated field in path %q", path)
+			}
+			result = append(result, expr[2:2+end])
+			expr = expr[2+end+2:]
+		case strings.HasPrefix(expr, "."):
+			expr = expr[1:]
+			end := strings.IndexAny(expr, ".[")
+			if end < 0 {
+				end = len(expr)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty field in path %q", path)
+			}
+			result = append(result, expr[:end])
+			expr = expr[end:]
+		default:
+			return nil, fmt.Errorf("invalid path %q, expected a field or [*] at %q", path, expr)
+		}
+	}
+	return result, nil
+}
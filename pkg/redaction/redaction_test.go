@@ -0,0 +1,196 @@
+package redaction
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	secretGVK = schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+	podGVK    = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []Rule
+		gvk   schema.GroupVersionKind
+		obj   map[string]interface{}
+		want  map[string]interface{}
+		// wantUnmatched is set when the rules don't apply to the kind
+		wantUnmatched bool
+	}{
+		{
+			name:  "secret data",
+			rules: []Rule{{Kind: "Secret", Paths: []string{".data", "{.stringData}"}}},
+			gvk:   secretGVK,
+			obj: map[string]interface{}{
+				"type": "Opaque",
+				"data": map[string]interface{}{"password": "c2VjcmV0", "user": "YWRtaW4="},
+			},
+			want: map[string]interface{}{
+				"type": "Opaque",
+				"data": map[string]interface{}{"password": Redacted, "user": Redacted},
+			},
+		},
+		{
+			name:          "other kind",
+			rules:         []Rule{{Kind: "Secret", Paths: []string{".data"}}},
+			gvk:           schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			obj:           map[string]interface{}{"data": map[string]interface{}{"key": "value"}},
+			want:          map[string]interface{}{"data": map[string]interface{}{"key": "value"}},
+			wantUnmatched: true,
+		},
+		{
+			name:          "other version",
+			rules:         []Rule{{Version: "v2", Kind: "Secret", Paths: []string{".data"}}},
+			gvk:           secretGVK,
+			obj:           map[string]interface{}{"data": map[string]interface{}{"key": "value"}},
+			want:          map[string]interface{}{"data": map[string]interface{}{"key": "value"}},
+			wantUnmatched: true,
+		},
+		{
+			name: "env values matching a pattern",
+			rules: []Rule{{
+				Kind:    "Pod",
+				Paths:   []string{".spec.containers[*].env[*].value"},
+				Pattern: "^(ghp_|AKIA)",
+			}},
+			gvk: podGVK,
+			obj: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name": "web",
+							"env": []interface{}{
+								map[string]interface{}{"name": "TOKEN", "value": "ghp_abcdef"},
+								map[string]interface{}{"name": "PORT", "value": "8080"},
+							},
+						},
+					},
+				},
+			},
+			want: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name": "web",
+							"env": []interface{}{
+								map[string]interface{}{"name": "TOKEN", "value": Redacted},
+								map[string]interface{}{"name": "PORT", "value": "8080"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "annotation",
+			rules: []Rule{{Kind: "Pod", Paths: []string{".metadata.annotations['example.com/token']"}}},
+			gvk:   podGVK,
+			obj: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{"example.com/token": "abc", "example.com/owner": "alice"},
+				},
+			},
+			want: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{"example.com/token": Redacted, "example.com/owner": "alice"},
+				},
+			},
+		},
+		{
+			name:  "missing field",
+			rules: []Rule{{Kind: "Pod", Paths: []string{".spec.containers[*].env[*].value", ".metadata.annotations.*"}}},
+			gvk:   podGVK,
+			obj:   map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{}}},
+			want:  map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy, err := NewPolicy(test.rules)
+			require.NoError(t, err)
+			assert.Equal(t, !test.wantUnmatched, policy.Matches(test.gvk))
+
+			policy.Redact(test.gvk, test.obj)
+			assert.Equal(t, test.want, test.obj)
+		})
+	}
+}
+
+func TestNewPolicyErrors(t *testing.T) {
+	for name, rule := range map[string]Rule{
+		"no kind":          {Paths: []string{".data"}},
+		"empty path":       {Kind: "Secret", Paths: []string{""}},
+		"no leading dot":   {Kind: "Secret", Paths: []string{"data"}},
+		"empty field":      {Kind: "Secret", Paths: []string{".data..key"}},
+		"unterminated key": {Kind: "Secret", Paths: []string{".metadata.annotations['key"}},
+		"invalid pattern":  {Kind: "Secret", Paths: []string{".data"}, Pattern: "("},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewPolicy([]Rule{rule})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	policy, err := NewPolicy([]Rule{
+		{Kind: "Secret", Paths: []string{".data", ".metadata.annotations['example.com/token']"}},
+		{Kind: "Pod", Paths: []string{".spec.containers[*].env[*].value"}},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		gvk   schema.GroupVersionKind
+		field []string
+		want  bool
+	}{
+		{gvk: secretGVK, field: []string{"data"}, want: true},
+		{gvk: secretGVK, field: []string{"data", "password"}, want: true},
+		{gvk: secretGVK, field: []string{"metadata", "annotations"}, want: true},
+		{gvk: secretGVK, field: []string{"metadata", "annotations", "example.com/token"}, want: true},
+		{gvk: secretGVK, field: []string{"metadata", "annotations", "example.com/other"}, want: false},
+		{gvk: secretGVK, field: []string{"metadata", "name"}, want: false},
+		{gvk: podGVK, field: []string{"spec", "containers", "env", "value"}, want: true},
+		{gvk: podGVK, field: []string{"spec", "containers", "image"}, want: false},
+		{gvk: podGVK, field: []string{"data"}, want: false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, policy.Overlaps(test.gvk, test.field), "%s %v", test.gvk.Kind, test.field)
+	}
+}
+
+func TestNilPolicy(t *testing.T) {
+	var policy *Policy
+	obj := map[string]interface{}{"data": map[string]interface{}{"key": "value"}}
+	assert.False(t, policy.Matches(secretGVK))
+	assert.False(t, policy.Overlaps(secretGVK, []string{"data"}))
+	policy.Redact(secretGVK, obj)
+	assert.Equal(t, map[string]interface{}{"data": map[string]interface{}{"key": "value"}}, obj)
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+- kind: Secret
+  paths: [".data", ".stringData"]
+- group: apps
+  kind: Deployment
+  paths: [".spec.template.spec.containers[*].env[*].value"]
+  pattern: "^ghp_"
+`), 0o600))
+
+	rules, err := LoadRules(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Kind: "Secret", Paths: []string{".data", ".stringData"}},
+		{Group: "apps", Kind: "Deployment", Paths: []string{".spec.template.spec.containers[*].env[*].value"}, Pattern: "^ghp_"},
+	}, rules)
+}
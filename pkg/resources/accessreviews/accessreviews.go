@@ -0,0 +1,191 @@
+// Package accessreviews registers the accessreview schema, explaining why a user is or isn't allowed a verb on a
+// resource, and the whocan schema, listing the subjects allowed a verb on a resource.
+package accessreviews
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+const (
+	schemaID       = "accessreview"
+	grantSchemaID  = "grant"
+	whoCanSchemaID = "whocan"
+)
+
+// AccessReview is a question about the access of a user, and its answer. The user, groups, verb, apiGroup, resource,
+// namespace and name are given, allowed, reason and grants are returned.
+type AccessReview struct {
+	User      string   `json:"user"`
+	Groups    []string `json:"groups,omitempty"`
+	Verb      string   `json:"verb"`
+	APIGroup  string   `json:"apiGroup"`
+	Resource  string   `json:"resource"`
+	Namespace string   `json:"namespace,omitempty"`
+	Name      string   `json:"name,omitempty"`
+
+	Allowed bool    `json:"allowed"`
+	Reason  string  `json:"reason,omitempty"`
+	Grants  []Grant `json:"grants"`
+}
+
+// Grant is a rule granting the access, and the binding and role it comes from
+type Grant struct {
+	SubjectKind      string            `json:"subjectKind"`
+	SubjectName      string            `json:"subjectName"`
+	SubjectNamespace string            `json:"subjectNamespace,omitempty"`
+	BindingKind      string            `json:"bindingKind"`
+	BindingName      string            `json:"bindingName"`
+	BindingNamespace string            `json:"bindingNamespace,omitempty"`
+	RoleKind         string            `json:"roleKind"`
+	RoleName         string            `json:"roleName"`
+	Rule             rbacv1.PolicyRule `json:"rule"`
+}
+
+// Register adds the accessreview schema, whose reviews are created with a POST to the collection, and the whocan
+// schema, listed with the verb, apiGroup, resource, namespace and name as query parameters. Only admins can review the
+// access of users.
+func Register(apiSchemas *types.APISchemas, explainer accesscontrol.AccessExplainer) {
+	apiSchemas.InternalSchemas.TypeName(schemaID, AccessReview{})
+	apiSchemas.MustImportAndCustomize(AccessReview{}, func(schema *types.APISchema) {
+		schema.CollectionMethods = []string{http.MethodPost}
+		schema.ResourceMethods = []string{}
+		schema.Store = &Store{explainer: explainer}
+	})
+	// whocan lists the grants of an access, as returned by the reviews, under a second name
+	whoCan := *apiSchemas.InternalSchemas.Schema(grantSchemaID)
+	whoCan.ID = whoCanSchemaID
+	whoCan.PluralName = whoCanSchemaID
+	whoCan.CollectionMethods = []string{http.MethodGet}
+	whoCan.ResourceMethods = []string{}
+	apiSchemas.MustAddSchema(types.APISchema{
+		Schema: &whoCan,
+		Store:  &WhoCanStore{explainer: explainer},
+	})
+}
+
+// Store explains the access of users with the AccessSets enforced by steve
+type Store struct {
+	empty.Store
+	explainer accesscontrol.AccessExplainer
+}
+
+func (s *Store) Create(apiOp *types.APIRequest, _ *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	if !accesscontrol.IsAdmin(apiOp) {
+		return types.APIObject{}, apierror.NewAPIError(validation.PermissionDenied, "only admins can review the access of users")
+	}
+
+	var review AccessReview
+	if err := convert.ToObj(data.Data(), &review); err != nil {
+		return types.APIObject{}, apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+	if review.User == "" && len(review.Groups) == 0 {
+		return types.APIObject{}, apierror.NewAPIError(validation.MissingRequired, "user or groups are required")
+	}
+	if review.Verb == "" || review.Resource == "" {
+		return types.APIObject{}, apierror.NewAPIError(validation.MissingRequired, "verb and resource are required")
+	}
+
+	gr := schema.GroupResource{Group: review.APIGroup, Resource: review.Resource}
+	explanation := s.explainer.ExplainAccess(&user.DefaultInfo{Name: review.User, Groups: review.Groups},
+		review.Verb, gr, review.Namespace, review.Name)
+
+	review.Allowed = explanation.Allowed
+	review.Grants = []Grant{}
+	for _, grant := range explanation.Grants {
+		review.Grants = append(review.Grants, Grant(grant))
+	}
+	review.Reason = reason(review, gr)
+
+	return types.APIObject{
+		Type:   schemaID,
+		Object: review,
+	}, nil
+}
+
+// reason summarizes the decision
+func reason(review AccessReview, gr schema.GroupResource) string {
+	target := gr.String()
+	if review.Name != "" {
+		target += " " + review.Name
+	}
+	if review.Namespace != "" {
+		target += " in namespace " + review.Namespace
+	}
+
+	if !review.Allowed {
+		return fmt.Sprintf("no RoleBinding or ClusterRoleBinding grants %s on %s", review.Verb, target)
+	}
+	var bindings []string
+	for _, grant := range review.Grants {
+		binding := grant.BindingKind + " " + grant.BindingName
+		if grant.BindingNamespace != "" {
+			binding = grant.BindingKind + " " + grant.BindingNamespace + "/" + grant.BindingName
+		}
+		bindings = append(bindings, binding)
+	}
+	return fmt.Sprintf("%s on %s is granted by %s", review.Verb, target, strings.Join(slices.Compact(bindings), ", "))
+}
+
+// WhoCanStore lists the subjects allowed a verb on a resource by the cached roles and bindings
+type WhoCanStore struct {
+	empty.Store
+	explainer accesscontrol.AccessExplainer
+}
+
+func (s *WhoCanStore) List(apiOp *types.APIRequest, _ *types.APISchema) (types.APIObjectList, error) {
+	if !accesscontrol.IsAdmin(apiOp) {
+		return types.APIObjectList{}, apierror.NewAPIError(validation.PermissionDenied, "only admins can review the access of users")
+	}
+
+	query := apiOp.Request.URL.Query()
+	verb, resource := query.Get("verb"), query.Get("resource")
+	if verb == "" || resource == "" {
+		return types.APIObjectList{}, apierror.NewAPIError(validation.MissingRequired, "verb and resource are required")
+	}
+	gr := schema.GroupResource{Group: query.Get("apiGroup"), Resource: resource}
+
+	result := types.APIObjectList{
+		Objects: []types.APIObject{},
+	}
+	seen := map[string]int{}
+	for _, grant := range s.explainer.WhoCan(verb, gr, query.Get("namespace"), query.Get("name")) {
+		// several rules of a role can grant the same access
+		id := grantID(grant)
+		if seen[id]++; seen[id] > 1 {
+			id += ":" + strconv.Itoa(seen[id]-1)
+		}
+		result.Objects = append(result.Objects, types.APIObject{
+			Type:   whoCanSchemaID,
+			ID:     id,
+			Object: Grant(grant),
+		})
+	}
+	return result, nil
+}
+
+// grantID identifies a grant by its binding and subject
+func grantID(grant accesscontrol.AccessGrant) string {
+	binding := grant.BindingName
+	if grant.BindingNamespace != "" {
+		binding = grant.BindingNamespace + "/" + binding
+	}
+	subject := grant.SubjectName
+	if grant.SubjectNamespace != "" {
+		subject = grant.SubjectNamespace + "/" + subject
+	}
+	return strings.Join([]string{grant.BindingKind, binding, grant.SubjectKind, subject}, ":")
+}
@@ -0,0 +1,296 @@
+package accessreviews
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+type explanation struct {
+	user      user.Info
+	verb      string
+	gr        schema.GroupResource
+	namespace string
+	name      string
+}
+
+type fakeExplainer struct {
+	result accesscontrol.AccessExplanation
+	grants []accesscontrol.AccessGrant
+	calls  []explanation
+}
+
+func (f *fakeExplainer) ExplainAccess(user user.Info, verb string, gr schema.GroupResource, namespace, name string) accesscontrol.AccessExplanation {
+	f.calls = append(f.calls, explanation{user: user, verb: verb, gr: gr, namespace: namespace, name: name})
+	return f.result
+}
+
+func (f *fakeExplainer) WhoCan(verb string, gr schema.GroupResource, namespace, name string) []accesscontrol.AccessGrant {
+	f.calls = append(f.calls, explanation{verb: verb, gr: gr, namespace: namespace, name: name})
+	return f.grants
+}
+
+func newRequest(admin bool) *types.APIRequest {
+	apiSchemas := types.EmptyAPISchemas()
+	accessSet := &accesscontrol.AccessSet{}
+	if admin {
+		accessSet.Add(accesscontrol.All, schema.GroupResource{Group: accesscontrol.All, Resource: accesscontrol.All},
+			accesscontrol.Access{Namespace: accesscontrol.All, ResourceName: accesscontrol.All})
+	} else {
+		accessSet.Add("*", schema.GroupResource{Group: "apps", Resource: "*"},
+			accesscontrol.Access{Namespace: accesscontrol.All, ResourceName: accesscontrol.All})
+	}
+	accesscontrol.SetAccessSetAttribute(apiSchemas, accessSet)
+	return &types.APIRequest{
+		Schemas: apiSchemas,
+		Method:  http.MethodPost,
+	}
+}
+
+func TestRegister(t *testing.T) {
+	apiSchemas := types.EmptyAPISchemas()
+	Register(apiSchemas, &fakeExplainer{})
+
+	review := apiSchemas.LookupSchema(schemaID)
+	require.NotNil(t, review)
+	assert.Equal(t, "array[grant]", review.ResourceFields["grants"].Type)
+
+	// whocan lists the same grants as the reviews
+	whoCan := apiSchemas.LookupSchema(whoCanSchemaID)
+	require.NotNil(t, whoCan)
+	assert.Equal(t, []string{http.MethodGet}, whoCan.CollectionMethods)
+	assert.Empty(t, whoCan.ResourceMethods)
+	assert.IsType(t, &WhoCanStore{}, whoCan.Store)
+	assert.Equal(t, apiSchemas.InternalSchemas.Schema(grantSchemaID).ResourceFields, whoCan.ResourceFields)
+}
+
+func TestCreate(t *testing.T) {
+	editRule := rbacv1.PolicyRule{
+		Verbs:     []string{"*"},
+		APIGroups: []string{"apps"},
+		Resources: []string{"deployments"},
+	}
+	tests := []struct {
+		name        string
+		admin       bool
+		input       map[string]interface{}
+		result      accesscontrol.AccessExplanation
+		wantStatus  int
+		wantCall    *explanation
+		wantAllowed bool
+		wantReason  string
+		wantGrants  []Grant
+	}{
+		{
+			name:  "allowed",
+			admin: true,
+			input: map[string]interface{}{
+				"user":      "alice",
+				"groups":    []interface{}{"devs"},
+				"verb":      "delete",
+				"apiGroup":  "apps",
+				"resource":  "deployments",
+				"namespace": "dev",
+				"name":      "web",
+			},
+			result: accesscontrol.AccessExplanation{
+				Allowed: true,
+				Grants: []accesscontrol.AccessGrant{
+					{
+						SubjectKind: "Group", SubjectName: "devs",
+						BindingKind: "RoleBinding", BindingName: "dev-edit", BindingNamespace: "dev",
+						RoleKind: "ClusterRole", RoleName: "edit", Rule: editRule,
+					},
+				},
+			},
+			wantCall: &explanation{
+				user:      &user.DefaultInfo{Name: "alice", Groups: []string{"devs"}},
+				verb:      "delete",
+				gr:        schema.GroupResource{Group: "apps", Resource: "deployments"},
+				namespace: "dev",
+				name:      "web",
+			},
+			wantAllowed: true,
+			wantReason:  "delete on deployments.apps web in namespace dev is granted by RoleBinding dev/dev-edit",
+			wantGrants: []Grant{
+				{
+					SubjectKind: "Group", SubjectName: "devs",
+					BindingKind: "RoleBinding", BindingName: "dev-edit", BindingNamespace: "dev",
+					RoleKind: "ClusterRole", RoleName: "edit", Rule: editRule,
+				},
+			},
+		},
+		{
+			name:  "denied",
+			admin: true,
+			input: map[string]interface{}{
+				"user":     "alice",
+				"verb":     "list",
+				"resource": "secrets",
+			},
+			wantCall: &explanation{
+				user: &user.DefaultInfo{Name: "alice"},
+				verb: "list",
+				gr:   schema.GroupResource{Resource: "secrets"},
+			},
+			wantReason: "no RoleBinding or ClusterRoleBinding grants list on secrets",
+			wantGrants: []Grant{},
+		},
+		{
+			name:       "not an admin",
+			input:      map[string]interface{}{"user": "alice", "verb": "list", "resource": "secrets"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "missing user",
+			admin:      true,
+			input:      map[string]interface{}{"verb": "list", "resource": "secrets"},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "missing resource",
+			admin:      true,
+			input:      map[string]interface{}{"user": "alice", "verb": "list"},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			explainer := &fakeExplainer{result: test.result}
+			store := &Store{explainer: explainer}
+
+			obj, err := store.Create(newRequest(test.admin), nil, types.APIObject{Object: test.input})
+			if test.wantStatus != 0 {
+				require.Error(t, err)
+				assert.Equal(t, test.wantStatus, err.(*apierror.APIError).Code.Status)
+				assert.Empty(t, explainer.calls)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Len(t, explainer.calls, 1)
+			assert.Equal(t, *test.wantCall, explainer.calls[0])
+			review := obj.Object.(AccessReview)
+			assert.Equal(t, test.wantAllowed, review.Allowed)
+			assert.Equal(t, test.wantReason, review.Reason)
+			assert.Equal(t, test.wantGrants, review.Grants)
+		})
+	}
+}
+
+func TestWhoCanList(t *testing.T) {
+	deleteRule := rbacv1.PolicyRule{
+		Verbs:     []string{"delete"},
+		APIGroups: []string{""},
+		Resources: []string{"secrets"},
+	}
+	adminRule := rbacv1.PolicyRule{
+		Verbs:     []string{"*"},
+		APIGroups: []string{"*"},
+		Resources: []string{"*"},
+	}
+	grants := []accesscontrol.AccessGrant{
+		{
+			SubjectKind: "User", SubjectName: "alice",
+			BindingKind: "ClusterRoleBinding", BindingName: "admins",
+			RoleKind: "ClusterRole", RoleName: "admin", Rule: adminRule,
+		},
+		{
+			SubjectKind: "ServiceAccount", SubjectName: "cleaner", SubjectNamespace: "prod",
+			BindingKind: "RoleBinding", BindingName: "secrets", BindingNamespace: "prod",
+			RoleKind: "Role", RoleName: "secrets", Rule: deleteRule,
+		},
+		{
+			SubjectKind: "ServiceAccount", SubjectName: "cleaner", SubjectNamespace: "prod",
+			BindingKind: "RoleBinding", BindingName: "secrets", BindingNamespace: "prod",
+			RoleKind: "Role", RoleName: "secrets", Rule: adminRule,
+		},
+	}
+	tests := []struct {
+		name       string
+		admin      bool
+		query      string
+		wantStatus int
+		wantCall   *explanation
+		wantIDs    []string
+	}{
+		{
+			name:  "subjects",
+			admin: true,
+			query: "verb=delete&resource=secrets&namespace=prod",
+			wantCall: &explanation{
+				verb:      "delete",
+				gr:        schema.GroupResource{Resource: "secrets"},
+				namespace: "prod",
+			},
+			wantIDs: []string{
+				"ClusterRoleBinding:admins:User:alice",
+				"RoleBinding:prod/secrets:ServiceAccount:prod/cleaner",
+				"RoleBinding:prod/secrets:ServiceAccount:prod/cleaner:1",
+			},
+		},
+		{
+			name:  "named resource in a group",
+			admin: true,
+			query: "verb=get&apiGroup=apps&resource=deployments&namespace=dev&name=web",
+			wantCall: &explanation{
+				verb:      "get",
+				gr:        schema.GroupResource{Group: "apps", Resource: "deployments"},
+				namespace: "dev",
+				name:      "web",
+			},
+			wantIDs: []string{
+				"ClusterRoleBinding:admins:User:alice",
+				"RoleBinding:prod/secrets:ServiceAccount:prod/cleaner",
+				"RoleBinding:prod/secrets:ServiceAccount:prod/cleaner:1",
+			},
+		},
+		{
+			name:       "not an admin",
+			query:      "verb=delete&resource=secrets",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "missing verb",
+			admin:      true,
+			query:      "resource=secrets",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			explainer := &fakeExplainer{grants: grants}
+			store := &WhoCanStore{explainer: explainer}
+			apiOp := newRequest(test.admin)
+			apiOp.Method = http.MethodGet
+			apiOp.Request = httptest.NewRequest(http.MethodGet, "/v1/whocan?"+test.query, nil)
+
+			list, err := store.List(apiOp, nil)
+			if test.wantStatus != 0 {
+				require.Error(t, err)
+				assert.Equal(t, test.wantStatus, err.(*apierror.APIError).Code.Status)
+				assert.Empty(t, explainer.calls)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Len(t, explainer.calls, 1)
+			assert.Equal(t, *test.wantCall, explainer.calls[0])
+			var ids []string
+			for i, obj := range list.Objects {
+				ids = append(ids, obj.ID)
+				assert.Equal(t, Grant(grants[i]), obj.Object)
+			}
+			assert.Equal(t, test.wantIDs, ids)
+		})
+	}
+}
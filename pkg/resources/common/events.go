@@ -0,0 +1,97 @@
+package common
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+const (
+	eventsLink     = "events"
+	eventSchemaID  = "event"
+	eventsSortDesc = "-lastTimestamp"
+)
+
+// customizeEventsLink adds the events link to namespaced schemas, listing the events whose involvedObject is the
+// requested object
+func customizeEventsLink(apiSchema *types.APISchema) {
+	if !attributes.Namespaced(apiSchema) || attributes.GVK(apiSchema).Kind == "" {
+		return
+	}
+	if apiSchema.LinkHandlers == nil {
+		apiSchema.LinkHandlers = map[string]http.Handler{}
+	}
+	apiSchema.LinkHandlers[eventsLink] = &EventsHandler{}
+}
+
+// removeEventsLink hides the events link of an object if the user isn't allowed to list events
+func removeEventsLink(request *types.APIRequest, resource *types.RawResource) {
+	if request.Schemas == nil || request.Schemas.LookupSchema(eventSchemaID) == nil {
+		delete(resource.Links, eventsLink)
+	}
+}
+
+// EventsHandler lists the events of an object, keeping the list parameters of the request
+type EventsHandler struct{}
+
+func (e *EventsHandler) ServeHTTP(_ http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+
+	// the object was already read by the store, checking the user can get it
+	obj, err := apiOp.Schema.Store.ByID(apiOp, apiOp.Schema, apiOp.Name)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	m, err := meta.Accessor(obj.Object)
+	if err != nil {
+		apiOp.WriteError(apierror.NewAPIError(validation.ServerError, err.Error()))
+		return
+	}
+
+	eventSchema := apiOp.Schemas.LookupSchema(eventSchemaID)
+	if eventSchema == nil || eventSchema.Store == nil {
+		apiOp.WriteError(apierror.NewAPIError(validation.PermissionDenied, "can not list events"))
+		return
+	}
+
+	eventOp := eventsRequest(apiOp, eventSchema, m.GetNamespace(), string(m.GetUID()))
+	if err := eventOp.AccessControl.CanList(eventOp, eventSchema); err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	list, err := eventSchema.Store.List(eventOp, eventSchema)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	eventOp.WriteResponseList(http.StatusOK, list)
+}
+
+// eventsRequest builds a list request for the events of the given object, keeping the list parameters of the
+// original request so that events can be further filtered, sorted and paginated
+func eventsRequest(apiOp *types.APIRequest, eventSchema *types.APISchema, namespace, uid string) *types.APIRequest {
+	query := apiOp.Request.URL.Query()
+	query.Del("link")
+	query.Add("filter", "involvedObject.uid="+uid)
+	if query.Get("sort") == "" {
+		query.Set("sort", eventsSortDesc)
+	}
+
+	req := apiOp.Request.Clone(apiOp.Context())
+	req.URL.RawQuery = query.Encode()
+
+	eventOp := *apiOp
+	eventOp.Type = eventSchema.ID
+	eventOp.Schema = eventSchema
+	eventOp.Namespace = namespace
+	eventOp.Name = ""
+	eventOp.Link = ""
+	eventOp.Query = query
+	eventOp.Request = req
+	return &eventOp
+}
@@ -0,0 +1,185 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/handlers"
+	"github.com/rancher/apiserver/pkg/server"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type eventsTestStore struct {
+	empty.Store
+	listOp *types.APIRequest
+}
+
+func (e *eventsTestStore) ByID(_ *types.APIRequest, _ *types.APISchema, id string) (types.APIObject, error) {
+	return types.APIObject{
+		ID: id,
+		Object: &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns", UID: "1234"},
+		},
+	}, nil
+}
+
+func (e *eventsTestStore) List(apiOp *types.APIRequest, _ *types.APISchema) (types.APIObjectList, error) {
+	e.listOp = apiOp
+	return types.APIObjectList{Objects: []types.APIObject{{ID: "ns/event"}}}, nil
+}
+
+type eventsTestWriter struct {
+	list *types.APIObjectList
+}
+
+func (e *eventsTestWriter) Write(_ *types.APIRequest, _ int, _ types.APIObject) {}
+
+func (e *eventsTestWriter) WriteList(_ *types.APIRequest, _ int, list types.APIObjectList) {
+	e.list = &list
+}
+
+func TestEventsHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		canListEvents bool
+		wantErr       bool
+		wantQuery     url.Values
+	}{
+		{
+			name:          "events sorted by last timestamp",
+			query:         "link=events",
+			canListEvents: true,
+			wantQuery: url.Values{
+				"filter": {"involvedObject.uid=1234"},
+				"sort":   {"-lastTimestamp"},
+			},
+		},
+		{
+			name:          "list parameters are kept",
+			query:         "link=events&filter=type=Warning&sort=metadata.name&pagesize=10",
+			canListEvents: true,
+			wantQuery: url.Values{
+				"filter":   {"type=Warning", "involvedObject.uid=1234"},
+				"sort":     {"metadata.name"},
+				"pagesize": {"10"},
+			},
+		},
+		{
+			name:    "events can not be listed",
+			query:   "link=events",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			podSchema := &types.APISchema{
+				Schema: &schemas.Schema{
+					ID:              "pod",
+					ResourceMethods: []string{http.MethodGet},
+				},
+				Store: &eventsTestStore{},
+			}
+			attributes.SetNamespaced(podSchema, true)
+			attributes.SetGVK(podSchema, schema2.GroupVersionKind{Version: "v1", Kind: "Pod"})
+			customizeEventsLink(podSchema)
+			require.IsType(t, &EventsHandler{}, podSchema.LinkHandlers[eventsLink])
+
+			eventStore := &eventsTestStore{}
+			eventSchema := &types.APISchema{
+				Schema: &schemas.Schema{ID: eventSchemaID},
+				Store:  eventStore,
+			}
+			if test.canListEvents {
+				eventSchema.CollectionMethods = []string{http.MethodGet}
+			}
+			apiSchemas := types.EmptyAPISchemas()
+			apiSchemas.MustAddSchema(*podSchema)
+			apiSchemas.MustAddSchema(*eventSchema)
+
+			writer := &eventsTestWriter{}
+			var handlerErr error
+			req := httptest.NewRequest(http.MethodGet, "/v1/pods/ns/pod?"+test.query, nil)
+			apiOp := types.StoreAPIContext(&types.APIRequest{
+				Type:           "pod",
+				Schema:         podSchema,
+				Schemas:        apiSchemas,
+				Namespace:      "ns",
+				Name:           "pod",
+				Link:           eventsLink,
+				Method:         http.MethodGet,
+				Query:          req.URL.Query(),
+				Request:        req,
+				Response:       httptest.NewRecorder(),
+				ResponseWriter: writer,
+				AccessControl:  &server.SchemaBasedAccess{},
+				ErrorHandler: func(_ *types.APIRequest, err error) {
+					handlerErr = err
+				},
+			})
+
+			// the apiserver serves the link once it read the object
+			_, err := handlers.ByIDHandler(apiOp)
+			assert.Equal(t, validation.ErrComplete, err)
+			if test.wantErr {
+				assert.Error(t, handlerErr)
+				assert.Nil(t, eventStore.listOp)
+				assert.Nil(t, writer.list)
+				return
+			}
+			require.NoError(t, handlerErr)
+			require.NotNil(t, eventStore.listOp)
+			assert.Equal(t, eventSchemaID, eventStore.listOp.Type)
+			assert.Equal(t, "ns", eventStore.listOp.Namespace)
+			assert.Empty(t, eventStore.listOp.Name)
+			assert.Equal(t, test.wantQuery, eventStore.listOp.Query)
+			assert.Equal(t, test.wantQuery, eventStore.listOp.Request.URL.Query())
+			require.NotNil(t, writer.list)
+			assert.Len(t, writer.list.Objects, 1)
+		})
+	}
+}
+
+func TestRemoveEventsLink(t *testing.T) {
+	podSchema := &types.APISchema{Schema: &schemas.Schema{ID: "pod"}}
+	withEvents := types.EmptyAPISchemas()
+	withEvents.MustAddSchema(types.APISchema{Schema: &schemas.Schema{ID: eventSchemaID}})
+
+	tests := []struct {
+		name      string
+		schemas   *types.APISchemas
+		wantLinks map[string]string
+	}{
+		{
+			name:      "events can be listed",
+			schemas:   withEvents,
+			wantLinks: map[string]string{"self": "self", eventsLink: "events"},
+		},
+		{
+			name:      "events can not be listed",
+			schemas:   types.EmptyAPISchemas(),
+			wantLinks: map[string]string{"self": "self"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resource := &types.RawResource{
+				Schema: podSchema,
+				Links:  map[string]string{"self": "self", eventsLink: "events"},
+			}
+			removeEventsLink(&types.APIRequest{Schemas: test.schemas}, resource)
+			assert.Equal(t, test.wantLinks, resource.Links)
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/redaction"
 	"github.com/rancher/steve/pkg/resources/virtual/common"
 	"github.com/sirupsen/logrus"
 
@@ -29,6 +30,11 @@ import (
 
 type TemplateOptions struct {
 	InSQLMode bool
+	// Redaction, if set, redacts fields of the objects returned to users who can't reveal them
+	Redaction *redaction.Policy
+	// ClientGetter, if set, is used by the actions and links added by DefaultSchemaTemplatesForStore. Without it,
+	// those aren't added. DefaultSchemaTemplates always uses its client factory instead.
+	ClientGetter proxy.ClientGetter
 }
 
 func DefaultTemplate(clientGetter proxy.ClientGetter,
@@ -39,6 +45,7 @@ func DefaultTemplate(clientGetter proxy.ClientGetter,
 	return schema.Template{
 		Store:     metricsStore.NewMetricsStore(proxy.NewProxyStore(clientGetter, summaryCache, asl, namespaceCache)),
 		Formatter: formatter(summaryCache, asl, options),
+		Customize: customizeLinks(summaryCache),
 	}
 }
 
@@ -50,6 +57,17 @@ func DefaultTemplateForStore(store types.Store,
 	return schema.Template{
 		Store:     store,
 		Formatter: formatter(summaryCache, asl, options),
+		Customize: customizeLinks(summaryCache),
+	}
+}
+
+// customizeLinks adds the events and graph links to the schemas of Kubernetes types
+func customizeLinks(summaryCache *summarycache.SummaryCache) func(*types.APISchema) {
+	return func(apiSchema *types.APISchema) {
+		customizeEventsLink(apiSchema)
+		if summaryCache != nil {
+			customizeGraphLink(summaryCache, apiSchema)
+		}
 	}
 }
 
@@ -158,6 +176,7 @@ func formatter(summarycache common.SummaryCache, asl accesscontrol.AccessSetLook
 		} else {
 			delete(resource.Links, "patch")
 		}
+		removeEventsLink(request, resource)
 
 		gvk := attributes.GVK(resource.Schema)
 		if unstr, ok := resource.APIObject.Object.(*unstructured.Unstructured); ok {
@@ -174,6 +193,10 @@ func formatter(summarycache common.SummaryCache, asl accesscontrol.AccessSetLook
 
 			summary.NormalizeConditions(unstr)
 
+			if options.Redaction.Matches(gvk) && !accessSet.Grants(redaction.RevealVerb, gvr.GroupResource(), resource.APIObject.Namespace(), resource.APIObject.Name()) {
+				options.Redaction.Redact(gvk, unstr.Object)
+			}
+
 			includeFields(request, unstr)
 			excludeFields(request, unstr)
 			excludeValues(request, unstr)
@@ -13,6 +13,7 @@ import (
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/accesscontrol/fake"
 	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/redaction"
 	"github.com/rancher/steve/pkg/resources/virtual/common"
 	"github.com/rancher/wrangler/v3/pkg/schemas"
 	"github.com/rancher/wrangler/v3/pkg/summary"
@@ -1363,3 +1364,75 @@ func TestFormatterAddsResourcePermissions(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatterRedaction(t *testing.T) {
+	policy, err := redaction.NewPolicy([]redaction.Rule{{Kind: "Secret", Paths: []string{".data"}}})
+	require.NoError(t, err)
+	secretsSchema := &types.APISchema{
+		Schema: &schemas.Schema{
+			ID: "secret",
+			Attributes: map[string]interface{}{
+				"group":    "",
+				"version":  "v1",
+				"kind":     "Secret",
+				"resource": "secrets",
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		verbs    []string
+		wantData interface{}
+	}{
+		{
+			name:     "redacted",
+			verbs:    []string{"get"},
+			wantData: map[string]interface{}{"password": redaction.Redacted},
+		},
+		{
+			name:     "revealed",
+			verbs:    []string{"get", redaction.RevealVerb},
+			wantData: map[string]interface{}{"password": "c2VjcmV0"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			accessSet := &accesscontrol.AccessSet{}
+			for _, verb := range test.verbs {
+				accessSet.Add(verb, schema2.GroupResource{Resource: "secrets"}, accesscontrol.Access{Namespace: "default", ResourceName: accesscontrol.All})
+			}
+			ctrl := gomock.NewController(t)
+			asl := fake.NewMockAccessSetLookup(ctrl)
+			asl.EXPECT().AccessFor(gomock.Any()).Return(accessSet).AnyTimes()
+
+			ctx := request.WithUser(context.Background(), &user.DefaultInfo{Name: "alice"})
+			httpRequest, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/v1/secrets/default/db", nil)
+			req := &types.APIRequest{
+				Request:    httpRequest,
+				URLBuilder: &urlbuilder.DefaultURLBuilder{},
+				Schemas:    types.EmptyAPISchemas(),
+			}
+			resource := &types.RawResource{
+				Schema: secretsSchema,
+				APIObject: types.APIObject{
+					ID: "default/db",
+					Object: &unstructured.Unstructured{Object: map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "Secret",
+						"metadata":   map[string]interface{}{"name": "db", "namespace": "default"},
+						"data":       map[string]interface{}{"password": "c2VjcmV0"},
+					}},
+				},
+				Links: map[string]string{},
+			}
+			fakeCache := &common.FakeSummaryCache{
+				SummarizedObject: &summary.SummarizedObject{},
+			}
+
+			formatter(fakeCache, asl, TemplateOptions{Redaction: policy})(req, resource)
+
+			assert.Equal(t, test.wantData, resource.APIObject.Object.(*unstructured.Unstructured).Object["data"])
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/summarycache"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/rancher/wrangler/v3/pkg/summary"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	graphLink         = "graph"
+	defaultGraphDepth = 1
+	maxGraphDepth     = 5
+	// maxGraphNodes bounds the size of a graph, as a few hops from a node or a namespace can reach most of a cluster
+	maxGraphNodes = 500
+)
+
+// relationshipCache resolves the relationships between the objects of the cluster
+type relationshipCache interface {
+	SummaryAndRelationship(runtime.Object) (*summary.SummarizedObject, []summarycache.Relationship)
+	Related(summarycache.Relationship) []runtime.Object
+}
+
+// Graph is the graph of the objects related to an object, as returned by the graph link
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+	// Truncated is set when more objects were related than the graph can hold
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// GraphNode is an object of a Graph, with its summary state. Depth is the number of relationships from the requested
+// object.
+type GraphNode struct {
+	ID    string     `json:"id"`
+	Type  string     `json:"type"`
+	Depth int        `json:"depth"`
+	State GraphState `json:"state"`
+}
+
+// GraphState is the summary state of a node, as in metadata.state
+type GraphState struct {
+	Name          string `json:"name"`
+	Error         bool   `json:"error"`
+	Transitioning bool   `json:"transitioning"`
+	Message       string `json:"message"`
+}
+
+// GraphEdge is a relationship between two nodes of a Graph, such as owner or selects
+type GraphEdge struct {
+	FromID   string `json:"fromId"`
+	FromType string `json:"fromType"`
+	ToID     string `json:"toId"`
+	ToType   string `json:"toType"`
+	Rel      string `json:"rel"`
+}
+
+type graphKey struct {
+	schemaID string
+	id       string
+}
+
+// customizeGraphLink adds the graph link to the schemas of Kubernetes types
+func customizeGraphLink(cache relationshipCache, apiSchema *types.APISchema) {
+	if attributes.GVK(apiSchema).Kind == "" {
+		return
+	}
+	if apiSchema.LinkHandlers == nil {
+		apiSchema.LinkHandlers = map[string]http.Handler{}
+	}
+	apiSchema.LinkHandlers[graphLink] = &GraphHandler{cache: cache}
+}
+
+// GraphHandler walks the relationships of an object transitively, up to the number of hops given by the depth
+// parameter. Objects the user can't get are left out of the graph, along with the objects only related through them.
+type GraphHandler struct {
+	cache relationshipCache
+}
+
+func (g *GraphHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+
+	depth := defaultGraphDepth
+	if value := apiOp.Request.URL.Query().Get("depth"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			apiOp.WriteError(apierror.NewAPIError(validation.InvalidFormat, "depth must be a non-negative number"))
+			return
+		}
+		depth = min(n, maxGraphDepth)
+	}
+
+	// the object was already read by the store, checking the user can get it
+	root, err := g.root(apiOp)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+
+	result := g.walk(apiOp, root, depth)
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(rw).Encode(result); err != nil {
+		logrus.Debugf("Failed to write the graph of %s %s: %v", apiOp.Type, apiOp.Name, err)
+	}
+}
+
+// root returns the requested object from the cache, or from the store if it isn't cached
+func (g *GraphHandler) root(apiOp *types.APIRequest) (runtime.Object, error) {
+	id := apiOp.Name
+	if apiOp.Namespace != "" {
+		id = apiOp.Namespace + "/" + apiOp.Name
+	}
+	if objs := g.cache.Related(summarycache.Relationship{ToType: apiOp.Schema.ID, ToID: id}); len(objs) == 1 {
+		return objs[0], nil
+	}
+	obj, err := apiOp.Schema.Store.ByID(apiOp, apiOp.Schema, apiOp.Name)
+	if err != nil {
+		return nil, err
+	}
+	ro, ok := obj.Object.(runtime.Object)
+	if !ok {
+		return nil, apierror.NewAPIError(validation.NotFound, "no relationships found for "+id)
+	}
+	return ro, nil
+}
+
+func (g *GraphHandler) walk(apiOp *types.APIRequest, root runtime.Object, depth int) *Graph {
+	type queued struct {
+		obj   runtime.Object
+		key   graphKey
+		depth int
+	}
+
+	result := &Graph{
+		Nodes: []GraphNode{},
+		Edges: []GraphEdge{},
+	}
+	seen := map[graphKey]bool{}
+	edges := map[GraphEdge]bool{}
+	addNode := func(obj runtime.Object, key graphKey, depth int) {
+		s, _ := g.cache.SummaryAndRelationship(obj)
+		seen[key] = true
+		result.Nodes = append(result.Nodes, GraphNode{
+			ID:    key.id,
+			Type:  key.schemaID,
+			Depth: depth,
+			State: GraphState{
+				Name:          s.State,
+				Error:         s.Error,
+				Transitioning: s.Transitioning,
+				Message:       strings.Join(s.Message, ":"),
+			},
+		})
+	}
+
+	rootKey := graphKey{schemaID: apiOp.Schema.ID, id: objectID(root)}
+	addNode(root, rootKey, 0)
+	queue := []queued{{obj: root, key: rootKey}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current.depth >= depth {
+			continue
+		}
+
+		_, rels := g.cache.SummaryAndRelationship(current.obj)
+		for _, rel := range rels {
+			schemaID := rel.ToType
+			if rel.FromType != "" {
+				schemaID = rel.FromType
+			}
+			for _, obj := range g.cache.Related(rel) {
+				key := graphKey{schemaID: schemaID, id: objectID(obj)}
+				if key == current.key || !canGet(apiOp, key) {
+					continue
+				}
+				if !seen[key] {
+					if len(result.Nodes) >= maxGraphNodes {
+						result.Truncated = true
+						continue
+					}
+					addNode(obj, key, current.depth+1)
+					queue = append(queue, queued{obj: obj, key: key, depth: current.depth + 1})
+				}
+
+				edge := GraphEdge{FromID: current.key.id, FromType: current.key.schemaID, ToID: key.id, ToType: key.schemaID, Rel: rel.Rel}
+				if rel.FromType != "" {
+					edge = GraphEdge{FromID: key.id, FromType: key.schemaID, ToID: current.key.id, ToType: current.key.schemaID, Rel: rel.Rel}
+				}
+				if !edges[edge] {
+					edges[edge] = true
+					result.Edges = append(result.Edges, edge)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// canGet returns whether the user can get the given object, according to the access of their schemas
+func canGet(apiOp *types.APIRequest, key graphKey) bool {
+	apiSchema := apiOp.Schemas.LookupSchema(key.schemaID)
+	if apiSchema == nil {
+		return false
+	}
+	namespace, name := "", key.id
+	if i := strings.Index(key.id, "/"); i >= 0 {
+		namespace, name = key.id[:i], key.id[i+1:]
+	}
+	access := accesscontrol.GetAccessListMap(apiSchema)
+	return access["get"].Grants(namespace, name) || access["list"].Grants(namespace, name)
+}
+
+func objectID(obj runtime.Object) string {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	if m.GetNamespace() == "" {
+		return m.GetName()
+	}
+	return m.GetNamespace() + "/" + m.GetName()
+}
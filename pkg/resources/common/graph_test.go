@@ -0,0 +1,201 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/summarycache"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/summary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type graphTestObject struct {
+	obj  *summary.SummarizedObject
+	rels []summarycache.Relationship
+}
+
+// graphTestCache holds the objects by schema ID and ID
+type graphTestCache map[graphKey]graphTestObject
+
+func (g graphTestCache) add(schemaID, namespace, name, state string, rels ...summarycache.Relationship) {
+	obj := &summary.SummarizedObject{
+		PartialObjectMetadata: metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		},
+		Summary: summary.Summary{State: state},
+	}
+	id := name
+	if namespace != "" {
+		id = namespace + "/" + name
+	}
+	g[graphKey{schemaID: schemaID, id: id}] = graphTestObject{obj: obj, rels: rels}
+}
+
+func (g graphTestCache) SummaryAndRelationship(obj runtime.Object) (*summary.SummarizedObject, []summarycache.Relationship) {
+	for _, o := range g {
+		if o.obj == obj {
+			return o.obj, o.rels
+		}
+	}
+	return obj.(*summary.SummarizedObject), nil
+}
+
+func (g graphTestCache) Related(rel summarycache.Relationship) []runtime.Object {
+	key := graphKey{schemaID: rel.ToType, id: rel.ToID}
+	if rel.FromType != "" {
+		key = graphKey{schemaID: rel.FromType, id: rel.FromID}
+	}
+	if o, ok := g[key]; ok {
+		return []runtime.Object{o.obj}
+	}
+	return nil
+}
+
+// newGraphTestCache returns the chain Ingress -> Service -> Pod -> Node/PVC -> PV, as related by the summary cache
+func newGraphTestCache() graphTestCache {
+	cache := graphTestCache{}
+	cache.add("networking.k8s.io.ingress", "web", "ing", "active",
+		summarycache.Relationship{ToType: "service", ToID: "web/svc", Rel: "uses"})
+	cache.add("service", "web", "svc", "active",
+		summarycache.Relationship{FromType: "networking.k8s.io.ingress", FromID: "web/ing", Rel: "uses"},
+		summarycache.Relationship{ToType: "pod", ToID: "web/pod", Rel: "selects"})
+	cache.add("pod", "web", "pod", "running",
+		summarycache.Relationship{FromType: "service", FromID: "web/svc", Rel: "selects"},
+		summarycache.Relationship{ToType: "node", ToID: "node1", Rel: "uses"},
+		summarycache.Relationship{ToType: "persistentvolumeclaim", ToID: "web/data", Rel: "uses"})
+	cache.add("node", "", "node1", "active",
+		summarycache.Relationship{FromType: "pod", FromID: "web/pod", Rel: "uses"})
+	cache.add("persistentvolumeclaim", "web", "data", "bound",
+		summarycache.Relationship{FromType: "pod", FromID: "web/pod", Rel: "uses"},
+		summarycache.Relationship{ToType: "persistentvolume", ToID: "pv1", Rel: "uses"})
+	cache.add("persistentvolume", "", "pv1", "bound",
+		summarycache.Relationship{FromType: "persistentvolumeclaim", FromID: "web/data", Rel: "uses"})
+	return cache
+}
+
+func TestGraphHandler(t *testing.T) {
+	allSchemas := []string{"networking.k8s.io.ingress", "service", "pod", "node", "persistentvolumeclaim", "persistentvolume"}
+
+	tests := []struct {
+		name      string
+		query     string
+		schemas   []string
+		wantCode  int
+		wantNodes map[string]int
+		wantEdges []GraphEdge
+	}{
+		{
+			name:     "default depth",
+			schemas:  allSchemas,
+			wantCode: http.StatusOK,
+			wantNodes: map[string]int{
+				"networking.k8s.io.ingress web/ing": 0,
+				"service web/svc":                   1,
+			},
+			wantEdges: []GraphEdge{
+				{FromType: "networking.k8s.io.ingress", FromID: "web/ing", ToType: "service", ToID: "web/svc", Rel: "uses"},
+			},
+		},
+		{
+			name:     "whole chain",
+			query:    "depth=10",
+			schemas:  allSchemas,
+			wantCode: http.StatusOK,
+			wantNodes: map[string]int{
+				"networking.k8s.io.ingress web/ing": 0,
+				"service web/svc":                   1,
+				"pod web/pod":                       2,
+				"node node1":                        3,
+				"persistentvolumeclaim web/data":    3,
+				"persistentvolume pv1":              4,
+			},
+			wantEdges: []GraphEdge{
+				{FromType: "networking.k8s.io.ingress", FromID: "web/ing", ToType: "service", ToID: "web/svc", Rel: "uses"},
+				{FromType: "service", FromID: "web/svc", ToType: "pod", ToID: "web/pod", Rel: "selects"},
+				{FromType: "pod", FromID: "web/pod", ToType: "node", ToID: "node1", Rel: "uses"},
+				{FromType: "pod", FromID: "web/pod", ToType: "persistentvolumeclaim", ToID: "web/data", Rel: "uses"},
+				{FromType: "persistentvolumeclaim", FromID: "web/data", ToType: "persistentvolume", ToID: "pv1", Rel: "uses"},
+			},
+		},
+		{
+			name:     "pruned to what the user can get",
+			query:    "depth=10",
+			schemas:  []string{"networking.k8s.io.ingress", "service", "pod", "persistentvolume"},
+			wantCode: http.StatusOK,
+			wantNodes: map[string]int{
+				"networking.k8s.io.ingress web/ing": 0,
+				"service web/svc":                   1,
+				"pod web/pod":                       2,
+			},
+			wantEdges: []GraphEdge{
+				{FromType: "networking.k8s.io.ingress", FromID: "web/ing", ToType: "service", ToID: "web/svc", Rel: "uses"},
+				{FromType: "service", FromID: "web/svc", ToType: "pod", ToID: "web/pod", Rel: "selects"},
+			},
+		},
+		{
+			name:     "invalid depth",
+			query:    "depth=all",
+			schemas:  allSchemas,
+			wantCode: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			apiSchemas := types.EmptyAPISchemas()
+			for _, id := range test.schemas {
+				s := &types.APISchema{Schema: &schemas.Schema{ID: id}}
+				attributes.SetAccess(s, accesscontrol.AccessListByVerb{
+					"list": accesscontrol.AccessList{{Namespace: accesscontrol.All, ResourceName: accesscontrol.All}},
+				})
+				apiSchemas.MustAddSchema(*s)
+			}
+			ingressSchema := apiSchemas.LookupSchema("networking.k8s.io.ingress")
+			attributes.SetGVK(ingressSchema, schema2.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"})
+			customizeGraphLink(newGraphTestCache(), ingressSchema)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/networking.k8s.io.ingresses/web/ing?link=graph&"+test.query, nil)
+			rw := httptest.NewRecorder()
+			apiOp := types.StoreAPIContext(&types.APIRequest{
+				Type:      ingressSchema.ID,
+				Schema:    ingressSchema,
+				Schemas:   apiSchemas,
+				Namespace: "web",
+				Name:      "ing",
+				Link:      graphLink,
+				Method:    http.MethodGet,
+				Request:   req,
+				Response:  rw,
+				ErrorHandler: func(apiOp *types.APIRequest, err error) {
+					http.Error(apiOp.Response, err.Error(), err.(*apierror.APIError).Code.Status)
+				},
+			})
+			ingressSchema.LinkHandlers[graphLink].ServeHTTP(rw, apiOp.Request)
+
+			require.Equal(t, test.wantCode, rw.Code)
+			if test.wantCode != http.StatusOK {
+				return
+			}
+			var graph Graph
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &graph))
+			nodes := map[string]int{}
+			for _, node := range graph.Nodes {
+				nodes[node.Type+" "+node.ID] = node.Depth
+			}
+			assert.Equal(t, test.wantNodes, nodes)
+			assert.ElementsMatch(t, test.wantEdges, graph.Edges)
+			assert.Equal(t, "active", graph.Nodes[0].State.Name)
+			assert.False(t, graph.Truncated)
+		})
+	}
+}
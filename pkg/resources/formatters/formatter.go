@@ -102,6 +102,20 @@ func decodeHelm3(data string) (any, error) {
 	return &rls, nil
 }
 
+// DecodeHelm3Secret decodes the helm3 release stored by a helm storage secret into v, given the value of the release
+// key of the secret data, as returned by the Kubernetes API
+func DecodeHelm3Secret(data string, v any) error {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return err
+	}
+	rls, err := decodeHelm3(string(b))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(*rls.(*json.RawMessage), v)
+}
+
 // decodeHelm2 receives a helm2 release data and returns the corresponding helm2 release proto struct
 func decodeHelm2(data string) (*rspb.Release, error) {
 	b, err := base64.StdEncoding.DecodeString(data)
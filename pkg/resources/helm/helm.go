@@ -0,0 +1,50 @@
+// Package helm registers the read-only helm.release schema, listing the Helm v3 releases stored in secrets.
+package helm
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/redaction"
+)
+
+const (
+	schemaID = "helm.release"
+
+	historyLink  = "history"
+	valuesLink   = "values"
+	manifestLink = "manifest"
+)
+
+// Release is the latest revision of a Helm release
+type Release struct {
+	ID           string `json:"id,omitempty"`
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace"`
+	Chart        string `json:"chart"`
+	ChartVersion string `json:"chartVersion"`
+	AppVersion   string `json:"appVersion"`
+	Revision     int    `json:"revision"`
+	Status       string `json:"status"`
+	Updated      string `json:"updated"`
+}
+
+// Register adds the helm.release schema. Releases are built from the helm storage secrets the user can list, so
+// that a user only sees the releases whose secrets they can read. Secrets whose release data is redacted for the user
+// by the given policy are left out.
+func Register(apiSchemas *types.APISchemas, redactionPolicy *redaction.Policy) {
+	store := &Store{redaction: redactionPolicy}
+	apiSchemas.InternalSchemas.TypeName(schemaID, Release{})
+	apiSchemas.MustImportAndCustomize(Release{}, func(schema *types.APISchema) {
+		schema.CollectionMethods = []string{http.MethodGet}
+		schema.ResourceMethods = []string{http.MethodGet}
+		attributes.SetNamespaced(schema, true)
+		schema.Store = store
+		schema.LinkHandlers = map[string]http.Handler{
+			historyLink:  http.HandlerFunc(store.serveHistory),
+			valuesLink:   http.HandlerFunc(store.serveValues),
+			manifestLink: http.HandlerFunc(store.serveManifest),
+		}
+	})
+}
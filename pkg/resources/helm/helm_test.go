@@ -0,0 +1,379 @@
+package helm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/server"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/redaction"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// helmSecret returns a helm storage secret for the given revision of a release, as returned by the Kubernetes API
+func helmSecret(t *testing.T, namespace, name string, revision int, status, chartVersion string) types.APIObject {
+	rls, err := json.Marshal(map[string]interface{}{
+		"name":      name,
+		"namespace": namespace,
+		"version":   revision,
+		"info": map[string]interface{}{
+			"status":        status,
+			"last_deployed": "2024-05-0" + strconv.Itoa(revision) + "T10:00:00.123456789+02:00",
+		},
+		"chart": map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name":       name + "-chart",
+				"version":    chartVersion,
+				"appVersion": "v" + chartVersion,
+			},
+		},
+		"config":   map[string]interface{}{"replicas": revision},
+		"manifest": "kind: ConfigMap\nrevision: " + strconv.Itoa(revision) + "\n",
+	})
+	require.NoError(t, err)
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, err = w.Write(rls)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	stored := base64.StdEncoding.EncodeToString(gz.Bytes())
+
+	return types.APIObject{
+		ID: namespace + "/sh.helm.release.v1." + name + ".v" + strconv.Itoa(revision),
+		Object: &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"type":       releaseSecretType,
+			"metadata": map[string]interface{}{
+				"name":      "sh.helm.release.v1." + name + ".v" + strconv.Itoa(revision),
+				"namespace": namespace,
+				"labels": map[string]interface{}{
+					"owner":   "helm",
+					"name":    name,
+					"status":  status,
+					"version": strconv.Itoa(revision),
+				},
+			},
+			"data": map[string]interface{}{
+				"release": base64.StdEncoding.EncodeToString([]byte(stored)),
+			},
+		}},
+	}
+}
+
+// secretStore emulates the filtering of secrets by namespace and label done by the stores of steve
+type secretStore struct {
+	empty.Store
+	secrets []types.APIObject
+	listOps []*types.APIRequest
+}
+
+func (s *secretStore) List(apiOp *types.APIRequest, _ *types.APISchema) (types.APIObjectList, error) {
+	s.listOps = append(s.listOps, apiOp)
+	var result types.APIObjectList
+	for _, secret := range s.secrets {
+		d := secret.Data()
+		if apiOp.Namespace != "" && d.String("metadata", "namespace") != apiOp.Namespace {
+			continue
+		}
+		result.Objects = append(result.Objects, secret)
+	}
+	return result, nil
+}
+
+type testWriter struct {
+	obj  *types.APIObject
+	list *types.APIObjectList
+}
+
+func (w *testWriter) Write(_ *types.APIRequest, _ int, obj types.APIObject) {
+	w.obj = &obj
+}
+
+func (w *testWriter) WriteList(_ *types.APIRequest, _ int, list types.APIObjectList) {
+	w.list = &list
+}
+
+func newRequest(t *testing.T, secrets *secretStore, namespace, name, query string) (*types.APIRequest, *httptest.ResponseRecorder) {
+	apiSchemas := types.EmptyAPISchemas()
+	Register(apiSchemas, nil)
+	if secrets != nil {
+		secretSchema := types.APISchema{
+			Schema: &schemas.Schema{
+				ID:                secretSchemaID,
+				CollectionMethods: []string{http.MethodGet},
+			},
+			Store: secrets,
+		}
+		attributes.SetGVK(&secretSchema, schema.GroupVersionKind{Version: "v1", Kind: "Secret"})
+		attributes.SetGVR(&secretSchema, schema.GroupVersionResource{Version: "v1", Resource: "secrets"})
+		apiSchemas.MustAddSchema(secretSchema)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/v1/helm.releases?"+query, nil)
+	rw := httptest.NewRecorder()
+	apiOp := types.StoreAPIContext(&types.APIRequest{
+		Type:           schemaID,
+		Schema:         apiSchemas.LookupSchema(schemaID),
+		Schemas:        apiSchemas,
+		Namespace:      namespace,
+		Name:           name,
+		Method:         http.MethodGet,
+		Query:          req.URL.Query(),
+		Request:        req,
+		Response:       rw,
+		ResponseWriter: &testWriter{},
+		AccessControl:  &server.SchemaBasedAccess{},
+	})
+	return apiOp, rw
+}
+
+func testSecrets(t *testing.T) *secretStore {
+	return &secretStore{
+		secrets: []types.APIObject{
+			helmSecret(t, "web", "nginx", 1, "superseded", "1.0.0"),
+			helmSecret(t, "web", "nginx", 2, "deployed", "1.1.0"),
+			helmSecret(t, "db", "postgres", 1, "failed", "12.0.0"),
+			helmSecret(t, "db", "redis", 1, "deployed", "7.0.0"),
+		},
+	}
+}
+
+func ids(list types.APIObjectList) []string {
+	var result []string
+	for _, obj := range list.Objects {
+		result = append(result, obj.ID)
+	}
+	return result
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		query     string
+		wantIDs   []string
+	}{
+		{
+			name:    "latest revisions sorted by namespace and name",
+			wantIDs: []string{"db/postgres", "db/redis", "web/nginx"},
+		},
+		{
+			name:      "namespace",
+			namespace: "db",
+			wantIDs:   []string{"db/postgres", "db/redis"},
+		},
+		{
+			name:    "filter",
+			query:   "filter=status=deployed",
+			wantIDs: []string{"db/redis", "web/nginx"},
+		},
+		{
+			name:    "sort by revision",
+			query:   "sort=-revision,name",
+			wantIDs: []string{"web/nginx", "db/postgres", "db/redis"},
+		},
+		{
+			name:    "pagination",
+			query:   "sort=-updated&pagesize=2&page=2",
+			wantIDs: []string{"db/redis"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			secrets := testSecrets(t)
+			apiOp, _ := newRequest(t, secrets, test.namespace, "", test.query)
+			list, err := apiOp.Schema.Store.List(apiOp, apiOp.Schema)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantIDs, ids(list))
+
+			require.Len(t, secrets.listOps, 1)
+			assert.Equal(t, secretSchemaID, secrets.listOps[0].Type)
+			assert.Equal(t, test.namespace, secrets.listOps[0].Namespace)
+			assert.Equal(t, []string{"metadata.labels.owner=helm"}, secrets.listOps[0].Request.URL.Query()["filter"])
+		})
+	}
+}
+
+func TestListWithoutSecretAccess(t *testing.T) {
+	apiOp, _ := newRequest(t, nil, "", "", "")
+	list, err := apiOp.Schema.Store.List(apiOp, apiOp.Schema)
+	require.NoError(t, err)
+	assert.Empty(t, list.Objects)
+}
+
+func TestByID(t *testing.T) {
+	secrets := testSecrets(t)
+	apiOp, _ := newRequest(t, secrets, "web", "nginx", "")
+	obj, err := apiOp.Schema.Store.ByID(apiOp, apiOp.Schema, "nginx")
+	require.NoError(t, err)
+	assert.Equal(t, "web/nginx", obj.ID)
+	assert.Equal(t, map[string]interface{}{
+		"id":           "web/nginx",
+		"name":         "nginx",
+		"namespace":    "web",
+		"chart":        "nginx-chart",
+		"chartVersion": "1.1.0",
+		"appVersion":   "v1.1.0",
+		"revision":     2,
+		"status":       "deployed",
+		"updated":      "2024-05-02T08:00:00Z",
+	}, obj.Object)
+	assert.Equal(t, []string{"metadata.labels.owner=helm", "metadata.labels.name=nginx"}, secrets.listOps[0].Request.URL.Query()["filter"])
+
+	apiOp, _ = newRequest(t, secrets, "db", "nginx", "")
+	_, err = apiOp.Schema.Store.ByID(apiOp, apiOp.Schema, "nginx")
+	assert.Error(t, err)
+}
+
+func TestHistory(t *testing.T) {
+	apiOp, _ := newRequest(t, testSecrets(t), "web", "nginx", "link=history")
+	apiOp.Schema.LinkHandlers[historyLink].ServeHTTP(apiOp.Response, apiOp.Request)
+
+	list := apiOp.ResponseWriter.(*testWriter).list
+	require.NotNil(t, list)
+	require.Len(t, list.Objects, 2)
+	assert.Equal(t, 2, list.Objects[0].Data()["revision"])
+	assert.Equal(t, 1, list.Objects[1].Data()["revision"])
+	assert.Equal(t, "superseded", list.Objects[1].Data()["status"])
+}
+
+func TestValuesAndManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		link     string
+		query    string
+		wantCode int
+		wantType string
+		wantBody string
+	}{
+		{
+			name:     "values of the latest revision",
+			link:     valuesLink,
+			wantCode: http.StatusOK,
+			wantType: "application/json",
+			wantBody: `{"replicas":2}` + "\n",
+		},
+		{
+			name:     "values of a revision",
+			link:     valuesLink,
+			query:    "revision=1",
+			wantCode: http.StatusOK,
+			wantType: "application/json",
+			wantBody: `{"replicas":1}` + "\n",
+		},
+		{
+			name:     "manifest of a revision",
+			link:     manifestLink,
+			query:    "revision=1",
+			wantCode: http.StatusOK,
+			wantType: "application/yaml",
+			wantBody: "kind: ConfigMap\nrevision: 1\n",
+		},
+		{
+			name:     "unknown revision",
+			link:     manifestLink,
+			query:    "revision=3",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "invalid revision",
+			link:     valuesLink,
+			query:    "revision=latest",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			apiOp, rw := newRequest(t, testSecrets(t), "web", "nginx", "link="+test.link+"&"+test.query)
+			apiOp.ErrorHandler = func(apiOp *types.APIRequest, err error) {
+				http.Error(apiOp.Response, err.Error(), err.(*apierror.APIError).Code.Status)
+			}
+			apiOp.Schema.LinkHandlers[test.link].ServeHTTP(apiOp.Response, apiOp.Request)
+
+			assert.Equal(t, test.wantCode, rw.Code)
+			if test.wantCode != http.StatusOK {
+				return
+			}
+			assert.Equal(t, test.wantType, rw.Header().Get("Content-Type"))
+			assert.Equal(t, test.wantBody, rw.Body.String())
+		})
+	}
+}
+
+func TestRedactedReleases(t *testing.T) {
+	policy, err := redaction.NewPolicy([]redaction.Rule{{Version: "v1", Kind: "Secret", Paths: []string{".data"}}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		revealIn     string
+		wantIDs      []string
+		wantValues   int
+		wantManifest int
+	}{
+		{
+			name:         "data redacted in all namespaces",
+			wantValues:   http.StatusNotFound,
+			wantManifest: http.StatusNotFound,
+		},
+		{
+			name:         "data revealed in a namespace",
+			revealIn:     "web",
+			wantIDs:      []string{"web/nginx"},
+			wantValues:   http.StatusOK,
+			wantManifest: http.StatusOK,
+		},
+		{
+			name:         "data revealed in other namespaces",
+			revealIn:     "db",
+			wantIDs:      []string{"db/postgres", "db/redis"},
+			wantValues:   http.StatusNotFound,
+			wantManifest: http.StatusNotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := func(namespace, name, query string) (*types.APIRequest, *httptest.ResponseRecorder) {
+				apiOp, rw := newRequest(t, testSecrets(t), namespace, name, query)
+				apiOp.Schema.Store.(*Store).redaction = policy
+				accessSet := &accesscontrol.AccessSet{}
+				if test.revealIn != "" {
+					accessSet.Add(redaction.RevealVerb, schema.GroupResource{Resource: "secrets"},
+						accesscontrol.Access{Namespace: test.revealIn, ResourceName: accesscontrol.All})
+				}
+				accesscontrol.SetAccessSetAttribute(apiOp.Schemas, accessSet)
+				apiOp.ErrorHandler = func(apiOp *types.APIRequest, err error) {
+					http.Error(apiOp.Response, err.Error(), err.(*apierror.APIError).Code.Status)
+				}
+				return apiOp, rw
+			}
+
+			apiOp, _ := request("", "", "")
+			list, err := apiOp.Schema.Store.List(apiOp, apiOp.Schema)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantIDs, ids(list))
+
+			apiOp, rw := request("web", "nginx", "link=values")
+			apiOp.Schema.LinkHandlers[valuesLink].ServeHTTP(apiOp.Response, apiOp.Request)
+			assert.Equal(t, test.wantValues, rw.Code)
+
+			apiOp, rw = request("web", "nginx", "link=manifest")
+			apiOp.Schema.LinkHandlers[manifestLink].ServeHTTP(apiOp.Response, apiOp.Request)
+			assert.Equal(t, test.wantManifest, rw.Code)
+		})
+	}
+}
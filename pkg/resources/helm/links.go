@@ -0,0 +1,94 @@
+package helm
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+)
+
+// serveHistory lists all the revisions of a release, the latest first
+func (s *Store) serveHistory(_ http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+	secrets, err := s.listSecrets(apiOp, apiOp.Namespace, apiOp.Name)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	slices.SortFunc(secrets, func(a, b storageSecret) int {
+		return b.revision - a.revision
+	})
+
+	var list types.APIObjectList
+	for _, secret := range secrets {
+		rls, err := secret.decode()
+		if err != nil {
+			logrus.Debugf("Failed to decode revision %d of helm release %s/%s: %v", secret.revision, secret.namespace, secret.release, err)
+			continue
+		}
+		list.Objects = append(list.Objects, rls.toAPIObject())
+	}
+	list.Count = len(list.Objects)
+	apiOp.WriteResponseList(http.StatusOK, list)
+}
+
+// serveValues writes the values supplied by the user for a revision of a release, given by the revision parameter,
+// as JSON
+func (s *Store) serveValues(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+	rls, err := s.requestedRevision(apiOp)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	values := rls.Config
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(rw).Encode(values); err != nil {
+		logrus.Debugf("Failed to write the values of helm release %s/%s: %v", apiOp.Namespace, apiOp.Name, err)
+	}
+}
+
+// serveManifest writes the manifest rendered for a revision of a release, given by the revision parameter, as YAML
+func (s *Store) serveManifest(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+	rls, err := s.requestedRevision(apiOp)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/yaml")
+	rw.WriteHeader(http.StatusOK)
+	if _, err := rw.Write([]byte(rls.Manifest)); err != nil {
+		logrus.Debugf("Failed to write the manifest of helm release %s/%s: %v", apiOp.Namespace, apiOp.Name, err)
+	}
+}
+
+// requestedRevision decodes the revision of the release given by the revision parameter, defaulting to the latest one
+func (s *Store) requestedRevision(apiOp *types.APIRequest) (*storedRelease, error) {
+	var revision int
+	if value := apiOp.Request.URL.Query().Get("revision"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return nil, apierror.NewAPIError(validation.InvalidFormat, "revision must be a positive number")
+		}
+		revision = n
+	}
+	secret, err := s.findRevision(apiOp, apiOp.Namespace, apiOp.Name, revision)
+	if err != nil {
+		return nil, err
+	}
+	rls, err := secret.decode()
+	if err != nil {
+		return nil, apierror.NewAPIError(validation.ServerError, err.Error())
+	}
+	return rls, nil
+}
@@ -0,0 +1,292 @@
+package helm
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/redaction"
+	"github.com/rancher/steve/pkg/resources/formatters"
+	"github.com/rancher/steve/pkg/stores/partition/listprocessor"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	secretSchemaID    = "secret"
+	releaseSecretType = "helm.sh/release.v1"
+	helmOwner         = "helm"
+)
+
+// storageSecret is a helm storage secret, holding a single revision of a release
+type storageSecret struct {
+	namespace string
+	release   string
+	revision  int
+	data      string
+}
+
+// storedRelease is the part of a revision of a release used by this package, as stored by helm
+type storedRelease struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Version   int    `json:"version"`
+	Info      struct {
+		Status       string `json:"status"`
+		LastDeployed string `json:"last_deployed"`
+	} `json:"info"`
+	Chart struct {
+		Metadata struct {
+			Name       string `json:"name"`
+			Version    string `json:"version"`
+			AppVersion string `json:"appVersion"`
+		} `json:"metadata"`
+	} `json:"chart"`
+	Config   map[string]interface{} `json:"config"`
+	Manifest string                 `json:"manifest"`
+}
+
+func (s *storageSecret) decode() (*storedRelease, error) {
+	var rls storedRelease
+	if err := formatters.DecodeHelm3Secret(s.data, &rls); err != nil {
+		return nil, err
+	}
+	return &rls, nil
+}
+
+func (r *storedRelease) toAPIObject() types.APIObject {
+	updated := r.Info.LastDeployed
+	if t, err := time.Parse(time.RFC3339Nano, updated); err == nil {
+		updated = t.UTC().Format(time.RFC3339)
+	}
+	release := Release{
+		ID:           r.Namespace + "/" + r.Name,
+		Name:         r.Name,
+		Namespace:    r.Namespace,
+		Chart:        r.Chart.Metadata.Name,
+		ChartVersion: r.Chart.Metadata.Version,
+		AppVersion:   r.Chart.Metadata.AppVersion,
+		Revision:     r.Version,
+		Status:       r.Info.Status,
+		Updated:      updated,
+	}
+	// the object is kept as a map, so that it can be filtered by the listprocessor
+	return types.APIObject{
+		Type: schemaID,
+		ID:   release.ID,
+		Object: map[string]interface{}{
+			"id":           release.ID,
+			"name":         release.Name,
+			"namespace":    release.Namespace,
+			"chart":        release.Chart,
+			"chartVersion": release.ChartVersion,
+			"appVersion":   release.AppVersion,
+			"revision":     release.Revision,
+			"status":       release.Status,
+			"updated":      release.Updated,
+		},
+	}
+}
+
+// Store lists the latest revision of the releases found in the helm storage secrets the user can list
+type Store struct {
+	empty.Store
+	redaction *redaction.Policy
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, _ *types.APISchema, id string) (types.APIObject, error) {
+	secret, err := s.findRevision(apiOp, apiOp.Namespace, id, 0)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	rls, err := secret.decode()
+	if err != nil {
+		return types.APIObject{}, apierror.NewAPIError(validation.ServerError, err.Error())
+	}
+	return rls.toAPIObject(), nil
+}
+
+// List supports the filter, sort, page and pagesize parameters, as for other types. Fields are sorted as strings,
+// except for the revision.
+func (s *Store) List(apiOp *types.APIRequest, _ *types.APISchema) (types.APIObjectList, error) {
+	secrets, err := s.listSecrets(apiOp, apiOp.Namespace, "")
+	if err != nil {
+		return types.APIObjectList{}, err
+	}
+
+	var items []unstructured.Unstructured
+	for _, secret := range latestRevisions(secrets) {
+		rls, err := secret.decode()
+		if err != nil {
+			logrus.Debugf("Failed to decode helm release %s/%s: %v", secret.namespace, secret.release, err)
+			continue
+		}
+		items = append(items, unstructured.Unstructured{Object: rls.toAPIObject().Object.(map[string]interface{})})
+	}
+
+	opts := listprocessor.ParseQuery(apiOp)
+	list := make(chan []unstructured.Unstructured, 1)
+	list <- items
+	close(list)
+	filtered := listprocessor.FilterList(list, opts.Filters)
+	sortOpts := opts.Sort
+	if len(sortOpts.Fields) == 0 {
+		// releases are sorted by namespace and name by default
+		sortOpts = listprocessor.Sort{
+			Fields: [][]string{{"namespace"}, {"name"}},
+			Orders: []listprocessor.SortOrder{listprocessor.ASC, listprocessor.ASC},
+		}
+	}
+	listprocessor.SortValues(filtered, sortOpts)
+	page, pages := listprocessor.PaginateList(filtered, opts.Pagination)
+
+	result := types.APIObjectList{
+		Count: len(filtered),
+		Pages: pages,
+	}
+	for _, item := range page {
+		result.Objects = append(result.Objects, types.APIObject{
+			Type:   schemaID,
+			ID:     convert.ToString(item.Object["id"]),
+			Object: item.Object,
+		})
+	}
+	return result, nil
+}
+
+// latestRevisions only keeps the latest revision of each release
+func latestRevisions(secrets []storageSecret) []storageSecret {
+	latest := map[string]int{}
+	var result []storageSecret
+	for _, secret := range secrets {
+		key := secret.namespace + "/" + secret.release
+		if i, ok := latest[key]; ok {
+			if result[i].revision < secret.revision {
+				result[i] = secret
+			}
+			continue
+		}
+		latest[key] = len(result)
+		result = append(result, secret)
+	}
+	return result
+}
+
+// findRevision returns the storage secret of the given revision of a release, or of its latest revision if revision
+// is 0
+func (s *Store) findRevision(apiOp *types.APIRequest, namespace, release string, revision int) (storageSecret, error) {
+	var secrets []storageSecret
+	if namespace != "" {
+		var err error
+		secrets, err = s.listSecrets(apiOp, namespace, release)
+		if err != nil {
+			return storageSecret{}, err
+		}
+	}
+	if revision == 0 {
+		secrets = latestRevisions(secrets)
+	}
+	for _, secret := range secrets {
+		if revision == 0 || secret.revision == revision {
+			return secret, nil
+		}
+	}
+	if revision == 0 {
+		return storageSecret{}, apierror.NewAPIError(validation.NotFound, "release "+namespace+"/"+release+" not found")
+	}
+	return storageSecret{}, apierror.NewAPIError(validation.NotFound,
+		"revision "+strconv.Itoa(revision)+" of release "+namespace+"/"+release+" not found")
+}
+
+// listSecrets lists the helm storage secrets the user can list, in the given namespace and for the given release if
+// not empty. Secrets are listed with the store of the secret schema, so that the access of the user is enforced as
+// for any list of secrets, and the secrets whose release data is redacted for the user are left out.
+func (s *Store) listSecrets(apiOp *types.APIRequest, namespace, release string) ([]storageSecret, error) {
+	secretSchema := apiOp.Schemas.LookupSchema(secretSchemaID)
+	if secretSchema == nil || secretSchema.Store == nil {
+		return nil, nil
+	}
+	secretOp := secretsRequest(apiOp, secretSchema, namespace, release)
+	if err := secretOp.AccessControl.CanList(secretOp, secretSchema); err != nil {
+		return nil, nil
+	}
+	list, err := secretSchema.Store.List(secretOp, secretSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []storageSecret
+	for _, obj := range list.Objects {
+		d := obj.Data()
+		if d.String("type") != releaseSecretType || d.String("metadata", "labels", "owner") != helmOwner {
+			continue
+		}
+		name := d.String("metadata", "labels", "name")
+		if release != "" && name != release {
+			continue
+		}
+		revision, err := strconv.Atoi(d.String("metadata", "labels", "version"))
+		if err != nil {
+			continue
+		}
+		secret := storageSecret{
+			namespace: d.String("metadata", "namespace"),
+			release:   name,
+			revision:  revision,
+			data:      d.String("data", "release"),
+		}
+		if s.redacted(apiOp, secretSchema, d.String("metadata", "name"), secret) {
+			continue
+		}
+		result = append(result, secret)
+	}
+	return result, nil
+}
+
+// redacted returns whether the release data of a storage secret is redacted for the user, as it would be in the
+// secret itself
+func (s *Store) redacted(apiOp *types.APIRequest, secretSchema *types.APISchema, name string, secret storageSecret) bool {
+	gvk := attributes.GVK(secretSchema)
+	if !s.redaction.Matches(gvk) {
+		return false
+	}
+	accessSet := accesscontrol.AccessSetFromAPIRequest(apiOp)
+	if accessSet != nil && accessSet.Grants(redaction.RevealVerb, attributes.GVR(secretSchema).GroupResource(), secret.namespace, name) {
+		return false
+	}
+	secretData := map[string]interface{}{"release": secret.data}
+	s.redaction.Redact(gvk, map[string]interface{}{"data": secretData})
+	return secretData["release"] != secret.data
+}
+
+// secretsRequest builds a list request for the helm storage secrets, filtered by their labels
+func secretsRequest(apiOp *types.APIRequest, secretSchema *types.APISchema, namespace, release string) *types.APIRequest {
+	query := url.Values{}
+	query.Add("filter", "metadata.labels.owner="+helmOwner)
+	if release != "" {
+		query.Add("filter", "metadata.labels.name="+release)
+	}
+
+	req := apiOp.Request.Clone(apiOp.Context())
+	req.Method = http.MethodGet
+	req.URL.RawQuery = query.Encode()
+
+	secretOp := *apiOp
+	secretOp.Type = secretSchema.ID
+	secretOp.Schema = secretSchema
+	secretOp.Method = http.MethodGet
+	secretOp.Namespace = namespace
+	secretOp.Name = ""
+	secretOp.Link = ""
+	secretOp.Query = query
+	secretOp.Request = req
+	return &secretOp
+}
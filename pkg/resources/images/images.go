@@ -0,0 +1,325 @@
+// Package images registers the read-only image schema, listing the container images used by the pods and workloads
+// of the cluster.
+package images
+
+import (
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/steve/pkg/stores/partition/listprocessor"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	schemaID = "image"
+
+	projectsOrNamespacesParam = "projectsornamespaces"
+	defaultRegistry           = "docker.io"
+	defaultTag                = "latest"
+)
+
+// containerPaths are the paths of the containers of each type using images, whose images are indexed by the SQL cache
+var containerPaths = map[string][][]string{
+	"pod": {
+		{"spec", "containers"},
+		{"spec", "initContainers"},
+		{"spec", "ephemeralContainers"},
+	},
+	"replicationcontroller": templateContainerPaths("spec", "template"),
+	"apps.daemonset":        templateContainerPaths("spec", "template"),
+	"apps.deployment":       templateContainerPaths("spec", "template"),
+	"apps.replicaset":       templateContainerPaths("spec", "template"),
+	"apps.statefulset":      templateContainerPaths("spec", "template"),
+	"batch.cronjob":         templateContainerPaths("spec", "jobTemplate", "spec", "template"),
+	"batch.job":             templateContainerPaths("spec", "template"),
+}
+
+// templateContainerPaths returns the paths of the containers of a pod template, which can't have ephemeral containers
+func templateContainerPaths(template ...string) [][]string {
+	var paths [][]string
+	for _, containers := range []string{"containers", "initContainers"} {
+		paths = append(paths, append(slices.Clone(template), "spec", containers))
+	}
+	return paths
+}
+
+// Image is a container image, with the objects using it
+type Image struct {
+	ID         string   `json:"id,omitempty"`
+	Registry   string   `json:"registry"`
+	Repository string   `json:"repository"`
+	Tag        string   `json:"tag"`
+	Digest     string   `json:"digest"`
+	Namespaces []string `json:"namespaces"`
+	UsedBy     []User   `json:"usedBy"`
+	// Count is the number of objects using the image
+	Count int `json:"count"`
+}
+
+// User is an object using an image
+type User struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Register adds the image schema. Images are read from the objects of the types the user can list, so that a user
+// only sees the images used by the objects they can read.
+func Register(apiSchemas *types.APISchemas) {
+	apiSchemas.InternalSchemas.TypeName(schemaID, Image{})
+	apiSchemas.MustImportAndCustomize(Image{}, func(schema *types.APISchema) {
+		schema.CollectionMethods = []string{http.MethodGet}
+		schema.Store = &Store{}
+	})
+}
+
+// FieldValuesLister lists the ID and the value of an indexed field of each object of a list, such as the SQL cache
+// store. The values of list fields are joined with "|".
+type FieldValuesLister interface {
+	ListFieldValues(apiOp *types.APIRequest, schema *types.APISchema, field []string) ([][]string, error)
+}
+
+// SetFieldValuesLister makes the image store read the images from the indexed fields of the lister, instead of
+// listing the objects using them
+func SetFieldValuesLister(apiSchemas *types.APISchemas, lister FieldValuesLister) {
+	schema := apiSchemas.LookupSchema(schemaID)
+	if schema == nil {
+		return
+	}
+	if store, ok := schema.Store.(*Store); ok {
+		store.lister = lister
+	}
+}
+
+// Store lists the distinct images used by the pods and workloads the user can list
+type Store struct {
+	empty.Store
+	lister FieldValuesLister
+}
+
+// List supports the filter, sort, page, pagesize and projectsornamespaces parameters. Images are sorted by their ID,
+// the normalized image reference, by default.
+func (s *Store) List(apiOp *types.APIRequest, _ *types.APISchema) (types.APIObjectList, error) {
+	images := map[string]*imageUsers{}
+	var ids []string
+	addImage := func(schemaID, objID, namespace, ref string) {
+		registry, repository, tag, digest := parseReference(ref)
+		id := formatReference(registry, repository, tag, digest)
+		image, ok := images[id]
+		if !ok {
+			image = &imageUsers{
+				image: map[string]interface{}{
+					"id":         id,
+					"registry":   registry,
+					"repository": repository,
+					"tag":        tag,
+					"digest":     digest,
+				},
+				users:      map[User]bool{},
+				namespaces: map[string]bool{},
+			}
+			images[id] = image
+			ids = append(ids, id)
+		}
+		image.add(schemaID, objID, namespace)
+	}
+
+	for _, schemaID := range slices.Sorted(maps.Keys(containerPaths)) {
+		source := apiOp.Schemas.LookupSchema(schemaID)
+		if source == nil || source.Store == nil {
+			continue
+		}
+		sourceOp := sourceRequest(apiOp, source)
+		if err := sourceOp.AccessControl.CanList(sourceOp, source); err != nil {
+			continue
+		}
+		if s.lister != nil {
+			if err := s.listIndexedImages(sourceOp, source, addImage); err != nil {
+				return types.APIObjectList{}, err
+			}
+			continue
+		}
+
+		list, err := source.Store.List(sourceOp, source)
+		if err != nil {
+			return types.APIObjectList{}, err
+		}
+		for _, obj := range list.Objects {
+			d := obj.Data()
+			namespace := d.String("metadata", "namespace")
+			for _, path := range containerPaths[schemaID] {
+				for _, container := range d.Slice(path...) {
+					if ref := container.String("image"); ref != "" {
+						addImage(schemaID, obj.ID, namespace, ref)
+					}
+				}
+			}
+		}
+	}
+
+	items := make([]unstructured.Unstructured, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, unstructured.Unstructured{Object: images[id].object()})
+	}
+
+	opts := listprocessor.ParseQuery(apiOp)
+	list := make(chan []unstructured.Unstructured, 1)
+	list <- items
+	close(list)
+	filtered := listprocessor.FilterList(list, opts.Filters)
+	sortOpts := opts.Sort
+	if len(sortOpts.Fields) == 0 {
+		sortOpts = listprocessor.Sort{
+			Fields: [][]string{{"id"}},
+			Orders: []listprocessor.SortOrder{listprocessor.ASC},
+		}
+	}
+	listprocessor.SortValues(filtered, sortOpts)
+	page, pages := listprocessor.PaginateList(filtered, opts.Pagination)
+
+	result := types.APIObjectList{
+		Count: len(filtered),
+		Pages: pages,
+	}
+	for _, item := range page {
+		result.Objects = append(result.Objects, types.APIObject{
+			Type:   schemaID,
+			ID:     convert.ToString(item.Object["id"]),
+			Object: item.Object,
+		})
+	}
+	return result, nil
+}
+
+// listIndexedImages reads the images of a type from the indexed image fields of its containers, without listing its
+// objects
+func (s *Store) listIndexedImages(apiOp *types.APIRequest, source *types.APISchema, addImage func(schemaID, objID, namespace, ref string)) error {
+	for _, path := range containerPaths[source.ID] {
+		values, err := s.lister.ListFieldValues(apiOp, source, append(slices.Clone(path), "image"))
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			if len(value) != 2 {
+				continue
+			}
+			objID := value[0]
+			namespace, _, ok := strings.Cut(objID, "/")
+			if !ok {
+				namespace = ""
+			}
+			for _, ref := range strings.Split(value[1], "|") {
+				if ref != "" {
+					addImage(source.ID, objID, namespace, ref)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// imageUsers is an image being aggregated, with the sets of the objects using it and of their namespaces
+type imageUsers struct {
+	image      map[string]interface{}
+	usedBy     []User
+	users      map[User]bool
+	namespaces map[string]bool
+}
+
+// add records that an object uses the image, once even if several of its containers use it
+func (i *imageUsers) add(schemaID, id, namespace string) {
+	user := User{Type: schemaID, ID: id}
+	if i.users[user] {
+		return
+	}
+	i.users[user] = true
+	i.usedBy = append(i.usedBy, user)
+	if namespace != "" {
+		i.namespaces[namespace] = true
+	}
+}
+
+// object returns the image with the objects using it, in the order they were listed, and its sorted namespaces
+func (i *imageUsers) object() map[string]interface{} {
+	usedBy := make([]interface{}, 0, len(i.usedBy))
+	for _, user := range i.usedBy {
+		usedBy = append(usedBy, map[string]interface{}{"type": user.Type, "id": user.ID})
+	}
+	namespaces := make([]interface{}, 0, len(i.namespaces))
+	for _, namespace := range slices.Sorted(maps.Keys(i.namespaces)) {
+		namespaces = append(namespaces, namespace)
+	}
+	i.image["usedBy"] = usedBy
+	i.image["namespaces"] = namespaces
+	i.image["count"] = len(usedBy)
+	return i.image
+}
+
+// sourceRequest builds a list request for a type using images, only keeping the projectsornamespaces parameter, as
+// the other list parameters apply to the images
+func sourceRequest(apiOp *types.APIRequest, source *types.APISchema) *types.APIRequest {
+	query := url.Values{}
+	if values, ok := apiOp.Query[projectsOrNamespacesParam]; ok {
+		query[projectsOrNamespacesParam] = values
+	}
+	if values, ok := apiOp.Query[projectsOrNamespacesParam+"!"]; ok {
+		query[projectsOrNamespacesParam+"!"] = values
+	}
+
+	req := apiOp.Request.Clone(apiOp.Context())
+	req.URL.RawQuery = query.Encode()
+
+	sourceOp := *apiOp
+	sourceOp.Type = source.ID
+	sourceOp.Schema = source
+	sourceOp.Name = ""
+	sourceOp.Query = query
+	sourceOp.Request = req
+	return &sourceOp
+}
+
+// parseReference splits an image reference into its registry, repository, tag and digest, following the defaults of
+// the container runtimes: images without a registry are pulled from Docker Hub, and images without a tag or digest
+// use the latest tag.
+func parseReference(ref string) (registry, repository, tag, digest string) {
+	name := ref
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, digest = name[:i], name[i+1:]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i+1:]
+	}
+	if tag == "" && digest == "" {
+		tag = defaultTag
+	}
+
+	registry = defaultRegistry
+	if i := strings.Index(name, "/"); i >= 0 {
+		first := name[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			registry, name = first, name[i+1:]
+		}
+	}
+	if registry == defaultRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	return registry, name, tag, digest
+}
+
+// formatReference returns the normalized reference of an image, so that the different ways of referring to the same
+// image are counted together
+func formatReference(registry, repository, tag, digest string) string {
+	ref := registry + "/" + repository
+	if tag != "" {
+		ref += ":" + tag
+	}
+	if digest != "" {
+		ref += "@" + digest
+	}
+	return ref
+}
@@ -0,0 +1,359 @@
+package images
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/server"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// sourceStore returns its objects in the namespaces requested with projectsornamespaces
+type sourceStore struct {
+	empty.Store
+	objects []types.APIObject
+	listOps []*types.APIRequest
+}
+
+func (s *sourceStore) List(apiOp *types.APIRequest, _ *types.APISchema) (types.APIObjectList, error) {
+	s.listOps = append(s.listOps, apiOp)
+	var result types.APIObjectList
+	namespace := apiOp.Query.Get(projectsOrNamespacesParam)
+	for _, obj := range s.objects {
+		if namespace != "" && obj.Data().String("metadata", "namespace") != namespace {
+			continue
+		}
+		result.Objects = append(result.Objects, obj)
+	}
+	return result, nil
+}
+
+func containers(images ...string) []interface{} {
+	var result []interface{}
+	for _, image := range images {
+		result = append(result, map[string]interface{}{"image": image})
+	}
+	return result
+}
+
+func pod(namespace, name string, images ...string) types.APIObject {
+	return types.APIObject{
+		ID: namespace + "/" + name,
+		Object: &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name, "namespace": namespace},
+			"spec":     map[string]interface{}{"containers": containers(images...)},
+		}},
+	}
+}
+
+func deployment(namespace, name string, images ...string) types.APIObject {
+	return types.APIObject{
+		ID: namespace + "/" + name,
+		Object: &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name, "namespace": namespace},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{"containers": containers(images...)},
+				},
+			},
+		}},
+	}
+}
+
+func cronJob(namespace, name string, images ...string) types.APIObject {
+	return types.APIObject{
+		ID: namespace + "/" + name,
+		Object: &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name, "namespace": namespace},
+			"spec": map[string]interface{}{
+				"jobTemplate": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"template": map[string]interface{}{
+							"spec": map[string]interface{}{"containers": containers(images...)},
+						},
+					},
+				},
+			},
+		}},
+	}
+}
+
+func testSources() map[string]*sourceStore {
+	return map[string]*sourceStore{
+		"pod": {objects: []types.APIObject{
+			pod("web", "nginx-1", "nginx:1.25", "docker.io/library/nginx:1.25"),
+			pod("db", "postgres-0", "postgres"),
+		}},
+		"apps.deployment": {objects: []types.APIObject{
+			deployment("web", "nginx", "nginx:1.25", "registry.example.com:5000/proxy/envoy@sha256:abc"),
+		}},
+		"batch.cronjob": {objects: []types.APIObject{
+			cronJob("db", "backup", "ghcr.io/acme/backup:v2"),
+		}},
+	}
+}
+
+func newRequest(sources map[string]*sourceStore, query string) *types.APIRequest {
+	apiSchemas := types.EmptyAPISchemas()
+	Register(apiSchemas)
+	for id, store := range sources {
+		apiSchemas.MustAddSchema(types.APISchema{
+			Schema: &schemas.Schema{
+				ID:                id,
+				CollectionMethods: []string{http.MethodGet},
+			},
+			Store: store,
+		})
+	}
+	req := httptest.NewRequest(http.MethodGet, "/v1/images?"+query, nil)
+	return &types.APIRequest{
+		Type:          schemaID,
+		Schema:        apiSchemas.LookupSchema(schemaID),
+		Schemas:       apiSchemas,
+		Method:        http.MethodGet,
+		Query:         req.URL.Query(),
+		Request:       req,
+		AccessControl: &server.SchemaBasedAccess{},
+	}
+}
+
+func ids(list types.APIObjectList) []string {
+	var result []string
+	for _, obj := range list.Objects {
+		result = append(result, obj.ID)
+	}
+	return result
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantIDs   []string
+		wantCount int
+	}{
+		{
+			name: "all images",
+			wantIDs: []string{
+				"docker.io/library/nginx:1.25",
+				"docker.io/library/postgres:latest",
+				"ghcr.io/acme/backup:v2",
+				"registry.example.com:5000/proxy/envoy@sha256:abc",
+			},
+			wantCount: 4,
+		},
+		{
+			name:      "filtered by registry",
+			query:     "filter=registry=ghcr.io",
+			wantIDs:   []string{"ghcr.io/acme/backup:v2"},
+			wantCount: 1,
+		},
+		{
+			name:      "filtered by tag",
+			query:     "filter=tag=latest",
+			wantIDs:   []string{"docker.io/library/postgres:latest"},
+			wantCount: 1,
+		},
+		{
+			name:      "sorted by count and paged",
+			query:     "sort=-count,id&pagesize=1",
+			wantIDs:   []string{"docker.io/library/nginx:1.25"},
+			wantCount: 4,
+		},
+		{
+			name:      "restricted to namespaces",
+			query:     "projectsornamespaces=db",
+			wantIDs:   []string{"docker.io/library/postgres:latest", "ghcr.io/acme/backup:v2"},
+			wantCount: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sources := testSources()
+			apiOp := newRequest(sources, test.query)
+
+			list, err := apiOp.Schema.Store.List(apiOp, apiOp.Schema)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.wantIDs, ids(list))
+			assert.Equal(t, test.wantCount, list.Count)
+			for _, source := range sources {
+				require.Len(t, source.listOps, 1)
+				for name := range source.listOps[0].Query {
+					assert.Equal(t, projectsOrNamespacesParam, name)
+				}
+			}
+		})
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	apiOp := newRequest(testSources(), "filter=repository=library/nginx")
+
+	list, err := apiOp.Schema.Store.List(apiOp, apiOp.Schema)
+	require.NoError(t, err)
+	require.Len(t, list.Objects, 1)
+
+	d := list.Objects[0].Data()
+	assert.Equal(t, "docker.io", d.String("registry"))
+	assert.Equal(t, "library/nginx", d.String("repository"))
+	assert.Equal(t, "1.25", d.String("tag"))
+	assert.Equal(t, 2, d["count"])
+	assert.Equal(t, []interface{}{"web"}, d["namespaces"])
+	assert.ElementsMatch(t, []interface{}{
+		map[string]interface{}{"type": "pod", "id": "web/nginx-1"},
+		map[string]interface{}{"type": "apps.deployment", "id": "web/nginx"},
+	}, d["usedBy"])
+}
+
+func TestListWithoutAccess(t *testing.T) {
+	sources := testSources()
+	apiOp := newRequest(sources, "")
+	apiOp.Schemas.LookupSchema("pod").CollectionMethods = nil
+
+	list, err := apiOp.Schema.Store.List(apiOp, apiOp.Schema)
+	require.NoError(t, err)
+
+	assert.Empty(t, sources["pod"].listOps)
+	assert.Equal(t, []string{
+		"docker.io/library/nginx:1.25",
+		"ghcr.io/acme/backup:v2",
+		"registry.example.com:5000/proxy/envoy@sha256:abc",
+	}, ids(list))
+}
+
+func TestListInitAndEphemeralContainers(t *testing.T) {
+	withContainers := func(obj types.APIObject, images ...string) types.APIObject {
+		spec := obj.Data().Map("spec")
+		if template := spec.Map("template"); template != nil {
+			spec = template.Map("spec")
+		}
+		spec["initContainers"] = containers(images[0])
+		if len(images) > 1 {
+			spec["ephemeralContainers"] = containers(images[1])
+		}
+		return obj
+	}
+	sources := map[string]*sourceStore{
+		"pod": {objects: []types.APIObject{
+			withContainers(pod("web", "nginx-1", "nginx:1.25"), "busybox", "debian:12"),
+		}},
+		"apps.deployment": {objects: []types.APIObject{
+			withContainers(deployment("web", "nginx", "nginx:1.25"), "alpine:3.20"),
+		}},
+	}
+	apiOp := newRequest(sources, "")
+
+	list, err := apiOp.Schema.Store.List(apiOp, apiOp.Schema)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"docker.io/library/alpine:3.20",
+		"docker.io/library/busybox:latest",
+		"docker.io/library/debian:12",
+		"docker.io/library/nginx:1.25",
+	}, ids(list))
+}
+
+// fieldValuesLister returns the values of the image fields of each type, joined with "|" like in the SQL cache
+type fieldValuesLister struct {
+	values map[string]map[string][][]string
+	calls  []string
+}
+
+func (f *fieldValuesLister) ListFieldValues(apiOp *types.APIRequest, schema *types.APISchema, field []string) ([][]string, error) {
+	f.calls = append(f.calls, schema.ID+":"+strings.Join(field, "."))
+	var result [][]string
+	namespace := apiOp.Query.Get(projectsOrNamespacesParam)
+	for _, value := range f.values[schema.ID][strings.Join(field, ".")] {
+		if namespace != "" && !strings.HasPrefix(value[0], namespace+"/") {
+			continue
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+func TestListIndexedImages(t *testing.T) {
+	lister := &fieldValuesLister{values: map[string]map[string][][]string{
+		"pod": {
+			"spec.containers.image": {
+				{"web/nginx-1", "nginx:1.25|docker.io/library/nginx:1.25"},
+				{"db/postgres-0", "postgres"},
+			},
+			"spec.initContainers.image": {
+				{"web/nginx-1", "busybox"},
+				{"db/postgres-0", ""},
+			},
+		},
+		"apps.deployment": {
+			"spec.template.spec.containers.image": {
+				{"web/nginx", "nginx:1.25|registry.example.com:5000/proxy/envoy@sha256:abc"},
+			},
+		},
+	}}
+	sources := testSources()
+	apiOp := newRequest(sources, "")
+	SetFieldValuesLister(apiOp.Schemas, lister)
+
+	list, err := apiOp.Schema.Store.List(apiOp, apiOp.Schema)
+	require.NoError(t, err)
+
+	// the objects aren't listed, only the image fields of the types the user can list
+	for _, source := range sources {
+		assert.Empty(t, source.listOps)
+	}
+	assert.ElementsMatch(t, []string{
+		"apps.deployment:spec.template.spec.containers.image",
+		"apps.deployment:spec.template.spec.initContainers.image",
+		"batch.cronjob:spec.jobTemplate.spec.template.spec.containers.image",
+		"batch.cronjob:spec.jobTemplate.spec.template.spec.initContainers.image",
+		"pod:spec.containers.image",
+		"pod:spec.initContainers.image",
+		"pod:spec.ephemeralContainers.image",
+	}, lister.calls)
+	assert.Equal(t, []string{
+		"docker.io/library/busybox:latest",
+		"docker.io/library/nginx:1.25",
+		"docker.io/library/postgres:latest",
+		"registry.example.com:5000/proxy/envoy@sha256:abc",
+	}, ids(list))
+
+	d := list.Objects[1].Data()
+	assert.Equal(t, 2, d["count"])
+	assert.Equal(t, []interface{}{"web"}, d["namespaces"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "apps.deployment", "id": "web/nginx"},
+		map[string]interface{}{"type": "pod", "id": "web/nginx-1"},
+	}, d["usedBy"])
+}
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		ref                               string
+		registry, repository, tag, digest string
+	}{
+		{ref: "nginx", registry: "docker.io", repository: "library/nginx", tag: "latest"},
+		{ref: "bitnami/redis:7.2", registry: "docker.io", repository: "bitnami/redis", tag: "7.2"},
+		{ref: "localhost/app", registry: "localhost", repository: "app", tag: "latest"},
+		{ref: "registry.example.com:5000/team/app:1.0", registry: "registry.example.com:5000", repository: "team/app", tag: "1.0"},
+		{ref: "quay.io/org/app@sha256:abc", registry: "quay.io", repository: "org/app", digest: "sha256:abc"},
+		{ref: "quay.io/org/app:1.0@sha256:abc", registry: "quay.io", repository: "org/app", tag: "1.0", digest: "sha256:abc"},
+	}
+	for _, test := range tests {
+		t.Run(test.ref, func(t *testing.T) {
+			registry, repository, tag, digest := parseReference(test.ref)
+			assert.Equal(t, test.registry, registry)
+			assert.Equal(t, test.repository, repository)
+			assert.Equal(t, test.tag, tag)
+			assert.Equal(t, test.digest, digest)
+		})
+	}
+}
@@ -0,0 +1,263 @@
+// Package logs adds the logs link to pods and workloads, streaming the logs of all their containers at once.
+package logs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const logsLink = "logs"
+
+var (
+	podGVK = schema2.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	// workloadGVKs are the types whose pods are found with spec.selector
+	workloadGVKs = []schema2.GroupVersionKind{
+		{Group: "apps", Version: "v1", Kind: "Deployment"},
+		{Group: "apps", Version: "v1", Kind: "StatefulSet"},
+		{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+		{Group: "apps", Version: "v1", Kind: "ReplicaSet"},
+		{Group: "batch", Version: "v1", Kind: "Job"},
+	}
+
+	upgrader = websocket.Upgrader{
+		HandshakeTimeout:  60 * time.Second,
+		EnableCompression: true,
+	}
+)
+
+// Templates returns the templates adding the logs link to pods and workloads. Logs are read with the client of the
+// user making the request.
+func Templates(cg proxy.ClientGetter) []schema.Template {
+	result := []schema.Template{
+		linkTemplate(cg, podGVK),
+	}
+	for _, gvk := range workloadGVKs {
+		result = append(result, linkTemplate(cg, gvk))
+	}
+	return result
+}
+
+func linkTemplate(cg proxy.ClientGetter, gvk schema2.GroupVersionKind) schema.Template {
+	return schema.Template{
+		Group: gvk.Group,
+		Kind:  gvk.Kind,
+		Customize: func(apiSchema *types.APISchema) {
+			AddLink(cg, apiSchema)
+		},
+	}
+}
+
+// AddLink adds the logs link to the given schema
+func AddLink(cg proxy.ClientGetter, apiSchema *types.APISchema) {
+	if apiSchema.LinkHandlers == nil {
+		apiSchema.LinkHandlers = map[string]http.Handler{}
+	}
+	apiSchema.LinkHandlers[logsLink] = &Handler{
+		cg:         cg,
+		openStream: openStream,
+	}
+}
+
+// options are the options of a logs request, parsed from its query parameters
+type options struct {
+	// Container restricts the logs to a single container
+	Container string
+	// Follow keeps streaming logs, including the ones of pods started after the request
+	Follow     bool
+	Previous   bool
+	Timestamps bool
+	Since      time.Duration
+	TailLines  *int64
+	// Grep only keeps the lines matching the expression
+	Grep *regexp.Regexp
+}
+
+// parseOptions reads the options of a logs request from its query parameters
+func parseOptions(apiOp *types.APIRequest) (options, error) {
+	q := apiOp.Request.URL.Query()
+	opts := options{
+		Container:  q.Get("container"),
+		Follow:     parseBool(q.Get("follow")),
+		Previous:   parseBool(q.Get("previous")),
+		Timestamps: parseBool(q.Get("timestamps")),
+	}
+	if since := q.Get("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			return opts, apierror.NewAPIError(validation.InvalidFormat, "since must be a positive duration, eg. 5m")
+		}
+		opts.Since = d
+	}
+	if tail := q.Get("tailLines"); tail != "" {
+		n, err := strconv.ParseInt(tail, 10, 64)
+		if err != nil || n < 0 {
+			return opts, apierror.NewAPIError(validation.InvalidFormat, "tailLines must be a non-negative number")
+		}
+		opts.TailLines = &n
+	}
+	if grep := q.Get("grep"); grep != "" {
+		re, err := regexp.Compile(grep)
+		if err != nil {
+			return opts, apierror.NewAPIError(validation.InvalidFormat, fmt.Sprintf("invalid grep expression: %v", err))
+		}
+		opts.Grep = re
+	}
+	return opts, nil
+}
+
+func parseBool(value string) bool {
+	b, _ := strconv.ParseBool(value)
+	return b
+}
+
+func (o options) podLogOptions(container string) *corev1.PodLogOptions {
+	result := &corev1.PodLogOptions{
+		Container:  container,
+		Follow:     o.Follow,
+		Previous:   o.Previous,
+		Timestamps: o.Timestamps,
+		TailLines:  o.TailLines,
+	}
+	if o.Since > 0 {
+		seconds := int64(o.Since.Seconds())
+		result.SinceSeconds = &seconds
+	}
+	return result
+}
+
+type streamOpener func(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, error)
+
+func openStream(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+	return client.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, opts).Stream(ctx)
+}
+
+// Handler serves the logs of the pods of the requested object, over a websocket if the request asks for an upgrade, or
+// as a chunked plain text response otherwise
+type Handler struct {
+	cg         proxy.ClientGetter
+	openStream streamOpener
+}
+
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+
+	opts, err := parseOptions(apiOp)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	client, err := h.cg.K8sInterface(apiOp)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	selector, err := h.podSelector(apiOp)
+	if err != nil {
+		apiOp.WriteError(proxy.TranslateError(err))
+		return
+	}
+
+	f := &follower{
+		client:     client,
+		namespace:  apiOp.Namespace,
+		selector:   selector,
+		opts:       opts,
+		openStream: h.openStream,
+	}
+	pods, err := f.list(req.Context())
+	if err != nil {
+		apiOp.WriteError(proxy.TranslateError(err))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	if websocket.IsWebSocketUpgrade(req) {
+		conn, err := upgrader.Upgrade(rw, req, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		f.out = newWebsocketWriter(conn, cancel)
+	} else {
+		f.out = newHTTPWriter(rw)
+	}
+
+	if err := f.run(ctx, pods); err != nil {
+		_ = f.out.WriteLine(fmt.Sprintf("[error] %v", err))
+	}
+	f.out.Close()
+}
+
+// podSelector returns the selector of the pods of the requested object, which is either a pod or a workload
+func (h *Handler) podSelector(apiOp *types.APIRequest) (podSelector, error) {
+	if attributes.GVK(apiOp.Schema) == podGVK {
+		return podSelector{
+			fields: fields.OneTermEqualSelector("metadata.name", apiOp.Name),
+		}, nil
+	}
+
+	client, err := h.cg.Client(apiOp, apiOp.Schema, apiOp.Namespace, rest.NoWarnings{})
+	if err != nil {
+		return podSelector{}, err
+	}
+	obj, err := client.Get(apiOp.Context(), apiOp.Name, metav1.GetOptions{})
+	if err != nil {
+		return podSelector{}, err
+	}
+	data, ok, err := unstructured.NestedMap(obj.Object, "spec", "selector")
+	if err != nil || !ok {
+		return podSelector{}, apierror.NewAPIError(validation.InvalidType, apiOp.Name+" has no pod selector")
+	}
+	var labelSelector metav1.LabelSelector
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(data, &labelSelector); err != nil {
+		return podSelector{}, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
+	if err != nil {
+		return podSelector{}, err
+	}
+	if selector.Empty() {
+		return podSelector{}, apierror.NewAPIError(validation.InvalidType, apiOp.Name+" has no pod selector")
+	}
+	return podSelector{labels: selector}, nil
+}
+
+type podSelector struct {
+	labels labels.Selector
+	fields fields.Selector
+}
+
+func (p podSelector) listOptions() metav1.ListOptions {
+	var result metav1.ListOptions
+	if p.labels != nil {
+		result.LabelSelector = p.labels.String()
+	}
+	if p.fields != nil {
+		result.FieldSelector = p.fields.String()
+	}
+	return result
+}
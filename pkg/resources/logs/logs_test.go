@@ -0,0 +1,328 @@
+package logs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var deploymentGVR = schema2.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+type testClientGetter struct {
+	proxy.ClientGetter
+	client  kubernetes.Interface
+	dynamic dynamic.Interface
+}
+
+func (t *testClientGetter) K8sInterface(_ *types.APIRequest) (kubernetes.Interface, error) {
+	return t.client, nil
+}
+
+func (t *testClientGetter) Client(_ *types.APIRequest, _ *types.APISchema, namespace string, _ rest.WarningHandler) (dynamic.ResourceInterface, error) {
+	return t.dynamic.Resource(deploymentGVR).Namespace(namespace), nil
+}
+
+// testLogs returns two lines for every container, the container name being part of both
+func testLogs(_ context.Context, _ kubernetes.Interface, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("hello from " + opts.Container + "\nbye from " + opts.Container)), nil
+}
+
+func newPod(name string, labels map[string]string, containers ...string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       k8stypes.UID(name),
+			Labels:    labels,
+		},
+	}
+	for _, container := range containers {
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{
+			Name:  container,
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		})
+	}
+	return pod
+}
+
+func newHandler(client kubernetes.Interface, objects ...runtime.Object) *Handler {
+	return &Handler{
+		cg: &testClientGetter{
+			client:  client,
+			dynamic: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...),
+		},
+		openStream: testLogs,
+	}
+}
+
+func newRequest(ctx context.Context, gvk schema2.GroupVersionKind, name, query string) *types.APIRequest {
+	apiSchema := &types.APISchema{Schema: &schemas.Schema{ID: strings.ToLower(gvk.Kind)}}
+	attributes.SetGVK(apiSchema, gvk)
+	req := httptest.NewRequest(http.MethodGet, "/v1/pods/default/"+name+"?link=logs&"+query, nil).WithContext(ctx)
+	return types.StoreAPIContext(&types.APIRequest{
+		Schema:    apiSchema,
+		Namespace: "default",
+		Name:      name,
+		Link:      logsLink,
+		Request:   req,
+		ErrorHandler: func(apiOp *types.APIRequest, err error) {
+			http.Error(apiOp.Response, err.Error(), http.StatusUnprocessableEntity)
+		},
+	})
+}
+
+func serve(h *Handler, apiOp *types.APIRequest) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	apiOp.Response = rec
+	h.ServeHTTP(rec, apiOp.Request)
+	return rec
+}
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    *corev1.PodLogOptions
+		wantErr bool
+	}{
+		{
+			query: "",
+			want:  &corev1.PodLogOptions{Container: "app"},
+		},
+		{
+			query: "follow=true&previous=1&timestamps=true&since=5m&tailLines=10",
+			want: &corev1.PodLogOptions{
+				Container:    "app",
+				Follow:       true,
+				Previous:     true,
+				Timestamps:   true,
+				SinceSeconds: &[]int64{300}[0],
+				TailLines:    &[]int64{10}[0],
+			},
+		},
+		{query: "since=5", wantErr: true},
+		{query: "tailLines=-1", wantErr: true},
+		{query: "grep=(", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			opts, err := parseOptions(newRequest(t.Context(), podGVK, "pod", test.query))
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, opts.podLogOptions("app"))
+		})
+	}
+}
+
+func TestPodLogs(t *testing.T) {
+	pod := newPod("web", nil, "app", "sidecar")
+	pod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+		{Name: "init", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}},
+	}
+	pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{
+		Name:  "waiting",
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{}},
+	})
+	h := newHandler(fake.NewClientset(pod, newPod("other", nil, "app")))
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name: "all containers",
+			want: []string{
+				"[web/init] hello from init", "[web/init] bye from init",
+				"[web/app] hello from app", "[web/app] bye from app",
+				"[web/sidecar] hello from sidecar", "[web/sidecar] bye from sidecar",
+			},
+		},
+		{
+			name:  "single container",
+			query: "container=sidecar",
+			want:  []string{"[web/sidecar] hello from sidecar", "[web/sidecar] bye from sidecar"},
+		},
+		{
+			name:  "grep",
+			query: "grep=^hello.*(app|init)",
+			want:  []string{"[web/init] hello from init", "[web/app] hello from app"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := serve(h, newRequest(t.Context(), podGVK, "web", test.query))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+			assert.ElementsMatch(t, test.want, strings.Split(strings.TrimSpace(rec.Body.String()), "\n"))
+		})
+	}
+}
+
+func TestWorkloadLogsFollow(t *testing.T) {
+	deployment := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      "web",
+			"namespace": "default",
+		},
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"app": "web"},
+			},
+		},
+	}}
+	client := fake.NewClientset(
+		newPod("web-1", map[string]string{"app": "web"}, "app"),
+		newPod("db-1", map[string]string{"app": "db"}, "app"),
+	)
+	h := newHandler(client, deployment)
+	var streams atomic.Int32
+	h.openStream = func(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+		streams.Add(1)
+		return testLogs(ctx, client, pod, opts)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- serve(h, newRequest(ctx, schema2.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, "web", "follow=true"))
+	}()
+
+	// wait for the watch to be established before starting a new pod
+	require.Eventually(t, func() bool {
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "watch" {
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+	pending := newPod("web-2", map[string]string{"app": "web"}, "app")
+	pending.Status.ContainerStatuses[0].State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{}}
+	_, err := client.CoreV1().Pods("default").Create(t.Context(), pending, metav1.CreateOptions{})
+	require.NoError(t, err)
+	// the logs of the pod are streamed once its container starts
+	_, err = client.CoreV1().Pods("default").UpdateStatus(t.Context(), newPod("web-2", map[string]string{"app": "web"}, "app"), metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return streams.Load() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+
+	rec := <-done
+	assert.ElementsMatch(t, []string{
+		"[web-1/app] hello from app", "[web-1/app] bye from app",
+		"[web-2/app] hello from app", "[web-2/app] bye from app",
+	}, strings.Split(strings.TrimSpace(rec.Body.String()), "\n"))
+}
+
+func TestLogsFollowRewatch(t *testing.T) {
+	client := fake.NewClientset(newPod("web", map[string]string{"app": "web"}, "app"))
+	watchers := make(chan *watch.FakeWatcher, 3)
+	var resourceVersions []string
+	client.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		resourceVersions = append(resourceVersions, action.(k8stesting.WatchActionImpl).WatchRestrictions.ResourceVersion)
+		w := watch.NewFake()
+		watchers <- w
+		return true, w, nil
+	})
+	h := newHandler(client)
+	var streams atomic.Int32
+	h.openStream = func(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+		streams.Add(1)
+		return testLogs(ctx, client, pod, opts)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- serve(h, newRequest(ctx, podGVK, "web", "follow=true"))
+	}()
+
+	// the apiserver closing the watch doesn't stop following
+	w := <-watchers
+	restarted := newPod("web", map[string]string{"app": "web"}, "app")
+	restarted.ResourceVersion = "10"
+	restarted.Status.ContainerStatuses[0].RestartCount = 1
+	w.Modify(restarted)
+	w.Stop()
+
+	w = <-watchers
+	restarted = restarted.DeepCopy()
+	restarted.ResourceVersion = "11"
+	restarted.Status.ContainerStatuses[0].RestartCount = 2
+	w.Modify(restarted)
+	// neither does an expired resourceVersion, the pods are listed again
+	w.Error(&metav1.Status{Status: metav1.StatusFailure, Code: http.StatusGone, Reason: metav1.StatusReasonExpired})
+
+	<-watchers
+	assert.Eventually(t, func() bool {
+		return streams.Load() == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+
+	rec := <-done
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"", "10", ""}, resourceVersions)
+}
+
+func TestLogsWebsocket(t *testing.T) {
+	h := newHandler(fake.NewClientset(newPod("web", nil, "app")))
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		apiOp := newRequest(req.Context(), podGVK, "web", "")
+		apiOp.Request = req.WithContext(apiOp.Request.Context())
+		apiOp.Response = rw
+		h.ServeHTTP(rw, apiOp.Request)
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	u.Scheme = "ws"
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var messages []string
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), err)
+			break
+		}
+		messages = append(messages, string(data))
+	}
+	assert.Equal(t, []string{"[web/app] hello from app", "[web/app] bye from app"}, messages)
+}
@@ -0,0 +1,291 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// maxStreams is the maximum number of containers whose logs are streamed by a single request
+	maxStreams = 50
+	// rewatchDelay is how long to wait before listing pods again after a watch failed
+	rewatchDelay = time.Second
+)
+
+// lineWriter sends log lines to the client, it must be safe for concurrent use
+type lineWriter interface {
+	WriteLine(line string) error
+	Close()
+}
+
+type httpWriter struct {
+	lock    sync.Mutex
+	rw      http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newHTTPWriter(rw http.ResponseWriter) *httpWriter {
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	rw.WriteHeader(http.StatusOK)
+	flusher, _ := rw.(http.Flusher)
+	return &httpWriter{
+		rw:      rw,
+		flusher: flusher,
+	}
+}
+
+func (h *httpWriter) WriteLine(line string) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _, err := io.WriteString(h.rw, line+"\n"); err != nil {
+		return err
+	}
+	if h.flusher != nil {
+		h.flusher.Flush()
+	}
+	return nil
+}
+
+func (h *httpWriter) Close() {}
+
+// websocketWriter sends every line as a text message. Messages sent by the client are discarded, and the request is
+// cancelled once the client closes the connection.
+type websocketWriter struct {
+	lock sync.Mutex
+	conn *websocket.Conn
+}
+
+func newWebsocketWriter(conn *websocket.Conn, cancel context.CancelFunc) *websocketWriter {
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+	return &websocketWriter{conn: conn}
+}
+
+func (w *websocketWriter) WriteLine(line string) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.conn.WriteMessage(websocket.TextMessage, []byte(line))
+}
+
+func (w *websocketWriter) Close() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	_ = w.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+}
+
+// follower streams the logs of all the containers of the selected pods. When following, pods are watched so that the
+// logs of containers started after the request are streamed too.
+type follower struct {
+	client     kubernetes.Interface
+	namespace  string
+	selector   podSelector
+	opts       options
+	openStream streamOpener
+	out        lineWriter
+
+	wg sync.WaitGroup
+	// streams holds the containers already streamed, by pod UID, name and restart count
+	streams map[string]bool
+	active  atomic.Int32
+	limited bool
+}
+
+// list returns the pods currently selected, which is done before streaming so that errors get a proper status code
+func (f *follower) list(ctx context.Context) (*corev1.PodList, error) {
+	return f.client.CoreV1().Pods(f.namespace).List(ctx, f.selector.listOptions())
+}
+
+// run streams the logs of the given pods, then of the pods started later on if following
+func (f *follower) run(ctx context.Context, pods *corev1.PodList) error {
+	f.streams = map[string]bool{}
+	defer f.wg.Wait()
+
+	for i := range pods.Items {
+		f.startStreams(ctx, &pods.Items[i])
+	}
+	if !f.opts.Follow {
+		return nil
+	}
+
+	// the apiserver closes watches after a timeout, so watching resumes until the request is done
+	resourceVersion := pods.ResourceVersion
+	for {
+		var err error
+		resourceVersion, err = f.watch(ctx, resourceVersion)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			continue
+		}
+
+		// the watch couldn't resume from the last resourceVersion, list the pods again instead
+		logrus.Debugf("Failed to watch pods for logs, listing them again: %v", err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(rewatchDelay):
+		}
+		pods, err := f.list(ctx)
+		if err != nil {
+			// watching without a resourceVersion starts with the pods that currently exist
+			resourceVersion = ""
+			continue
+		}
+		for i := range pods.Items {
+			f.startStreams(ctx, &pods.Items[i])
+		}
+		resourceVersion = pods.ResourceVersion
+	}
+}
+
+// watch starts the streams of the pods added or updated after the given resourceVersion, until the watch is closed.
+// It returns the last resourceVersion seen, or an error if the watch couldn't be started or failed.
+func (f *follower) watch(ctx context.Context, resourceVersion string) (string, error) {
+	listOptions := f.selector.listOptions()
+	listOptions.ResourceVersion = resourceVersion
+	listOptions.AllowWatchBookmarks = true
+	w, err := f.client.CoreV1().Pods(f.namespace).Watch(ctx, listOptions)
+	if err != nil {
+		return resourceVersion, err
+	}
+	defer w.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion, nil
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return resourceVersion, nil
+			}
+			if event.Type == watch.Error {
+				return resourceVersion, apierrors.FromObject(event.Object)
+			}
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok {
+				continue
+			}
+			resourceVersion = pod.ResourceVersion
+			if event.Type == watch.Added || event.Type == watch.Modified {
+				f.startStreams(ctx, pod)
+			}
+		}
+	}
+}
+
+func (f *follower) matches(pod *corev1.Pod) bool {
+	if f.selector.labels != nil && !f.selector.labels.Matches(labels.Set(pod.Labels)) {
+		return false
+	}
+	if f.selector.fields != nil && !f.selector.fields.Matches(fields.Set{"metadata.name": pod.Name}) {
+		return false
+	}
+	return true
+}
+
+// startStreams starts streaming the logs of the containers of the pod that have started and aren't streamed yet
+func (f *follower) startStreams(ctx context.Context, pod *corev1.Pod) {
+	if !f.matches(pod) {
+		return
+	}
+	for _, status := range f.containers(pod) {
+		container := status.Name
+		key := fmt.Sprintf("%s/%s/%d", pod.UID, container, status.RestartCount)
+		if f.streams[key] {
+			continue
+		}
+		if f.active.Load() >= maxStreams {
+			if !f.limited {
+				f.limited = true
+				_ = f.out.WriteLine(fmt.Sprintf("[error] too many containers, only the logs of %d of them are shown", maxStreams))
+			}
+			return
+		}
+		f.streams[key] = true
+		prefix := fmt.Sprintf("[%s/%s]", pod.Name, container)
+
+		f.active.Add(1)
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			defer f.active.Add(-1)
+			if err := f.stream(ctx, pod, container, prefix); err != nil && ctx.Err() == nil {
+				logrus.Debugf("Failed to stream logs of %s/%s: %v", pod.Namespace, prefix, err)
+				_ = f.out.WriteLine(fmt.Sprintf("%s [error] %v", prefix, err))
+			}
+		}()
+	}
+}
+
+// containers returns the statuses of the containers of the pod having logs, init containers first
+func (f *follower) containers(pod *corev1.Pod) []corev1.ContainerStatus {
+	var statuses []corev1.ContainerStatus
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	statuses = append(statuses, pod.Status.EphemeralContainerStatuses...)
+
+	var result []corev1.ContainerStatus
+	for _, status := range statuses {
+		if f.opts.Container != "" && status.Name != f.opts.Container {
+			continue
+		}
+		started := status.State.Running != nil || status.State.Terminated != nil
+		if f.opts.Previous {
+			started = status.LastTerminationState.Terminated != nil
+		}
+		if started {
+			result = append(result, status)
+		}
+	}
+	return result
+}
+
+func (f *follower) stream(ctx context.Context, pod *corev1.Pod, container, prefix string) error {
+	stream, err := f.openStream(ctx, f.client, pod, f.opts.podLogOptions(container))
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			line = strings.TrimSuffix(line, "\n")
+			if f.opts.Grep == nil || f.opts.Grep.MatchString(line) {
+				if err := f.out.WriteLine(prefix + " " + line); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,459 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DrainStatusAnnotation holds a summary of the ongoing or last drain of a node, so that its progress can be
+	// followed by watching the node
+	DrainStatusAnnotation = "steve.cattle.io/drain-status"
+
+	defaultDrainTimeout = 10 * time.Minute
+)
+
+var (
+	// evictionRetryInterval is the time waited before retrying an eviction blocked by a PodDisruptionBudget
+	evictionRetryInterval = 5 * time.Second
+	// deletionPollInterval is the interval at which evicted pods are checked for deletion
+	deletionPollInterval = time.Second
+)
+
+type DrainState string
+
+const (
+	DrainRunning   DrainState = "running"
+	DrainCompleted DrainState = "completed"
+	DrainFailed    DrainState = "failed"
+	DrainCancelled DrainState = "cancelled"
+)
+
+type PodDrainState string
+
+const (
+	PodPending  PodDrainState = "pending"
+	PodEvicting PodDrainState = "evicting"
+	PodDeleted  PodDrainState = "deleted"
+	PodFailed   PodDrainState = "failed"
+)
+
+// DrainInput is the input of the drain action
+type DrainInput struct {
+	// GracePeriodSeconds overrides the termination grace period of the pods, if set
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty"`
+	// IgnoreDaemonSets leaves the pods managed by DaemonSets in place instead of failing the drain
+	IgnoreDaemonSets bool `json:"ignoreDaemonSets,omitempty"`
+	// DeleteEmptyDirData allows evicting pods using emptyDir volumes, whose data is lost
+	DeleteEmptyDirData bool `json:"deleteEmptyDirData,omitempty"`
+	// Force allows evicting pods that aren't managed by a controller, and won't be recreated
+	Force bool `json:"force,omitempty"`
+	// DisableEviction deletes pods instead of evicting them, bypassing PodDisruptionBudgets
+	DisableEviction bool `json:"disableEviction,omitempty"`
+	// TimeoutSeconds is the time after which the drain is given up, defaults to 10 minutes
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+}
+
+// DrainStatus is the progress of the drain of a node
+type DrainStatus struct {
+	State          DrainState       `json:"state"`
+	Message        string           `json:"message,omitempty"`
+	StartTime      metav1.Time      `json:"startTime"`
+	CompletionTime *metav1.Time     `json:"completionTime,omitempty"`
+	Pods           []PodDrainStatus `json:"pods,omitempty"`
+}
+
+// PodDrainStatus is the progress of the eviction of a single pod
+type PodDrainStatus struct {
+	Namespace string        `json:"namespace"`
+	Name      string        `json:"name"`
+	State     PodDrainState `json:"state"`
+	Message   string        `json:"message,omitempty"`
+}
+
+// drainSummary is the content of DrainStatusAnnotation
+type drainSummary struct {
+	State   DrainState `json:"state"`
+	Total   int        `json:"total"`
+	Deleted int        `json:"deleted"`
+	Failed  int        `json:"failed"`
+}
+
+type drain struct {
+	status    DrainStatus
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// Drainer drains nodes in the background, keeping track of the progress of the last drain of every node
+type Drainer struct {
+	cg proxy.ClientGetter
+
+	lock   sync.Mutex
+	drains map[string]*drain
+}
+
+func newDrainer(cg proxy.ClientGetter) *Drainer {
+	return &Drainer{
+		cg:     cg,
+		drains: map[string]*drain{},
+	}
+}
+
+// ServeHTTP cordons the node and starts evicting its pods, returning once the eviction started. Errors preventing the
+// drain, such as pods that can't be evicted with the given options, are returned right away.
+func (d *Drainer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+	nodeName := apiOp.Name
+
+	var input DrainInput
+	if err := decodeInput(apiOp, &input); err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	client, err := d.cg.K8sInterface(apiOp)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+
+	timeout := defaultDrainTimeout
+	if input.TimeoutSeconds > 0 {
+		timeout = time.Duration(input.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	current, err := d.start(nodeName, cancel)
+	if err != nil {
+		cancel()
+		apiOp.WriteError(err)
+		return
+	}
+
+	pods, err := d.prepare(apiOp, client, nodeName, input)
+	if err != nil {
+		cancel()
+		d.forget(nodeName, current)
+		apiOp.WriteError(proxy.TranslateError(err))
+		return
+	}
+	status := d.setPods(nodeName, pods)
+
+	go d.run(ctx, cancel, client, nodeName, pods, input)
+
+	apiOp.WriteResponse(http.StatusAccepted, types.APIObject{
+		Type:   "drainStatus",
+		ID:     nodeName,
+		Object: status,
+	})
+}
+
+func (d *Drainer) serveStatus(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+	status, ok := d.status(apiOp.Name)
+	if !ok {
+		apiOp.WriteError(apierror.NewAPIError(validation.NotFound, "node "+apiOp.Name+" has not been drained"))
+		return
+	}
+	apiOp.WriteResponse(http.StatusOK, types.APIObject{
+		Type:   "drainStatus",
+		ID:     apiOp.Name,
+		Object: status,
+	})
+}
+
+// start registers a new drain for the given node, unless one is already running
+func (d *Drainer) start(nodeName string, cancel context.CancelFunc) (*drain, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if current, ok := d.drains[nodeName]; ok && current.status.State == DrainRunning {
+		return nil, apierror.NewAPIError(validation.Conflict, "node "+nodeName+" is already being drained")
+	}
+	current := &drain{
+		status: DrainStatus{
+			State:     DrainRunning,
+			StartTime: metav1.Now(),
+		},
+		cancel: cancel,
+	}
+	d.drains[nodeName] = current
+	return current, nil
+}
+
+// forget removes a drain that failed to start
+func (d *Drainer) forget(nodeName string, current *drain) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.drains[nodeName] == current {
+		delete(d.drains, nodeName)
+	}
+}
+
+// cancel stops the ongoing drain of the given node, if any
+func (d *Drainer) cancel(nodeName string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if current, ok := d.drains[nodeName]; ok && current.status.State == DrainRunning {
+		current.cancelled = true
+		current.cancel()
+	}
+}
+
+func (d *Drainer) status(nodeName string) (DrainStatus, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	current, ok := d.drains[nodeName]
+	if !ok {
+		return DrainStatus{}, false
+	}
+	return current.status.copy(), true
+}
+
+func (s DrainStatus) copy() DrainStatus {
+	result := s
+	result.Pods = append([]PodDrainStatus(nil), s.Pods...)
+	return result
+}
+
+func (s DrainStatus) summary() drainSummary {
+	result := drainSummary{
+		State: s.State,
+		Total: len(s.Pods),
+	}
+	for _, pod := range s.Pods {
+		switch pod.State {
+		case PodDeleted:
+			result.Deleted++
+		case PodFailed:
+			result.Failed++
+		}
+	}
+	return result
+}
+
+// prepare cordons the node and lists the pods to evict, failing if some of them can't be evicted with the given options
+func (d *Drainer) prepare(apiOp *types.APIRequest, client kubernetes.Interface, nodeName string, input DrainInput) ([]corev1.Pod, error) {
+	if _, err := setUnschedulable(apiOp, client, nodeName, true); err != nil {
+		return nil, err
+	}
+
+	podList, err := client.CoreV1().Pods("").List(apiOp.Context(), metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		pods     []corev1.Pod
+		problems []string
+	)
+	for _, pod := range podList.Items {
+		evict, reason := filterPod(&pod, input)
+		if reason != "" {
+			problems = append(problems, fmt.Sprintf("%s/%s: %s", pod.Namespace, pod.Name, reason))
+		}
+		if evict {
+			pods = append(pods, pod)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, apierror.NewAPIError(validation.InvalidBodyContent, "cannot drain node "+nodeName+": "+strings.Join(problems, ", "))
+	}
+	return pods, nil
+}
+
+// filterPod returns whether the pod should be evicted, along with the reason it can't be evicted with the given options,
+// following the rules of kubectl drain
+func filterPod(pod *corev1.Pod, input DrainInput) (bool, string) {
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return false, ""
+	}
+	// completed pods are deleted regardless of any other rule
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return true, ""
+	}
+
+	controller := metav1.GetControllerOf(pod)
+	if controller != nil && controller.Kind == "DaemonSet" {
+		if input.IgnoreDaemonSets {
+			return false, ""
+		}
+		return false, "managed by a DaemonSet, set ignoreDaemonSets"
+	}
+	if controller == nil && !input.Force {
+		return false, "not managed by a controller, set force"
+	}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir != nil && !input.DeleteEmptyDirData {
+			return false, "uses emptyDir volumes, set deleteEmptyDirData"
+		}
+	}
+	return true, ""
+}
+
+func (d *Drainer) setPods(nodeName string, pods []corev1.Pod) DrainStatus {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	current := d.drains[nodeName]
+	for _, pod := range pods {
+		current.status.Pods = append(current.status.Pods, PodDrainStatus{
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+			State:     PodPending,
+		})
+	}
+	return current.status.copy()
+}
+
+func (d *Drainer) setPodState(nodeName string, index int, state PodDrainState, message string) drainSummary {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	current := d.drains[nodeName]
+	current.status.Pods[index].State = state
+	current.status.Pods[index].Message = message
+	return current.status.summary()
+}
+
+func (d *Drainer) finish(nodeName string) drainSummary {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	current := d.drains[nodeName]
+	summary := current.status.summary()
+	switch {
+	case current.cancelled:
+		current.status.State = DrainCancelled
+	case summary.Failed > 0:
+		current.status.State = DrainFailed
+		current.status.Message = fmt.Sprintf("failed to evict %d pods", summary.Failed)
+	default:
+		current.status.State = DrainCompleted
+	}
+	now := metav1.Now()
+	current.status.CompletionTime = &now
+	summary.State = current.status.State
+	return summary
+}
+
+func (d *Drainer) run(ctx context.Context, cancel context.CancelFunc, client kubernetes.Interface, nodeName string, pods []corev1.Pod, input DrainInput) {
+	defer cancel()
+
+	logrus.Infof("Draining node %s, evicting %d pods", nodeName, len(pods))
+	d.annotate(client, nodeName, d.summary(nodeName))
+
+	var wg sync.WaitGroup
+	for i := range pods {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			d.setPodState(nodeName, i, PodEvicting, "")
+
+			state, message := PodDeleted, ""
+			if err := evict(ctx, client, &pods[i], input, func(message string) {
+				d.setPodState(nodeName, i, PodEvicting, message)
+			}); err != nil {
+				state, message = PodFailed, err.Error()
+			}
+			d.annotate(client, nodeName, d.setPodState(nodeName, i, state, message))
+		}(i)
+	}
+	wg.Wait()
+
+	summary := d.finish(nodeName)
+	d.annotate(client, nodeName, summary)
+	logrus.Infof("Drain of node %s %s: %d of %d pods deleted", nodeName, summary.State, summary.Deleted, summary.Total)
+}
+
+func (d *Drainer) summary(nodeName string) drainSummary {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.drains[nodeName].status.summary()
+}
+
+// annotate publishes the progress of the drain on the node, failures are only logged as the drain itself goes on
+func (d *Drainer) annotate(client kubernetes.Interface, nodeName string, summary drainSummary) {
+	value, err := json.Marshal(summary)
+	if err != nil {
+		return
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				DrainStatusAnnotation: string(value),
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := client.CoreV1().Nodes().Patch(ctx, nodeName, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		logrus.Debugf("Failed to update drain status of node %s: %v", nodeName, err)
+	}
+}
+
+// evict evicts or deletes the pod, retrying while PodDisruptionBudgets don't allow it, then waits for it to be gone
+func evict(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod, input DrainInput, progress func(string)) error {
+	deleteOptions := metav1.DeleteOptions{
+		GracePeriodSeconds: input.GracePeriodSeconds,
+	}
+	for {
+		var err error
+		if input.DisableEviction {
+			err = client.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, deleteOptions)
+		} else {
+			err = client.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      pod.Name,
+					Namespace: pod.Namespace,
+				},
+				DeleteOptions: &deleteOptions,
+			})
+		}
+		if err == nil || apierrors.IsNotFound(err) {
+			break
+		}
+		if !apierrors.IsTooManyRequests(err) {
+			return err
+		}
+
+		progress("eviction blocked by a PodDisruptionBudget, retrying")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("eviction blocked by a PodDisruptionBudget: %w", ctx.Err())
+		case <-time.After(evictionRetryInterval):
+		}
+	}
+
+	return wait.PollUntilContextCancel(ctx, deletionPollInterval, true, func(ctx context.Context) (bool, error) {
+		current, err := client.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		// a pod with the same name may have been recreated by its controller
+		return current.UID != pod.UID, nil
+	})
+}
@@ -0,0 +1,143 @@
+// Package nodes registers the cordon, uncordon and drain actions on nodes, along with the drainstatus link reporting
+// the progress of a drain.
+package nodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	nodeSchemaID = "node"
+
+	cordonAction    = "cordon"
+	uncordonAction  = "uncordon"
+	drainAction     = "drain"
+	drainStatusLink = "drainstatus"
+)
+
+// Register adds the schemas of the input and output of the drain action
+func Register(apiSchemas *types.APISchemas) {
+	apiSchemas.MustImportAndCustomize(&DrainInput{}, nil)
+	apiSchemas.MustImportAndCustomize(&DrainStatus{}, nil)
+}
+
+// Template returns the template adding the actions and the drainstatus link to the node schema. Actions are run with
+// the client of the user making the request.
+func Template(cg proxy.ClientGetter) schema.Template {
+	drainer := newDrainer(cg)
+	return schema.Template{
+		ID: nodeSchemaID,
+		Customize: func(apiSchema *types.APISchema) {
+			AddActions(drainer, apiSchema)
+		},
+	}
+}
+
+// AddActions adds the node actions and the drainstatus link to the given schema
+func AddActions(d *Drainer, apiSchema *types.APISchema) {
+	if apiSchema.ActionHandlers == nil {
+		apiSchema.ActionHandlers = map[string]http.Handler{}
+	}
+	apiSchema.ActionHandlers[cordonAction] = &cordonHandler{cg: d.cg, unschedulable: true}
+	apiSchema.ActionHandlers[uncordonAction] = &cordonHandler{cg: d.cg, drainer: d}
+	apiSchema.ActionHandlers[drainAction] = d
+
+	if apiSchema.ResourceActions == nil {
+		apiSchema.ResourceActions = map[string]schemas.Action{}
+	}
+	apiSchema.ResourceActions[cordonAction] = schemas.Action{Output: nodeSchemaID}
+	apiSchema.ResourceActions[uncordonAction] = schemas.Action{Output: nodeSchemaID}
+	apiSchema.ResourceActions[drainAction] = schemas.Action{
+		Input:  "drainInput",
+		Output: "drainStatus",
+	}
+
+	if apiSchema.LinkHandlers == nil {
+		apiSchema.LinkHandlers = map[string]http.Handler{}
+	}
+	apiSchema.LinkHandlers[drainStatusLink] = http.HandlerFunc(d.serveStatus)
+}
+
+// cordonHandler marks a node as schedulable or unschedulable. Uncordoning a node also cancels its ongoing drain.
+type cordonHandler struct {
+	cg            proxy.ClientGetter
+	unschedulable bool
+	drainer       *Drainer
+}
+
+func (c *cordonHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+
+	client, err := c.cg.K8sInterface(apiOp)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	node, err := setUnschedulable(apiOp, client, apiOp.Name, c.unschedulable)
+	if err != nil {
+		apiOp.WriteError(proxy.TranslateError(err))
+		return
+	}
+	if c.drainer != nil {
+		c.drainer.cancel(apiOp.Name)
+	}
+
+	result, err := toAPIObject(node)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	apiOp.WriteResponse(http.StatusOK, result)
+}
+
+func setUnschedulable(apiOp *types.APIRequest, client kubernetes.Interface, name string, unschedulable bool) (*corev1.Node, error) {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"unschedulable": unschedulable,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client.CoreV1().Nodes().Patch(apiOp.Context(), name, k8stypes.MergePatchType, patch, metav1.PatchOptions{})
+}
+
+// toAPIObject converts a node returned by the typed client, which has no type information
+func toAPIObject(node *corev1.Node) (types.APIObject, error) {
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(node)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	unstr := &unstructured.Unstructured{Object: data}
+	unstr.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Node"))
+	return types.APIObject{
+		Type:   nodeSchemaID,
+		ID:     node.Name,
+		Object: unstr,
+	}, nil
+}
+
+func decodeInput(apiOp *types.APIRequest, input interface{}) error {
+	if apiOp.Request.ContentLength == 0 {
+		return nil
+	}
+	if err := json.NewDecoder(apiOp.Request.Body).Decode(input); err != nil {
+		return apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("failed to parse body: %v", err))
+	}
+	return nil
+}
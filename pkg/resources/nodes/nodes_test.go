@@ -0,0 +1,280 @@
+package nodes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+type testClientGetter struct {
+	proxy.ClientGetter
+	client kubernetes.Interface
+}
+
+func (t *testClientGetter) K8sInterface(_ *types.APIRequest) (kubernetes.Interface, error) {
+	return t.client, nil
+}
+
+type testResponseWriter struct {
+	code   int
+	object types.APIObject
+}
+
+func (t *testResponseWriter) Write(_ *types.APIRequest, code int, obj types.APIObject) {
+	t.code = code
+	t.object = obj
+}
+
+func (t *testResponseWriter) WriteList(_ *types.APIRequest, _ int, _ types.APIObjectList) {}
+
+func newNodeSchema(client kubernetes.Interface) *types.APISchema {
+	apiSchema := &types.APISchema{
+		Schema: &schemas.Schema{ID: nodeSchemaID},
+	}
+	AddActions(newDrainer(&testClientGetter{client: client}), apiSchema)
+	return apiSchema
+}
+
+// serve runs the given action or link handler on node1, returning the written object or the error
+func serve(t *testing.T, handler http.Handler, body string) (*testResponseWriter, error) {
+	t.Helper()
+	var handlerErr error
+	writer := &testResponseWriter{}
+	req := httptest.NewRequest(http.MethodPost, "/v1/nodes/node1", strings.NewReader(body))
+	apiOp := types.StoreAPIContext(&types.APIRequest{
+		Type:           nodeSchemaID,
+		Name:           "node1",
+		Request:        req,
+		Response:       httptest.NewRecorder(),
+		ResponseWriter: writer,
+		ErrorHandler: func(_ *types.APIRequest, err error) {
+			handlerErr = err
+		},
+	})
+	handler.ServeHTTP(apiOp.Response, apiOp.Request)
+	return writer, handlerErr
+}
+
+func newPod(name string, controllerKind string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       k8stypes.UID("uid-" + name),
+		},
+		Spec: corev1.PodSpec{NodeName: "node1"},
+	}
+	if controllerKind != "" {
+		pod.OwnerReferences = []metav1.OwnerReference{{
+			Kind:       controllerKind,
+			Name:       "owner",
+			Controller: &[]bool{true}[0],
+		}}
+	}
+	return pod
+}
+
+// evictionReactor deletes the evicted pods, as the eviction API does
+func evictionReactor(client *fake.Clientset) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		err := client.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+		return true, nil, err
+	}
+}
+
+// nodeNameReactor filters listed pods by node name, as the fake client doesn't support field selectors
+func nodeNameReactor(client *fake.Clientset) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		selector := action.(k8stesting.ListAction).GetListRestrictions().Fields
+		obj, err := client.Tracker().List(corev1.SchemeGroupVersion.WithResource("pods"), corev1.SchemeGroupVersion.WithKind("Pod"), "")
+		if err != nil {
+			return true, nil, err
+		}
+		list := obj.(*corev1.PodList)
+		var pods []corev1.Pod
+		for _, pod := range list.Items {
+			if selector.Matches(fields.Set{"spec.nodeName": pod.Spec.NodeName}) {
+				pods = append(pods, pod)
+			}
+		}
+		list.Items = pods
+		return true, list, nil
+	}
+}
+
+func TestFilterPod(t *testing.T) {
+	emptyDir := newPod("emptydir", "ReplicaSet")
+	emptyDir.Spec.Volumes = []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+	mirror := newPod("mirror", "")
+	mirror.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "hash"}
+	completed := newPod("completed", "")
+	completed.Status.Phase = corev1.PodSucceeded
+
+	tests := []struct {
+		name       string
+		pod        *corev1.Pod
+		input      DrainInput
+		wantEvict  bool
+		wantReason bool
+	}{
+		{name: "managed pod", pod: newPod("managed", "ReplicaSet"), wantEvict: true},
+		{name: "mirror pod", pod: mirror},
+		{name: "completed pod", pod: completed, wantEvict: true},
+		{name: "daemonset pod", pod: newPod("ds", "DaemonSet"), wantReason: true},
+		{name: "daemonset pod, ignored", pod: newPod("ds", "DaemonSet"), input: DrainInput{IgnoreDaemonSets: true}},
+		{name: "unmanaged pod", pod: newPod("unmanaged", ""), wantReason: true},
+		{name: "unmanaged pod, forced", pod: newPod("unmanaged", ""), input: DrainInput{Force: true}, wantEvict: true},
+		{name: "emptydir pod", pod: emptyDir, wantReason: true},
+		{name: "emptydir pod, allowed", pod: emptyDir, input: DrainInput{DeleteEmptyDirData: true}, wantEvict: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			evict, reason := filterPod(test.pod, test.input)
+			assert.Equal(t, test.wantEvict, evict)
+			assert.Equal(t, test.wantReason, reason != "", reason)
+		})
+	}
+}
+
+func TestCordon(t *testing.T) {
+	client := fake.NewClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}})
+	apiSchema := newNodeSchema(client)
+
+	writer, err := serve(t, apiSchema.ActionHandlers[cordonAction], "")
+	require.NoError(t, err)
+	assert.Equal(t, "node1", writer.object.ID)
+	node, err := client.CoreV1().Nodes().Get(t.Context(), "node1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, node.Spec.Unschedulable)
+
+	_, err = serve(t, apiSchema.ActionHandlers[uncordonAction], "")
+	require.NoError(t, err)
+	node, err = client.CoreV1().Nodes().Get(t.Context(), "node1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.False(t, node.Spec.Unschedulable)
+}
+
+func TestDrain(t *testing.T) {
+	evictionRetryInterval = 10 * time.Millisecond
+	deletionPollInterval = 10 * time.Millisecond
+
+	other := newPod("other", "ReplicaSet")
+	other.Spec.NodeName = "node2"
+	client := fake.NewClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		newPod("web", "ReplicaSet"),
+		newPod("db", "StatefulSet"),
+		newPod("ds", "DaemonSet"),
+		other,
+	)
+	client.PrependReactor("list", "pods", nodeNameReactor(client))
+	client.PrependReactor("create", "pods", evictionReactor(client))
+	// the first eviction of db is refused, as if a PodDisruptionBudget didn't allow it
+	var refused atomic.Bool
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		eviction, ok := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		if ok && eviction.Name == "db" && refused.CompareAndSwap(false, true) {
+			return true, nil, apierrors.NewTooManyRequests("disruption budget", 1)
+		}
+		return false, nil, nil
+	})
+	apiSchema := newNodeSchema(client)
+
+	_, err := serve(t, apiSchema.LinkHandlers[drainStatusLink], "")
+	assert.Equal(t, http.StatusNotFound, err.(*apierror.APIError).Code.Status)
+
+	_, err = serve(t, apiSchema.ActionHandlers[drainAction], "{}")
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, err.(*apierror.APIError).Code.Status)
+	assert.Contains(t, err.Error(), "default/ds")
+
+	writer, err := serve(t, apiSchema.ActionHandlers[drainAction], `{"ignoreDaemonSets": true}`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, writer.code)
+	status := writer.object.Object.(DrainStatus)
+	assert.Len(t, status.Pods, 2)
+
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		writer, err := serve(t, apiSchema.LinkHandlers[drainStatusLink], "")
+		if !assert.NoError(c, err) {
+			return
+		}
+		status := writer.object.Object.(DrainStatus)
+		assert.Equal(c, DrainCompleted, status.State)
+		for _, pod := range status.Pods {
+			assert.Equal(c, PodDeleted, pod.State, pod.Name)
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, refused.Load())
+
+	node, err := client.CoreV1().Nodes().Get(t.Context(), "node1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, node.Spec.Unschedulable)
+	assert.JSONEq(t, `{"state":"completed","total":2,"deleted":2,"failed":0}`, node.Annotations[DrainStatusAnnotation])
+
+	pods, err := client.CoreV1().Pods("").List(t.Context(), metav1.ListOptions{})
+	require.NoError(t, err)
+	var names []string
+	for _, pod := range pods.Items {
+		names = append(names, pod.Name)
+	}
+	assert.ElementsMatch(t, []string{"ds", "other"}, names)
+}
+
+func TestDrainCancelledByUncordon(t *testing.T) {
+	evictionRetryInterval = 10 * time.Millisecond
+
+	client := fake.NewClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		newPod("web", "ReplicaSet"),
+	)
+	// evictions are never allowed
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() == "eviction" {
+			return true, nil, apierrors.NewTooManyRequests("disruption budget", 1)
+		}
+		return false, nil, nil
+	})
+	apiSchema := newNodeSchema(client)
+
+	_, err := serve(t, apiSchema.ActionHandlers[drainAction], "")
+	require.NoError(t, err)
+	_, err = serve(t, apiSchema.ActionHandlers[drainAction], "")
+	assert.Equal(t, http.StatusConflict, err.(*apierror.APIError).Code.Status)
+
+	_, err = serve(t, apiSchema.ActionHandlers[uncordonAction], "")
+	require.NoError(t, err)
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		writer, err := serve(t, apiSchema.LinkHandlers[drainStatusLink], "")
+		if !assert.NoError(c, err) {
+			return
+		}
+		status := writer.object.Object.(DrainStatus)
+		assert.Equal(c, DrainCancelled, status.State)
+		assert.Equal(c, PodFailed, status.Pods[0].State)
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,146 @@
+package podexec
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// Channels of the websocket framing. Every message is a binary message whose first byte is the channel, the rest being
+// its data.
+const (
+	// stdinChannel carries the input of the command, an empty message closes it. When port forwarding, it carries
+	// the data sent to and received from the port.
+	stdinChannel byte = 0
+	// stdoutChannel carries the output of the command. When port forwarding, it carries the errors of the port.
+	stdoutChannel byte = 1
+	stderrChannel byte = 2
+	// statusChannel carries a single Status message, sent once the command completes
+	statusChannel byte = 3
+	// resizeChannel carries TerminalSize messages, sent whenever the terminal of the client is resized
+	resizeChannel byte = 4
+)
+
+// dataChannel and errorChannel are the channels used when port forwarding
+const (
+	dataChannel  = stdinChannel
+	errorChannel = stdoutChannel
+)
+
+// Status is the outcome of a command
+type Status struct {
+	// ExitCode is the exit code of the command, it isn't set if the command couldn't be run
+	ExitCode *int `json:"exitCode,omitempty"`
+	// Message explains why the command failed
+	Message string `json:"message,omitempty"`
+}
+
+// TerminalSize is the size of the terminal of the client
+type TerminalSize struct {
+	Width  uint16 `json:"width"`
+	Height uint16 `json:"height"`
+}
+
+// session multiplexes the channels over a websocket connection
+type session struct {
+	lock sync.Mutex
+	conn *websocket.Conn
+}
+
+func (s *session) write(channel byte, data []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	frame := make([]byte, len(data)+1)
+	frame[0] = channel
+	copy(frame[1:], data)
+	return s.conn.WriteMessage(websocket.BinaryMessage, frame)
+}
+
+func (s *session) writeJSON(channel byte, obj interface{}) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return s.write(channel, data)
+}
+
+// writer returns a writer sending everything written to it as messages on the given channel
+func (s *session) writer(channel byte) io.Writer {
+	return &channelWriter{session: s, channel: channel}
+}
+
+func (s *session) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_ = s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	_ = s.conn.Close()
+}
+
+// read dispatches the messages received from the client until the connection is closed, which cancels the session
+func (s *session) read(cancel context.CancelFunc, handle func(channel byte, data []byte)) {
+	defer cancel()
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if len(data) == 0 {
+			continue
+		}
+		handle(data[0], data[1:])
+	}
+}
+
+type channelWriter struct {
+	session *session
+	channel byte
+}
+
+func (c *channelWriter) Write(data []byte) (int, error) {
+	if err := c.session.write(c.channel, data); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// sizeQueue passes the terminal sizes sent by the client to the executor
+type sizeQueue struct {
+	ctx   context.Context
+	sizes chan TerminalSize
+}
+
+func newSizeQueue(ctx context.Context) *sizeQueue {
+	return &sizeQueue{
+		ctx:   ctx,
+		sizes: make(chan TerminalSize, 1),
+	}
+}
+
+// push replaces the pending size, if any, as only the latest one matters
+func (q *sizeQueue) push(size TerminalSize) {
+	for {
+		select {
+		case q.sizes <- size:
+			return
+		default:
+		}
+		select {
+		case <-q.sizes:
+		default:
+		}
+	}
+}
+
+func (q *sizeQueue) Next() *remotecommand.TerminalSize {
+	select {
+	case <-q.ctx.Done():
+		return nil
+	case size := <-q.sizes:
+		return &remotecommand.TerminalSize{Width: size.Width, Height: size.Height}
+	}
+}
@@ -0,0 +1,291 @@
+// Package podexec adds the exec, attach and portforward links to pods. The links are websocket endpoints speaking a
+// simple framing, translated to the streaming protocols of Kubernetes with the configuration of the user making the
+// request.
+package podexec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+const (
+	execLink        = "exec"
+	attachLink      = "attach"
+	portForwardLink = "portforward"
+)
+
+var (
+	podGVR = schema2.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+	upgrader = websocket.Upgrader{
+		HandshakeTimeout:  60 * time.Second,
+		EnableCompression: true,
+	}
+)
+
+// Template returns the template adding the exec, attach and portforward links to pods
+func Template(cg proxy.ClientGetter) schema.Template {
+	return schema.Template{
+		Kind: "Pod",
+		Customize: func(apiSchema *types.APISchema) {
+			AddLinks(cg, apiSchema)
+		},
+	}
+}
+
+// AddLinks adds the exec, attach and portforward links to the given schema. The links are only added if the
+// ClientGetter implements proxy.RESTConfigGetter.
+func AddLinks(clientGetter proxy.ClientGetter, apiSchema *types.APISchema) {
+	cg, ok := clientGetter.(proxy.RESTConfigGetter)
+	if !ok {
+		return
+	}
+	if apiSchema.LinkHandlers == nil {
+		apiSchema.LinkHandlers = map[string]http.Handler{}
+	}
+	apiSchema.LinkHandlers[execLink] = &Handler{
+		cg:          cg,
+		subresource: execLink,
+		newExecutor: newExecutor,
+	}
+	apiSchema.LinkHandlers[attachLink] = &Handler{
+		cg:          cg,
+		subresource: attachLink,
+		newExecutor: newExecutor,
+	}
+	apiSchema.LinkHandlers[portForwardLink] = &PortForwardHandler{
+		cg:        cg,
+		newDialer: newDialer,
+	}
+}
+
+// options are the options of an exec or attach request, parsed from its query parameters
+type options struct {
+	Command   []string
+	Container string
+	TTY       bool
+	// Stdin is true unless disabled with stdin=false
+	Stdin bool
+}
+
+func parseOptions(apiOp *types.APIRequest, subresource string) (options, error) {
+	q := apiOp.Request.URL.Query()
+	opts := options{
+		Command:   q["command"],
+		Container: q.Get("container"),
+		TTY:       q.Get("tty") == "true" || q.Get("tty") == "1",
+		Stdin:     q.Get("stdin") != "false" && q.Get("stdin") != "0",
+	}
+	if subresource == execLink && len(opts.Command) == 0 {
+		return opts, apierror.NewAPIError(validation.MissingRequired, "command is required")
+	}
+	return opts, nil
+}
+
+// params returns the parameters of the exec or attach request sent to Kubernetes
+func (o options) params(subresource string) runtime.Object {
+	if subresource == attachLink {
+		return &corev1.PodAttachOptions{
+			Container: o.Container,
+			Stdin:     o.Stdin,
+			Stdout:    true,
+			Stderr:    !o.TTY,
+			TTY:       o.TTY,
+		}
+	}
+	return &corev1.PodExecOptions{
+		Container: o.Container,
+		Command:   o.Command,
+		Stdin:     o.Stdin,
+		Stdout:    true,
+		Stderr:    !o.TTY,
+		TTY:       o.TTY,
+	}
+}
+
+type executorFactory func(cfg *rest.Config, u *url.URL) (remotecommand.Executor, error)
+
+// newExecutor returns an executor using websockets, falling back to SPDY for the servers not supporting them yet
+func newExecutor(cfg *rest.Config, u *url.URL) (remotecommand.Executor, error) {
+	websocketExecutor, err := remotecommand.NewWebSocketExecutor(cfg, http.MethodGet, u.String())
+	if err != nil {
+		return nil, err
+	}
+	spdyExecutor, err := remotecommand.NewSPDYExecutor(cfg, http.MethodPost, u)
+	if err != nil {
+		return nil, err
+	}
+	return remotecommand.NewFallbackExecutor(websocketExecutor, spdyExecutor, shouldFallback)
+}
+
+func shouldFallback(err error) bool {
+	return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+}
+
+// Handler runs a command in a container of the requested pod, or attaches to its running process
+type Handler struct {
+	cg          proxy.RESTConfigGetter
+	subresource string
+	newExecutor executorFactory
+}
+
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+
+	opts, err := parseOptions(apiOp, h.subresource)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	if err := prepare(apiOp, h.subresource); err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	cfg, err := h.cg.RESTConfig(apiOp)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	u, err := subresourceURL(cfg, apiOp, h.subresource, opts.params(h.subresource))
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	executor, err := h.newExecutor(cfg, u)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(rw, req, nil)
+	if err != nil {
+		return
+	}
+	s := &session{conn: conn}
+	defer s.close()
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	stdin, stdinWriter := io.Pipe()
+	// unblocks the reads of the client messages if the command completes without reading all of its input
+	defer stdin.Close()
+	sizes := newSizeQueue(ctx)
+	go func() {
+		defer stdinWriter.Close()
+		s.read(cancel, func(channel byte, data []byte) {
+			switch channel {
+			case stdinChannel:
+				if !opts.Stdin {
+					return
+				}
+				if len(data) == 0 {
+					_ = stdinWriter.Close()
+					return
+				}
+				_, _ = stdinWriter.Write(data)
+			case resizeChannel:
+				var size TerminalSize
+				if err := json.Unmarshal(data, &size); err == nil {
+					sizes.push(size)
+				}
+			}
+		})
+	}()
+
+	streamOptions := remotecommand.StreamOptions{
+		Stdout: s.writer(stdoutChannel),
+		Tty:    opts.TTY,
+	}
+	if opts.Stdin {
+		streamOptions.Stdin = stdin
+	}
+	if opts.TTY {
+		streamOptions.TerminalSizeQueue = sizes
+	} else {
+		streamOptions.Stderr = s.writer(stderrChannel)
+	}
+	err = executor.StreamWithContext(ctx, streamOptions)
+	_ = s.writeJSON(statusChannel, toStatus(err))
+}
+
+// toStatus returns the status sent to the client once the command completes
+func toStatus(err error) Status {
+	if err == nil {
+		code := 0
+		return Status{ExitCode: &code}
+	}
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitStatus()
+		return Status{ExitCode: &code, Message: err.Error()}
+	}
+	return Status{Message: err.Error()}
+}
+
+// prepare checks that the request can be upgraded and that the user is allowed to create the subresource of the pod.
+// Kubernetes checks it too, but only once the client connection has already been upgraded, while denying the request
+// beforehand gives it the right status code.
+func prepare(apiOp *types.APIRequest, subresource string) error {
+	if !websocket.IsWebSocketUpgrade(apiOp.Request) {
+		return apierror.NewAPIError(validation.InvalidAction, subresource+" requires a websocket connection")
+	}
+	accessSet := accesscontrol.AccessSetFromAPIRequest(apiOp)
+	if accessSet == nil {
+		return nil
+	}
+	gr := schema2.GroupResource{Group: podGVR.Group, Resource: podGVR.Resource + "/" + subresource}
+	if !accessSet.Grants("create", gr, apiOp.Namespace, apiOp.Name) {
+		return apierror.NewAPIError(validation.PermissionDenied, "can not "+subresource+" pod "+apiOp.Namespace+"/"+apiOp.Name)
+	}
+	return nil
+}
+
+// subresourceURL returns the URL of the subresource of the requested pod
+func subresourceURL(cfg *rest.Config, apiOp *types.APIRequest, subresource string, params runtime.Object) (*url.URL, error) {
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	req := client.CoreV1().RESTClient().Post().
+		Resource(podGVR.Resource).
+		Namespace(apiOp.Namespace).
+		Name(apiOp.Name).
+		SubResource(subresource)
+	if params != nil {
+		req = req.VersionedParams(params, scheme.ParameterCodec)
+	}
+	return req.URL(), nil
+}
+
+// translateError keeps the status code of the errors returned by Kubernetes when upgrading a connection
+func translateError(err error) error {
+	var statusErr *apierrors.StatusError
+	if errors.As(err, &statusErr) {
+		return proxy.TranslateError(statusErr)
+	}
+	return err
+}
@@ -0,0 +1,264 @@
+package podexec
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	"k8s.io/apimachinery/pkg/util/httpstream/wsstream"
+	"k8s.io/apimachinery/pkg/util/remotecommand"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+)
+
+type testClientGetter struct {
+	proxy.ClientGetter
+	host string
+}
+
+func (t *testClientGetter) RESTConfig(_ *types.APIRequest) (*rest.Config, error) {
+	return &rest.Config{Host: t.host}, nil
+}
+
+// fakeStreamingServer speaks the streaming protocols of Kubernetes. Commands echo their input in upper case on stdout,
+// write their name on stderr and exit with code 3, while forwarded ports echo the data they receive.
+type fakeStreamingServer struct {
+	lock     sync.Mutex
+	requests []*url.URL
+}
+
+func (f *fakeStreamingServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	f.lock.Lock()
+	f.requests = append(f.requests, req.URL)
+	f.lock.Unlock()
+
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/exec"):
+		f.exec(rw, req)
+	case strings.HasSuffix(req.URL.Path, "/portforward"):
+		f.portForward(rw, req)
+	default:
+		http.NotFound(rw, req)
+	}
+}
+
+func (f *fakeStreamingServer) exec(rw http.ResponseWriter, req *http.Request) {
+	conn := wsstream.NewConn(map[string]wsstream.ChannelProtocolConfig{
+		remotecommand.StreamProtocolV5Name: {
+			Binary: true,
+			Channels: []wsstream.ChannelType{
+				wsstream.ReadChannel,
+				wsstream.WriteChannel,
+				wsstream.WriteChannel,
+				wsstream.WriteChannel,
+				wsstream.ReadChannel,
+			},
+		},
+	})
+	_, streams, err := conn.Open(rw, req)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	input, _ := io.ReadAll(streams[0])
+	_, _ = streams[1].Write([]byte(strings.ToUpper(string(input))))
+	_, _ = streams[2].Write([]byte(strings.Join(req.URL.Query()["command"], " ")))
+	status, _ := json.Marshal(metav1.Status{
+		Status: metav1.StatusFailure,
+		Reason: remotecommand.NonZeroExitCodeReason,
+		Details: &metav1.StatusDetails{
+			Causes: []metav1.StatusCause{{Type: remotecommand.ExitCodeCauseType, Message: "3"}},
+		},
+	})
+	_, _ = streams[3].Write(status)
+}
+
+func (f *fakeStreamingServer) portForward(rw http.ResponseWriter, req *http.Request) {
+	if wsstream.IsWebSocketRequest(req) {
+		// as an older server, only SPDY is supported
+		http.Error(rw, "websockets are not supported", http.StatusBadRequest)
+		return
+	}
+	if _, err := httpstream.Handshake(req, rw, []string{portforward.PortForwardProtocolV1Name}); err != nil {
+		return
+	}
+	streams := make(chan httpstream.Stream, 2)
+	conn := spdy.NewResponseUpgrader().UpgradeResponse(rw, req, func(stream httpstream.Stream, _ <-chan struct{}) error {
+		streams <- stream
+		return nil
+	})
+	if conn == nil {
+		return
+	}
+	defer conn.Close()
+
+	for range 2 {
+		stream := <-streams
+		if stream.Headers().Get("streamType") == "error" {
+			defer stream.Close()
+			continue
+		}
+		_, _ = io.Copy(stream, stream)
+		_ = stream.Close()
+	}
+}
+
+// newServer returns a server serving the given link handler for the pod default/web, as the user allowed the given
+// subresource
+func newServer(t *testing.T, handler http.Handler, link, allowed string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		accessSet := &accesscontrol.AccessSet{}
+		accessSet.Add("create", schema.GroupResource{Resource: "pods/" + allowed}, accesscontrol.Access{
+			Namespace:    accesscontrol.All,
+			ResourceName: accesscontrol.All,
+		})
+		schemas := types.EmptyAPISchemas()
+		accesscontrol.SetAccessSetAttribute(schemas, accessSet)
+		apiOp := types.StoreAPIContext(&types.APIRequest{
+			Schemas:   schemas,
+			Namespace: "default",
+			Name:      "web",
+			Link:      link,
+			Request:   req,
+			Response:  rw,
+			ErrorHandler: func(apiOp *types.APIRequest, err error) {
+				http.Error(apiOp.Response, err.Error(), err.(*apierror.APIError).Code.Status)
+			},
+		})
+		handler.ServeHTTP(rw, apiOp.Request)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func dial(t *testing.T, server *httptest.Server, query string) (*websocket.Conn, *http.Response, error) {
+	u, err := url.Parse(server.URL + "?" + query)
+	require.NoError(t, err)
+	u.Scheme = "ws"
+	conn, resp, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if conn != nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+func TestAddLinks(t *testing.T) {
+	apiSchema := &types.APISchema{}
+	AddLinks(&testClientGetter{}, apiSchema)
+	assert.Len(t, apiSchema.LinkHandlers, 3)
+
+	// without a REST config, the links can't be served
+	apiSchema = &types.APISchema{}
+	AddLinks(struct{ proxy.ClientGetter }{}, apiSchema)
+	assert.Empty(t, apiSchema.LinkHandlers)
+}
+
+func TestExec(t *testing.T) {
+	fake := &fakeStreamingServer{}
+	kubernetes := httptest.NewServer(fake)
+	defer kubernetes.Close()
+	apiSchema := &types.APISchema{}
+	AddLinks(&testClientGetter{host: kubernetes.URL}, apiSchema)
+	server := newServer(t, apiSchema.LinkHandlers[execLink], execLink, execLink)
+
+	conn, _, err := dial(t, server, "command=sh&command=-c&container=app")
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte("\x00hello")))
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte("\x00 world")))
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte{stdinChannel}))
+
+	output := map[byte]string{}
+	var status Status
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), err)
+			break
+		}
+		require.NotEmpty(t, data)
+		if data[0] == statusChannel {
+			require.NoError(t, json.Unmarshal(data[1:], &status))
+			continue
+		}
+		output[data[0]] += string(data[1:])
+	}
+	assert.Equal(t, "HELLO WORLD", output[stdoutChannel])
+	assert.Equal(t, "sh -c", output[stderrChannel])
+	require.NotNil(t, status.ExitCode)
+	assert.Equal(t, 3, *status.ExitCode)
+
+	require.Len(t, fake.requests, 1)
+	assert.Equal(t, "/api/v1/namespaces/default/pods/web/exec", fake.requests[0].Path)
+	assert.Equal(t, "app", fake.requests[0].Query().Get("container"))
+	assert.Equal(t, "true", fake.requests[0].Query().Get("stdin"))
+}
+
+func TestExecErrors(t *testing.T) {
+	apiSchema := &types.APISchema{}
+	AddLinks(&testClientGetter{host: "http://localhost:1"}, apiSchema)
+
+	tests := []struct {
+		name     string
+		allowed  string
+		query    string
+		wantCode int
+	}{
+		{name: "missing command", allowed: execLink, wantCode: http.StatusUnprocessableEntity},
+		{name: "not allowed", allowed: attachLink, query: "command=sh", wantCode: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newServer(t, apiSchema.LinkHandlers[execLink], execLink, test.allowed)
+			_, resp, err := dial(t, server, test.query)
+			require.ErrorIs(t, err, websocket.ErrBadHandshake)
+			assert.Equal(t, test.wantCode, resp.StatusCode)
+		})
+	}
+}
+
+func TestPortForward(t *testing.T) {
+	fake := &fakeStreamingServer{}
+	kubernetes := httptest.NewServer(fake)
+	defer kubernetes.Close()
+	apiSchema := &types.APISchema{}
+	AddLinks(&testClientGetter{host: kubernetes.URL}, apiSchema)
+	server := newServer(t, apiSchema.LinkHandlers[portForwardLink], portForwardLink, portForwardLink)
+
+	_, resp, err := dial(t, server, "port=0")
+	require.ErrorIs(t, err, websocket.ErrBadHandshake)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	conn, _, err := dial(t, server, "port=8080")
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte("\x00ping")))
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte{dataChannel}))
+
+	var received string
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), err)
+			break
+		}
+		require.Equal(t, dataChannel, data[0])
+		received += string(data[1:])
+	}
+	assert.Equal(t, "ping", received)
+}
@@ -0,0 +1,154 @@
+package podexec
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+type dialerFactory func(cfg *rest.Config, u *url.URL) (httpstream.Dialer, error)
+
+// newDialer returns a dialer tunneling SPDY over websockets, falling back to SPDY for the servers not supporting it yet
+func newDialer(cfg *rest.Config, u *url.URL) (httpstream.Dialer, error) {
+	transport, spdyUpgrader, err := spdy.RoundTripperFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	spdyDialer := spdy.NewDialer(spdyUpgrader, &http.Client{Transport: transport}, http.MethodPost, u)
+	tunnelingDialer, err := portforward.NewSPDYOverWebsocketDialer(u, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return portforward.NewFallbackDialer(tunnelingDialer, spdyDialer, shouldFallback), nil
+}
+
+// PortForwardHandler forwards a single connection to a port of the requested pod, given by the port query parameter
+type PortForwardHandler struct {
+	cg        proxy.RESTConfigGetter
+	newDialer dialerFactory
+}
+
+func (p *PortForwardHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+
+	port := apiOp.Request.URL.Query().Get("port")
+	if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+		apiOp.WriteError(apierror.NewAPIError(validation.InvalidFormat, "port must be a number between 1 and 65535"))
+		return
+	}
+	if err := prepare(apiOp, portForwardLink); err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	cfg, err := p.cg.RESTConfig(apiOp)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	u, err := subresourceURL(cfg, apiOp, portForwardLink, nil)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	dialer, err := p.newDialer(cfg, u)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	// the connection to the pod is made before upgrading the client connection, so that errors get a status code
+	streamConn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		apiOp.WriteError(translateError(err))
+		return
+	}
+	defer streamConn.Close()
+	errorStream, dataStream, err := createStreams(streamConn, port)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+	defer dataStream.Reset()
+
+	conn, err := upgrader.Upgrade(rw, req, nil)
+	if err != nil {
+		return
+	}
+	s := &session{conn: conn}
+	defer s.close()
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	go s.read(cancel, func(channel byte, data []byte) {
+		if channel != dataChannel {
+			return
+		}
+		if len(data) == 0 {
+			_ = dataStream.Close()
+			return
+		}
+		if _, err := dataStream.Write(data); err != nil {
+			logrus.Debugf("Failed to forward data to %s/%s:%s: %v", apiOp.Namespace, apiOp.Name, port, err)
+		}
+	})
+
+	// the forwarding is complete once the pod has closed both the data and error streams
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(s.writer(dataChannel), dataStream)
+	}()
+	go func() {
+		defer wg.Done()
+		message, err := io.ReadAll(errorStream)
+		if err == nil && len(message) > 0 {
+			_ = s.write(errorChannel, message)
+		}
+	}()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+	case <-streamConn.CloseChan():
+	case <-done:
+	}
+}
+
+// createStreams creates the error and data streams forwarding a connection to the port, as kubectl does
+func createStreams(streamConn httpstream.Connection, port string) (httpstream.Stream, httpstream.Stream, error) {
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, port)
+	headers.Set(corev1.PortForwardRequestIDHeader, "0")
+	errorStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the error stream is only read from
+	_ = errorStream.Close()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		return nil, nil, err
+	}
+	return errorStream, dataStream, nil
+}
@@ -9,12 +9,19 @@ import (
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/client"
 	"github.com/rancher/steve/pkg/clustercache"
+	"github.com/rancher/steve/pkg/redaction"
 	"github.com/rancher/steve/pkg/resources/apigroups"
 	"github.com/rancher/steve/pkg/resources/cluster"
 	"github.com/rancher/steve/pkg/resources/common"
 	"github.com/rancher/steve/pkg/resources/counts"
 	"github.com/rancher/steve/pkg/resources/formatters"
+	"github.com/rancher/steve/pkg/resources/helm"
+	"github.com/rancher/steve/pkg/resources/images"
+	"github.com/rancher/steve/pkg/resources/logs"
+	"github.com/rancher/steve/pkg/resources/nodes"
+	"github.com/rancher/steve/pkg/resources/podexec"
 	"github.com/rancher/steve/pkg/resources/userpreferences"
+	"github.com/rancher/steve/pkg/resources/workloads"
 	"github.com/rancher/steve/pkg/schema"
 	"github.com/rancher/steve/pkg/stores/proxy"
 	"github.com/rancher/steve/pkg/summarycache"
@@ -24,7 +31,7 @@ import (
 )
 
 func DefaultSchemas(ctx context.Context, baseSchema *types.APISchemas, ccache clustercache.ClusterCache,
-	cg proxy.ClientGetter, schemaFactory schema.Factory, serverVersion string) error {
+	cg proxy.ClientGetter, schemaFactory schema.Factory, serverVersion string, redactionPolicy *redaction.Policy) error {
 	counts.Register(baseSchema, ccache)
 	subscribe.Register(baseSchema, func(apiOp *types.APIRequest) *types.APISchemas {
 		user, ok := request.UserFrom(apiOp.Context())
@@ -39,6 +46,10 @@ func DefaultSchemas(ctx context.Context, baseSchema *types.APISchemas, ccache cl
 	apiroot.Register(baseSchema, []string{"v1"}, "proxy:/apis")
 	cluster.Register(ctx, baseSchema, cg, schemaFactory)
 	userpreferences.Register(baseSchema)
+	workloads.Register(baseSchema)
+	nodes.Register(baseSchema)
+	helm.Register(baseSchema, redactionPolicy)
+	images.Register(baseSchema)
 	return nil
 }
 
@@ -49,7 +60,7 @@ func DefaultSchemaTemplates(cf *client.Factory,
 	discovery discovery.DiscoveryInterface,
 	namespaceCache corecontrollers.NamespaceCache,
 	options common.TemplateOptions) []schema.Template {
-	return []schema.Template{
+	templates := []schema.Template{
 		common.DefaultTemplate(cf, summaryCache, lookup, namespaceCache, options),
 		apigroups.Template(discovery),
 		{
@@ -70,7 +81,11 @@ func DefaultSchemaTemplates(cf *client.Factory,
 				cluster.AddApply(baseSchemas, apiSchema)
 			},
 		},
+		nodes.Template(cf),
+		podexec.Template(cf),
 	}
+	templates = append(templates, logs.Templates(cf)...)
+	return append(templates, workloads.Templates(cf)...)
 }
 
 // DefaultSchemaTemplatesForStore returns the same default templates as DefaultSchemaTemplates, only using DefaultSchemaTemplateFoStore internally to construct the templates.
@@ -81,7 +96,7 @@ func DefaultSchemaTemplatesForStore(store types.Store,
 	discovery discovery.DiscoveryInterface,
 	options common.TemplateOptions) []schema.Template {
 
-	return []schema.Template{
+	templates := []schema.Template{
 		common.DefaultTemplateForStore(store, summaryCache, lookup, options),
 		apigroups.Template(discovery),
 		{
@@ -103,4 +118,11 @@ func DefaultSchemaTemplatesForStore(store types.Store,
 			},
 		},
 	}
+	// the templates of actions and links need a client, only added if one was given
+	if cg := options.ClientGetter; cg != nil {
+		templates = append(templates, nodes.Template(cg), podexec.Template(cg))
+		templates = append(templates, logs.Templates(cg)...)
+		templates = append(templates, workloads.Templates(cg)...)
+	}
+	return templates
 }
@@ -5,7 +5,6 @@ import (
 	"context"
 	"fmt"
 	"sync"
-	"time"
 
 	"github.com/rancher/apiserver/pkg/builtin"
 	schemastore "github.com/rancher/apiserver/pkg/store/schema"
@@ -160,26 +159,17 @@ func (s *Store) sendSchemas(result chan types.APIEvent, apiOp *types.APIRequest,
 	return schemas
 }
 
-// userChangeNotify gets the provided users AccessSet every 2 seconds.
+// userChangeNotify watches the provided users AccessSet, pushed on changes to roles and bindings or polled every 2
+// seconds, depending on the AccessSetLookup.
 // If the AccessSet has changed the caller is notified via an empty struct sent on the returned channel.
 // If the given context is finished then the returned channel will be closed.
 func (s *Store) userChangeNotify(ctx context.Context, user user.Info) chan interface{} {
-	as := s.asl.AccessFor(user)
+	changes := accesscontrol.NotifyAccessChanges(ctx, s.asl, user)
 	result := make(chan interface{})
 	go func() {
 		defer close(result)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-time.After(2 * time.Second):
-			}
-
-			newAS := s.asl.AccessFor(user)
-			if newAS.ID != as.ID {
-				result <- struct{}{}
-				as = newAS
-			}
+		for range changes {
+			result <- struct{}{}
 		}
 	}()
 
@@ -0,0 +1,104 @@
+// Package certificates provides the cache.TransformFunc's setting the expiry and identity of the certificates held by
+// TLS secrets and cert-manager Certificates, so that they can be sorted and filtered without decoding the certificate
+package certificates
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const tlsSecretType = "kubernetes.io/tls"
+
+var (
+	SecretGVK      = k8sschema.GroupVersionKind{Group: "", Version: "v1", Kind: "Secret"}
+	CertificateGVK = k8sschema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+	// NotAfterField holds the expiry of the certificate, in milliseconds since the epoch as for other dates
+	NotAfterField = []string{"metadata", "certificate", "notAfter"}
+	// IssuerField holds the issuer of the certificate
+	IssuerField = []string{"metadata", "certificate", "issuer"}
+	// SubjectField holds the subject of the certificate
+	SubjectField = []string{"metadata", "certificate", "subject"}
+	// DNSNamesField holds the DNS names the certificate is valid for
+	DNSNamesField = []string{"metadata", "certificate", "dnsNames"}
+)
+
+// TransformTLSSecret sets the certificate fields of a TLS secret from the leaf certificate of its tls.crt. Other
+// secrets, and those whose certificate can't be parsed, are left unchanged.
+func TransformTLSSecret(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if secretType, _, _ := unstructured.NestedString(obj.Object, "type"); secretType != tlsSecretType {
+		return obj, nil
+	}
+	encoded, ok, err := unstructured.NestedString(obj.Object, "data", "tls.crt")
+	if err != nil || !ok {
+		return obj, err
+	}
+	cert, err := parseCertificate(encoded)
+	if err != nil {
+		logrus.Errorf("parse certificate of secret %s/%s, failed with error: %s", obj.GetNamespace(), obj.GetName(), err)
+		return obj, nil
+	}
+	return setCertificate(obj, cert.NotAfter, cert.Issuer.String(), cert.Subject.String(), cert.DNSNames)
+}
+
+// TransformCertificate sets the certificate fields of a cert-manager Certificate from its spec and status. The
+// issuer is the name of the cert-manager issuer, and the expiry is only known once the certificate has been issued.
+// Certificates with an invalid expiry are left unchanged.
+func TransformCertificate(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	var notAfter time.Time
+	if value, ok, _ := unstructured.NestedString(obj.Object, "status", "notAfter"); ok {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			logrus.Errorf("parse notAfter of certificate %s/%s, failed with error: %s", obj.GetNamespace(), obj.GetName(), err)
+			return obj, nil
+		}
+		notAfter = t
+	}
+	issuer, _, _ := unstructured.NestedString(obj.Object, "spec", "issuerRef", "name")
+	subject, _, _ := unstructured.NestedString(obj.Object, "spec", "commonName")
+	dnsNames, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "dnsNames")
+	return setCertificate(obj, notAfter, issuer, subject, dnsNames)
+}
+
+// parseCertificate returns the first certificate of a base64 encoded PEM bundle, which is the leaf certificate
+func parseCertificate(encoded string) (*x509.Certificate, error) {
+	bundle, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, bundle = pem.Decode(bundle)
+		if block == nil {
+			return nil, fmt.Errorf("no certificate found")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+func setCertificate(obj *unstructured.Unstructured, notAfter time.Time, issuer, subject string, dnsNames []string) (*unstructured.Unstructured, error) {
+	if !notAfter.IsZero() {
+		if err := unstructured.SetNestedField(obj.Object, notAfter.UnixMilli(), NotAfterField...); err != nil {
+			return obj, err
+		}
+	}
+	if err := unstructured.SetNestedField(obj.Object, issuer, IssuerField...); err != nil {
+		return obj, err
+	}
+	if err := unstructured.SetNestedField(obj.Object, subject, SubjectField...); err != nil {
+		return obj, err
+	}
+	if err := unstructured.SetNestedStringSlice(obj.Object, dnsNames, DNSNamesField...); err != nil {
+		return obj, err
+	}
+	return obj, nil
+}
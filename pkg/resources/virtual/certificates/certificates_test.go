@@ -0,0 +1,181 @@
+package certificates
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// newCertificate returns a PEM encoded certificate for the given names, signed by a new CA, followed by the CA
+func newCertificate(t *testing.T, notAfter time.Time, commonName string, dnsNames ...string) []byte {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca", Organization: []string{"steve"}},
+		NotBefore:             notAfter.Add(-48 * time.Hour),
+		NotAfter:              notAfter.Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})
+	return append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})...)
+}
+
+func TestTransformTLSSecret(t *testing.T) {
+	notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	cert := newCertificate(t, notAfter, "web.example.com", "web.example.com", "www.example.com")
+	key := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("key")})
+
+	tests := []struct {
+		name         string
+		secretType   string
+		data         map[string]interface{}
+		wantNotAfter int64
+		wantIssuer   string
+		wantSubject  string
+		wantDNSNames []string
+	}{
+		{
+			name:       "tls secret",
+			secretType: "kubernetes.io/tls",
+			data: map[string]interface{}{
+				"tls.crt": base64.StdEncoding.EncodeToString(cert),
+				"tls.key": base64.StdEncoding.EncodeToString(key),
+			},
+			wantNotAfter: notAfter.UnixMilli(),
+			wantIssuer:   "CN=test-ca,O=steve",
+			wantSubject:  "CN=web.example.com",
+			wantDNSNames: []string{"web.example.com", "www.example.com"},
+		},
+		{
+			name:       "key before the certificate",
+			secretType: "kubernetes.io/tls",
+			data: map[string]interface{}{
+				"tls.crt": base64.StdEncoding.EncodeToString(append(key, cert...)),
+			},
+			wantNotAfter: notAfter.UnixMilli(),
+			wantIssuer:   "CN=test-ca,O=steve",
+			wantSubject:  "CN=web.example.com",
+			wantDNSNames: []string{"web.example.com", "www.example.com"},
+		},
+		{
+			name:       "other secret",
+			secretType: "Opaque",
+			data: map[string]interface{}{
+				"tls.crt": base64.StdEncoding.EncodeToString(cert),
+			},
+		},
+		{
+			name:       "tls secret without certificate",
+			secretType: "kubernetes.io/tls",
+			data:       map[string]interface{}{},
+		},
+		{
+			name:       "invalid certificate",
+			secretType: "kubernetes.io/tls",
+			data: map[string]interface{}{
+				"tls.crt": base64.StdEncoding.EncodeToString([]byte("not a certificate")),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"type":       test.secretType,
+				"metadata":   map[string]interface{}{"name": "web", "namespace": "default"},
+				"data":       test.data,
+			}}
+
+			got, err := TransformTLSSecret(obj)
+			require.NoError(t, err)
+
+			if test.wantNotAfter == 0 {
+				_, found, _ := unstructured.NestedMap(got.Object, "metadata", "certificate")
+				assert.False(t, found)
+				return
+			}
+			notAfter, _, _ := unstructured.NestedInt64(got.Object, NotAfterField...)
+			issuer, _, _ := unstructured.NestedString(got.Object, IssuerField...)
+			subject, _, _ := unstructured.NestedString(got.Object, SubjectField...)
+			dnsNames, _, _ := unstructured.NestedStringSlice(got.Object, DNSNamesField...)
+			assert.Equal(t, test.wantNotAfter, notAfter)
+			assert.Equal(t, test.wantIssuer, issuer)
+			assert.Equal(t, test.wantSubject, subject)
+			assert.Equal(t, test.wantDNSNames, dnsNames)
+		})
+	}
+}
+
+func TestTransformCertificate(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Certificate",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"commonName": "web.example.com",
+			"dnsNames":   []interface{}{"web.example.com"},
+			"issuerRef":  map[string]interface{}{"name": "letsencrypt", "kind": "ClusterIssuer"},
+		},
+		"status": map[string]interface{}{
+			"notAfter": "2030-01-02T03:04:05Z",
+		},
+	}}
+
+	got, err := TransformCertificate(obj)
+	require.NoError(t, err)
+
+	notAfter, _, _ := unstructured.NestedInt64(got.Object, NotAfterField...)
+	issuer, _, _ := unstructured.NestedString(got.Object, IssuerField...)
+	subject, _, _ := unstructured.NestedString(got.Object, SubjectField...)
+	dnsNames, _, _ := unstructured.NestedStringSlice(got.Object, DNSNamesField...)
+	assert.Equal(t, time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC).UnixMilli(), notAfter)
+	assert.Equal(t, "letsencrypt", issuer)
+	assert.Equal(t, "web.example.com", subject)
+	assert.Equal(t, []string{"web.example.com"}, dnsNames)
+
+	// not issued yet
+	unstructured.RemoveNestedField(obj.Object, "status")
+	unstructured.RemoveNestedField(obj.Object, "metadata", "certificate")
+	got, err = TransformCertificate(obj)
+	require.NoError(t, err)
+	_, found, _ := unstructured.NestedInt64(got.Object, NotAfterField...)
+	assert.False(t, found)
+
+	// an invalid expiry doesn't fail the transform
+	unstructured.RemoveNestedField(obj.Object, "metadata", "certificate")
+	require.NoError(t, unstructured.SetNestedField(obj.Object, "soon", "status", "notAfter"))
+	got, err = TransformCertificate(obj)
+	require.NoError(t, err)
+	_, found, _ = unstructured.NestedMap(got.Object, "metadata", "certificate")
+	assert.False(t, found)
+}
@@ -0,0 +1,146 @@
+// Package usage provides the cache.TransformFunc's computing the resource usage of pods and nodes reported by
+// metrics.k8s.io, and the Poller caching these metrics so that the usage can be joined to pods and nodes
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	PodMetricsGVK  = k8sschema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "PodMetrics"}
+	NodeMetricsGVK = k8sschema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "NodeMetrics"}
+
+	// CPUField holds the CPU usage, in millicores
+	CPUField = []string{"metadata", "usage", "cpu"}
+	// MemoryField holds the memory usage, in bytes
+	MemoryField = []string{"metadata", "usage", "memory"}
+)
+
+// TransformPodMetrics sets the usage fields of a PodMetrics to the sum of the usage of its containers
+func TransformPodMetrics(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	containers, _, err := unstructured.NestedSlice(obj.Object, "containers")
+	if err != nil {
+		return obj, err
+	}
+	var cpu, memory resource.Quantity
+	for _, container := range containers {
+		container, ok := container.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		containerCPU, containerMemory, err := parseUsage(container)
+		if err != nil {
+			return obj, fmt.Errorf("container usage of pod %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+		}
+		cpu.Add(containerCPU)
+		memory.Add(containerMemory)
+	}
+	return setUsage(obj, cpu, memory)
+}
+
+// TransformNodeMetrics sets the usage fields of a NodeMetrics
+func TransformNodeMetrics(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	cpu, memory, err := parseUsage(obj.Object)
+	if err != nil {
+		return obj, fmt.Errorf("usage of node %s: %w", obj.GetName(), err)
+	}
+	return setUsage(obj, cpu, memory)
+}
+
+// parseUsage returns the quantities of the usage field of the given object, missing ones being zero
+func parseUsage(obj map[string]interface{}) (resource.Quantity, resource.Quantity, error) {
+	var result [2]resource.Quantity
+	for i, name := range []string{"cpu", "memory"} {
+		value, ok, err := unstructured.NestedString(obj, "usage", name)
+		if err != nil || !ok {
+			continue
+		}
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return result[0], result[1], err
+		}
+		result[i] = q
+	}
+	return result[0], result[1], nil
+}
+
+func setUsage(obj *unstructured.Unstructured, cpu, memory resource.Quantity) (*unstructured.Unstructured, error) {
+	if err := unstructured.SetNestedField(obj.Object, cpu.MilliValue(), CPUField...); err != nil {
+		return obj, err
+	}
+	if err := unstructured.SetNestedField(obj.Object, memory.Value(), MemoryField...); err != nil {
+		return obj, err
+	}
+	return obj, nil
+}
+
+// Warmer warms up the cache of a given type
+type Warmer interface {
+	Prewarm(ctx context.Context, schema *types.APISchema) error
+}
+
+// Poller keeps the metrics of pods and nodes cached while metrics.k8s.io is served. As the metrics can't be watched,
+// their cache polls them, and the usage is joined to pods and nodes whenever it changes. If metrics.k8s.io isn't
+// served, eg. when metrics-server isn't installed, nothing is cached and the usage of pods and nodes is left empty.
+type Poller struct {
+	ctx    context.Context
+	warmer Warmer
+
+	lock     sync.Mutex
+	starting map[k8sschema.GroupVersionKind]bool
+}
+
+func NewPoller(ctx context.Context, warmer Warmer) *Poller {
+	return &Poller{
+		ctx:      ctx,
+		warmer:   warmer,
+		starting: map[k8sschema.GroupVersionKind]bool{},
+	}
+}
+
+// OnSchemas starts caching the metrics of pods and nodes in the background, if they are served and not cached yet.
+// Their caches are stopped when their schemas are removed, and started again once they're back.
+func (p *Poller) OnSchemas(schemas *schema.Collection) error {
+	for _, id := range schemas.IDs() {
+		s := schemas.Schema(id)
+		if s == nil {
+			continue
+		}
+		gvk := attributes.GVK(s)
+		if gvk != PodMetricsGVK && gvk != NodeMetricsGVK {
+			continue
+		}
+		p.start(gvk, s)
+	}
+	return nil
+}
+
+func (p *Poller) start(gvk k8sschema.GroupVersionKind, s *types.APISchema) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.starting[gvk] {
+		return
+	}
+	p.starting[gvk] = true
+
+	go func() {
+		defer func() {
+			p.lock.Lock()
+			delete(p.starting, gvk)
+			p.lock.Unlock()
+		}()
+		if err := p.warmer.Prewarm(p.ctx, s); err != nil {
+			logrus.Warnf("Failed to cache %s, the usage of pods and nodes won't be available: %v", s.ID, err)
+		}
+	}()
+}
@@ -0,0 +1,162 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestTransformPodMetrics(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers []interface{}
+		wantCPU    int64
+		wantMemory int64
+		wantErr    bool
+	}{
+		{
+			name: "sum of containers",
+			containers: []interface{}{
+				map[string]interface{}{"name": "app", "usage": map[string]interface{}{"cpu": "250m", "memory": "64Mi"}},
+				map[string]interface{}{"name": "sidecar", "usage": map[string]interface{}{"cpu": "1", "memory": "1Gi"}},
+			},
+			wantCPU:    1250,
+			wantMemory: 64<<20 + 1<<30,
+		},
+		{
+			name: "nanocores",
+			containers: []interface{}{
+				map[string]interface{}{"name": "app", "usage": map[string]interface{}{"cpu": "1500000n", "memory": "2048Ki"}},
+			},
+			wantCPU:    2,
+			wantMemory: 2 << 20,
+		},
+		{
+			name: "missing usage",
+			containers: []interface{}{
+				map[string]interface{}{"name": "app"},
+			},
+		},
+		{
+			name: "invalid quantity",
+			containers: []interface{}{
+				map[string]interface{}{"name": "app", "usage": map[string]interface{}{"cpu": "lots"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "metrics.k8s.io/v1beta1",
+				"kind":       "PodMetrics",
+				"metadata": map[string]interface{}{
+					"name":      "web",
+					"namespace": "default",
+				},
+				"containers": test.containers,
+			}}
+			result, err := TransformPodMetrics(obj)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			cpu, _, _ := unstructured.NestedInt64(result.Object, CPUField...)
+			memory, _, _ := unstructured.NestedInt64(result.Object, MemoryField...)
+			assert.Equal(t, test.wantCPU, cpu)
+			assert.Equal(t, test.wantMemory, memory)
+		})
+	}
+}
+
+func TestTransformNodeMetrics(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "metrics.k8s.io/v1beta1",
+		"kind":       "NodeMetrics",
+		"metadata": map[string]interface{}{
+			"name": "node1",
+		},
+		"usage": map[string]interface{}{"cpu": "3", "memory": "4Gi"},
+	}}
+	result, err := TransformNodeMetrics(obj)
+	require.NoError(t, err)
+	cpu, _, _ := unstructured.NestedInt64(result.Object, CPUField...)
+	memory, _, _ := unstructured.NestedInt64(result.Object, MemoryField...)
+	assert.Equal(t, int64(3000), cpu)
+	assert.Equal(t, int64(4<<30), memory)
+}
+
+type testWarmer struct {
+	lock   sync.Mutex
+	warmed []string
+	fail   bool
+}
+
+func (w *testWarmer) Prewarm(_ context.Context, s *types.APISchema) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.warmed = append(w.warmed, s.ID)
+	if w.fail {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func (w *testWarmer) Warmed() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return append([]string(nil), w.warmed...)
+}
+
+func newCollection(gvks map[string]k8sschema.GroupVersionKind) *schema.Collection {
+	collection := schema.NewCollection(context.Background(), types.EmptyAPISchemas(), nil)
+	byID := map[string]*types.APISchema{}
+	for id, gvk := range gvks {
+		s := &types.APISchema{Schema: &schemas.Schema{ID: id}}
+		attributes.SetGVK(s, gvk)
+		attributes.SetGVR(s, gvk.GroupVersion().WithResource(strings.ToLower(gvk.Kind)))
+		byID[id] = s
+	}
+	collection.Reset(byID)
+	return collection
+}
+
+func TestPoller(t *testing.T) {
+	warmer := &testWarmer{fail: true}
+	p := NewPoller(t.Context(), warmer)
+
+	// metrics.k8s.io isn't served
+	require.NoError(t, p.OnSchemas(newCollection(map[string]k8sschema.GroupVersionKind{
+		"pod": {Version: "v1", Kind: "Pod"},
+	})))
+
+	served := newCollection(map[string]k8sschema.GroupVersionKind{
+		"pod":                  {Version: "v1", Kind: "Pod"},
+		"metrics.k8s.io.pods":  PodMetricsGVK,
+		"metrics.k8s.io.nodes": NodeMetricsGVK,
+	})
+	require.NoError(t, p.OnSchemas(served))
+	assert.Eventually(t, func() bool {
+		return len(warmer.Warmed()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{"metrics.k8s.io.pods", "metrics.k8s.io.nodes"}, warmer.Warmed())
+
+	// failures are retried when schemas change
+	require.NoError(t, p.OnSchemas(served))
+	assert.Eventually(t, func() bool {
+		return len(warmer.Warmed()) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -8,9 +8,11 @@ import (
 	"time"
 
 	rescommon "github.com/rancher/steve/pkg/resources/common"
+	"github.com/rancher/steve/pkg/resources/virtual/certificates"
 	"github.com/rancher/steve/pkg/resources/virtual/clusters"
 	"github.com/rancher/steve/pkg/resources/virtual/common"
 	"github.com/rancher/steve/pkg/resources/virtual/events"
+	"github.com/rancher/steve/pkg/resources/virtual/usage"
 
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -41,6 +43,14 @@ func (t *TransformBuilder) GetTransformFunc(gvk schema.GroupVersionKind, columns
 		converters = append(converters, events.TransformEventObject)
 	} else if gvk.Kind == "Cluster" && gvk.Group == "management.cattle.io" && gvk.Version == "v3" {
 		converters = append(converters, clusters.TransformManagedCluster)
+	} else if gvk == usage.PodMetricsGVK {
+		converters = append(converters, usage.TransformPodMetrics)
+	} else if gvk == usage.NodeMetricsGVK {
+		converters = append(converters, usage.TransformNodeMetrics)
+	} else if gvk == certificates.SecretGVK {
+		converters = append(converters, certificates.TransformTLSSecret)
+	} else if gvk == certificates.CertificateGVK {
+		converters = append(converters, certificates.TransformCertificate)
 	}
 
 	// Detecting if we need to convert date fields
@@ -0,0 +1,311 @@
+package workloads
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/storage/names"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// restartedAtAnnotation is the pod template annotation also used by kubectl rollout restart
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+	// instantiateAnnotation marks jobs created from a CronJob by hand, as kubectl create job --from does
+	instantiateAnnotation = "cronjob.kubernetes.io/instantiate"
+)
+
+// Action runs a single workload action on the object of the request
+type Action struct {
+	cg   proxy.ClientGetter
+	name string
+	gvk  schema2.GroupVersionKind
+}
+
+func (a *Action) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+
+	client, err := a.cg.K8sInterface(apiOp)
+	if err != nil {
+		apiOp.WriteError(err)
+		return
+	}
+
+	result, err := a.run(apiOp, client)
+	if err != nil {
+		apiOp.WriteError(proxy.TranslateError(err))
+		return
+	}
+
+	status := http.StatusOK
+	if a.name == triggerAction {
+		status = http.StatusCreated
+	}
+	apiOp.WriteResponse(status, result)
+}
+
+func (a *Action) run(apiOp *types.APIRequest, client kubernetes.Interface) (types.APIObject, error) {
+	ctx := apiOp.Context()
+	w := &workload{
+		client:    client,
+		gvk:       a.gvk,
+		namespace: apiOp.Namespace,
+		name:      apiOp.Name,
+	}
+
+	var (
+		obj runtime.Object
+		err error
+	)
+	switch a.name {
+	case scaleAction:
+		var input ScaleInput
+		if err := decodeInput(apiOp, &input); err != nil {
+			return types.APIObject{}, err
+		}
+		if input.Replicas == nil || *input.Replicas < 0 {
+			return types.APIObject{}, apierror.NewAPIError(validation.InvalidBodyContent, "replicas must be a non-negative number")
+		}
+		obj, err = w.scale(ctx, *input.Replicas)
+	case restartAction:
+		obj, err = w.restart(ctx, time.Now())
+	case pauseAction:
+		obj, err = w.setPaused(ctx, true)
+	case resumeAction:
+		obj, err = w.setPaused(ctx, false)
+	case rollbackAction:
+		var input RollbackInput
+		if err := decodeInput(apiOp, &input); err != nil {
+			return types.APIObject{}, err
+		}
+		if input.Revision == "" {
+			return types.APIObject{}, apierror.NewAPIError(validation.MissingRequired, "revision is required")
+		}
+		obj, err = w.rollback(ctx, input.Revision)
+	case triggerAction:
+		var job *batchv1.Job
+		job, err = w.trigger(ctx)
+		if err == nil {
+			return toAPIObject(job, jobGVK, jobSchemaID)
+		}
+	default:
+		return types.APIObject{}, apierror.NewAPIError(validation.InvalidAction, "unknown action "+a.name)
+	}
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	return toAPIObject(obj, a.gvk, apiOp.Schema.ID)
+}
+
+func decodeInput(apiOp *types.APIRequest, input interface{}) error {
+	if err := json.NewDecoder(apiOp.Request.Body).Decode(input); err != nil {
+		return apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("failed to parse body: %v", err))
+	}
+	return nil
+}
+
+// workload performs the actions on a single object, using the typed client matching its kind
+type workload struct {
+	client    kubernetes.Interface
+	gvk       schema2.GroupVersionKind
+	namespace string
+	name      string
+}
+
+func (w *workload) get(ctx context.Context) (metav1.Object, error) {
+	switch w.gvk {
+	case deploymentGVK:
+		return w.client.AppsV1().Deployments(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+	case statefulSetGVK:
+		return w.client.AppsV1().StatefulSets(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+	case daemonSetGVK:
+		return w.client.AppsV1().DaemonSets(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+	case replicaSetGVK:
+		return w.client.AppsV1().ReplicaSets(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+	case cronJobGVK:
+		return w.client.BatchV1().CronJobs(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+	}
+	return nil, fmt.Errorf("unsupported workload type %s", w.gvk)
+}
+
+func (w *workload) patch(ctx context.Context, pt k8stypes.PatchType, data []byte, subresources ...string) (runtime.Object, error) {
+	opts := metav1.PatchOptions{}
+	switch w.gvk {
+	case deploymentGVK:
+		return w.client.AppsV1().Deployments(w.namespace).Patch(ctx, w.name, pt, data, opts, subresources...)
+	case statefulSetGVK:
+		return w.client.AppsV1().StatefulSets(w.namespace).Patch(ctx, w.name, pt, data, opts, subresources...)
+	case daemonSetGVK:
+		return w.client.AppsV1().DaemonSets(w.namespace).Patch(ctx, w.name, pt, data, opts, subresources...)
+	case replicaSetGVK:
+		return w.client.AppsV1().ReplicaSets(w.namespace).Patch(ctx, w.name, pt, data, opts, subresources...)
+	case cronJobGVK:
+		return w.client.BatchV1().CronJobs(w.namespace).Patch(ctx, w.name, pt, data, opts, subresources...)
+	}
+	return nil, fmt.Errorf("unsupported workload type %s", w.gvk)
+}
+
+func (w *workload) mergePatch(ctx context.Context, patch map[string]interface{}, subresources ...string) (runtime.Object, error) {
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	return w.patch(ctx, k8stypes.MergePatchType, data, subresources...)
+}
+
+// scale updates the replicas through the scale subresource, so that users only allowed to scale can do so
+func (w *workload) scale(ctx context.Context, replicas int32) (runtime.Object, error) {
+	_, err := w.mergePatch(ctx, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+		},
+	}, "scale")
+	if err != nil {
+		return nil, err
+	}
+	obj, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(runtime.Object), nil
+}
+
+func (w *workload) restart(ctx context.Context, now time.Time) (runtime.Object, error) {
+	return w.mergePatch(ctx, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{
+						restartedAtAnnotation: now.Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+}
+
+// setPaused pauses or resumes the rollout of a Deployment, or the scheduling of a CronJob
+func (w *workload) setPaused(ctx context.Context, paused bool) (runtime.Object, error) {
+	field := "paused"
+	if w.gvk == cronJobGVK {
+		field = "suspend"
+	}
+	return w.mergePatch(ctx, map[string]interface{}{
+		"spec": map[string]interface{}{
+			field: paused,
+		},
+	})
+}
+
+func (w *workload) rollback(ctx context.Context, revision string) (runtime.Object, error) {
+	if w.gvk == deploymentGVK {
+		return w.rollbackDeployment(ctx, revision)
+	}
+
+	owner, err := w.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	history, err := w.client.AppsV1().ControllerRevisions(w.namespace).Get(ctx, revision, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if !metav1.IsControlledBy(history, owner) {
+		return nil, apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("controller revision %s does not belong to %s", revision, w.name))
+	}
+	// the data of a controller revision is a strategic merge patch restoring the pod template
+	return w.patch(ctx, k8stypes.StrategicMergePatchType, history.Data.Raw)
+}
+
+func (w *workload) rollbackDeployment(ctx context.Context, revision string) (runtime.Object, error) {
+	deployment, err := w.client.AppsV1().Deployments(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if deployment.Spec.Paused {
+		return nil, apierror.NewAPIError(validation.Conflict, "a paused deployment can not be rolled back, resume it first")
+	}
+	rs, err := w.client.AppsV1().ReplicaSets(w.namespace).Get(ctx, revision, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if !metav1.IsControlledBy(rs, deployment) {
+		return nil, apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("replica set %s does not belong to %s", revision, w.name))
+	}
+
+	template := rs.Spec.Template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	data, err := json.Marshal([]map[string]interface{}{
+		{
+			"op":    "replace",
+			"path":  "/spec/template",
+			"value": template,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return w.patch(ctx, k8stypes.JSONPatchType, data)
+}
+
+// trigger creates a Job from the template of a CronJob
+func (w *workload) trigger(ctx context.Context) (*batchv1.Job, error) {
+	cronJob, err := w.client.BatchV1().CronJobs(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := map[string]string{
+		instantiateAnnotation: "manual",
+	}
+	for k, v := range cronJob.Spec.JobTemplate.Annotations {
+		annotations[k] = v
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        names.SimpleNameGenerator.GenerateName(cronJob.Name + "-manual-"),
+			Namespace:   cronJob.Namespace,
+			Labels:      cronJob.Spec.JobTemplate.Labels,
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cronJob, cronJobGVK),
+			},
+		},
+		Spec: *cronJob.Spec.JobTemplate.Spec.DeepCopy(),
+	}
+	return w.client.BatchV1().Jobs(w.namespace).Create(ctx, job, metav1.CreateOptions{})
+}
+
+// toAPIObject converts the typed objects returned by the client, which have no type information
+func toAPIObject(obj runtime.Object, gvk schema2.GroupVersionKind, schemaID string) (types.APIObject, error) {
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	unstr := &unstructured.Unstructured{Object: data}
+	unstr.SetGroupVersionKind(gvk)
+
+	id := unstr.GetName()
+	if ns := unstr.GetNamespace(); ns != "" {
+		id = ns + "/" + id
+	}
+	return types.APIObject{
+		Type:   schemaID,
+		ID:     id,
+		Object: unstr,
+	}, nil
+}
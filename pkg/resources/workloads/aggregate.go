@@ -0,0 +1,466 @@
+package workloads
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/resources/common"
+	"github.com/rancher/steve/pkg/stores/partition/listprocessor"
+	"github.com/rancher/wrangler/v3/pkg/data"
+	"github.com/rancher/wrangler/v3/pkg/summary"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	workloadSchemaID = "workload"
+
+	projectsOrNamespacesParam = "projectsornamespaces"
+	filterParam               = "filter"
+	sortParam                 = "sort"
+	pageSizeParam             = "pagesize"
+	pageParam                 = "page"
+
+	kindField = "kind"
+)
+
+var (
+	// memberSchemaIDs are the types listed by the workload schema
+	memberSchemaIDs = []string{
+		"apps.deployment",
+		"apps.statefulset",
+		"apps.daemonset",
+		"batch.job",
+		"batch.cronjob",
+		"replicationcontroller",
+	}
+
+	// podTemplatePaths are the paths of the pod template of each kind
+	podTemplatePaths = map[string][]string{
+		"Deployment":            {"spec", "template", "spec"},
+		"StatefulSet":           {"spec", "template", "spec"},
+		"DaemonSet":             {"spec", "template", "spec"},
+		"Job":                   {"spec", "template", "spec"},
+		"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+		"ReplicationController": {"spec", "template", "spec"},
+	}
+
+	// memberFields are the fields of Workloads found at the same path in the objects of all the workload types, so that
+	// lists can be filtered and sorted on them by the store of each type
+	memberFields = map[string]bool{
+		"metadata.name":              true,
+		"metadata.namespace":         true,
+		"metadata.creationTimestamp": true,
+	}
+
+	// defaultSort sorts Workloads by namespace and name
+	defaultSort = listprocessor.Sort{
+		Fields: [][]string{{"metadata", "namespace"}, {"metadata", "name"}, {kindField}},
+		Orders: []listprocessor.SortOrder{listprocessor.ASC, listprocessor.ASC, listprocessor.ASC},
+	}
+
+	workloadColumns = []common.ColumnDefinition{
+		{TableColumnDefinition: metav1.TableColumnDefinition{Name: "Name", Type: "string"}, Field: "$.metadata.name"},
+		{TableColumnDefinition: metav1.TableColumnDefinition{Name: "Namespace", Type: "string"}, Field: "$.metadata.namespace"},
+		{TableColumnDefinition: metav1.TableColumnDefinition{Name: "Kind", Type: "string"}, Field: "$.kind"},
+		{TableColumnDefinition: metav1.TableColumnDefinition{Name: "State", Type: "string"}, Field: "$.metadata.state.name"},
+		{TableColumnDefinition: metav1.TableColumnDefinition{Name: "Images", Type: "string"}, Field: "$.images"},
+		{TableColumnDefinition: metav1.TableColumnDefinition{Name: "Ready", Type: "integer"}, Field: "$.ready"},
+		{TableColumnDefinition: metav1.TableColumnDefinition{Name: "Desired", Type: "integer"}, Field: "$.desired"},
+		{TableColumnDefinition: metav1.TableColumnDefinition{Name: "Age", Type: "date"}, Field: "$.metadata.creationTimestamp"},
+	}
+)
+
+// Workload is an object of any of the workload types, with the fields they have in common
+type Workload struct {
+	ID     string   `json:"id,omitempty"`
+	Kind   string   `json:"kind"`
+	Images []string `json:"images"`
+	// Ready is the number of ready pods, or of succeeded pods for Jobs and of active jobs for CronJobs
+	Ready int `json:"ready"`
+	// Desired is the number of pods wanted, or of completions for Jobs. It is 0 for CronJobs.
+	Desired int `json:"desired"`
+}
+
+// registerAggregate adds the read-only workload schema, listing the objects of all the workload types at once
+func registerAggregate(apiSchemas *types.APISchemas) {
+	apiSchemas.InternalSchemas.TypeName(workloadSchemaID, Workload{})
+	apiSchemas.MustImportAndCustomize(Workload{}, func(schema *types.APISchema) {
+		schema.CollectionMethods = []string{http.MethodGet}
+		attributes.SetNamespaced(schema, true)
+		attributes.SetColumns(schema, workloadColumns)
+		schema.Store = &AggregateStore{}
+	})
+}
+
+// AggregateStore lists and watches the objects of all the workload types the user can list, as Workloads. Every type
+// is read with its own store, so that the objects of each type are restricted to the ones the user can see, and read
+// from the SQL cache when it is enabled. Each Workload is typed with the schema of its type, so that its links point
+// to the actual object.
+type AggregateStore struct {
+	empty.Store
+}
+
+// List supports the filter, sort, page, pagesize and projectsornamespaces parameters. Workloads are sorted by
+// namespace and name by default. When the list is only filtered and sorted on the kind and the fields common to all
+// the types, the stores of the types filter and sort their objects, only returning the ones up to the requested page.
+// Otherwise, all the objects are listed, then filtered, sorted and paginated as Workloads.
+func (a *AggregateStore) List(apiOp *types.APIRequest, _ *types.APISchema) (types.APIObjectList, error) {
+	if query, ok := parseMemberQuery(apiOp); ok {
+		return a.listMembers(apiOp, query)
+	}
+	return a.listAll(apiOp)
+}
+
+// listMembers merges the pages listed by the stores of the workload types for the given query
+func (a *AggregateStore) listMembers(apiOp *types.APIRequest, query *memberQuery) (types.APIObjectList, error) {
+	var items []unstructured.Unstructured
+	var revisions []string
+	count := 0
+	for _, member := range memberSchemas(apiOp) {
+		if !query.matchesKind(attributes.GVK(member).Kind) {
+			continue
+		}
+		memberOp := memberRequest(apiOp, member, query.params())
+		if err := memberOp.AccessControl.CanList(memberOp, member); err != nil {
+			continue
+		}
+		list, err := member.Store.List(memberOp, member)
+		if err != nil {
+			return types.APIObjectList{}, err
+		}
+		for _, obj := range list.Objects {
+			items = append(items, unstructured.Unstructured{Object: toWorkload(member, obj)})
+		}
+		count += list.Count
+		revisions = append(revisions, list.Revision)
+	}
+
+	listprocessor.SortValues(items, query.sort)
+	pages := 0
+	if query.pageSize > 0 {
+		pages = (count + query.pageSize - 1) / query.pageSize
+		offset := min(query.pageSize*(query.page-1), len(items))
+		items = items[offset:min(offset+query.pageSize, len(items))]
+	}
+	return workloadList(items, count, pages, revisions), nil
+}
+
+// listAll lists all the objects of the workload types, then filters, sorts and paginates them as Workloads
+func (a *AggregateStore) listAll(apiOp *types.APIRequest) (types.APIObjectList, error) {
+	var items []unstructured.Unstructured
+	var revisions []string
+	for _, member := range memberSchemas(apiOp) {
+		memberOp := memberRequest(apiOp, member, nil)
+		if err := memberOp.AccessControl.CanList(memberOp, member); err != nil {
+			continue
+		}
+		list, err := member.Store.List(memberOp, member)
+		if err != nil {
+			return types.APIObjectList{}, err
+		}
+		for _, obj := range list.Objects {
+			items = append(items, unstructured.Unstructured{Object: toWorkload(member, obj)})
+		}
+		revisions = append(revisions, list.Revision)
+	}
+
+	opts := listprocessor.ParseQuery(apiOp)
+	list := make(chan []unstructured.Unstructured, 1)
+	list <- items
+	close(list)
+	filtered := listprocessor.FilterList(list, opts.Filters)
+	sortOpts := opts.Sort
+	if len(sortOpts.Fields) == 0 {
+		sortOpts = defaultSort
+	}
+	listprocessor.SortValues(filtered, sortOpts)
+	page, pages := listprocessor.PaginateList(filtered, opts.Pagination)
+	return workloadList(page, len(filtered), pages, revisions), nil
+}
+
+func workloadList(items []unstructured.Unstructured, count, pages int, revisions []string) types.APIObjectList {
+	result := types.APIObjectList{
+		Count:    count,
+		Pages:    pages,
+		Revision: strings.Join(revisions, ","),
+	}
+	for _, item := range items {
+		result.Objects = append(result.Objects, types.APIObject{
+			Type:   convert.ToString(item.Object["type"]),
+			ID:     convert.ToString(item.Object["id"]),
+			Object: item.Object,
+		})
+	}
+	return result
+}
+
+// memberQuery holds the list parameters of a Workload list that can be handled by the stores of the workload types
+type memberQuery struct {
+	// filters are the filter parameters on the fields common to all the types, forwarded as is
+	filters []string
+	// kindFilters are the parsed filter parameters on the kind, selecting the types to list
+	kindFilters []listprocessor.OrFilter
+	// sort sorts the merged Workloads, memberSort is the part of it forwarded to the stores
+	sort       listprocessor.Sort
+	memberSort []string
+	pageSize   int
+	page       int
+}
+
+// parseMemberQuery returns the list parameters to forward to the stores of the workload types, or false if the list
+// is filtered or sorted on other fields than the kind and the fields common to all the types
+func parseMemberQuery(apiOp *types.APIRequest) (*memberQuery, bool) {
+	q := apiOp.Request.URL.Query()
+	query := &memberQuery{}
+
+	var kindFilters []string
+	for _, value := range q[filterParam] {
+		fields, ok := filterFields(value)
+		switch {
+		case !ok:
+			return nil, false
+		case allFields(fields, func(field string) bool { return memberFields[field] }):
+			query.filters = append(query.filters, value)
+		case allFields(fields, func(field string) bool { return field == kindField }):
+			kindFilters = append(kindFilters, value)
+		default:
+			return nil, false
+		}
+	}
+	if len(kindFilters) > 0 {
+		kindReq := apiOp.Request.Clone(apiOp.Context())
+		kindReq.URL.RawQuery = url.Values{filterParam: kindFilters}.Encode()
+		query.kindFilters = listprocessor.ParseQuery(&types.APIRequest{Request: kindReq}).Filters
+	}
+
+	opts := listprocessor.ParseQuery(apiOp)
+	query.sort = opts.Sort
+	if len(query.sort.Fields) == 0 {
+		query.sort = defaultSort
+	}
+	for i, field := range query.sort.Fields {
+		name := strings.Join(field, ".")
+		if name == kindField {
+			// each type has a single kind
+			continue
+		}
+		if !memberFields[name] {
+			return nil, false
+		}
+		if query.sort.Orders[i] == listprocessor.DESC {
+			name = "-" + name
+		}
+		query.memberSort = append(query.memberSort, name)
+	}
+
+	query.pageSize = opts.Pagination.PageSize()
+	query.page, _ = strconv.Atoi(q.Get(pageParam))
+	query.page = max(query.page, 1)
+	return query, true
+}
+
+// filterFields returns the fields of a filter parameter, or false if one of its filters has no field
+func filterFields(value string) ([]string, bool) {
+	var fields []string
+	for _, filter := range strings.Split(value, ",") {
+		field, _, ok := strings.Cut(filter, "=")
+		field = strings.TrimSuffix(field, "!")
+		if !ok || field == "" {
+			return nil, false
+		}
+		fields = append(fields, field)
+	}
+	return fields, true
+}
+
+func allFields(fields []string, f func(field string) bool) bool {
+	for _, field := range fields {
+		if !f(field) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesKind returns whether the objects of the given kind match the filters on the kind
+func (q *memberQuery) matchesKind(kind string) bool {
+	if len(q.kindFilters) == 0 {
+		return true
+	}
+	list := make(chan []unstructured.Unstructured, 1)
+	list <- []unstructured.Unstructured{{Object: map[string]interface{}{kindField: kind}}}
+	close(list)
+	return len(listprocessor.FilterList(list, q.kindFilters)) > 0
+}
+
+// params returns the list parameters for the stores of the workload types, each store returning its objects up to
+// the requested page
+func (q *memberQuery) params() url.Values {
+	params := url.Values{}
+	if len(q.filters) > 0 {
+		params[filterParam] = q.filters
+	}
+	if len(q.memberSort) > 0 {
+		params.Set(sortParam, strings.Join(q.memberSort, ","))
+	}
+	if q.pageSize > 0 {
+		params.Set(pageSizeParam, strconv.Itoa(q.pageSize*q.page))
+		params.Set(pageParam, "1")
+	}
+	return params
+}
+
+// Watch merges the watches of all the workload types the user can watch. As the revisions of the types are
+// unrelated, the watch always starts from the current state.
+func (a *AggregateStore) Watch(apiOp *types.APIRequest, _ *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	result := make(chan types.APIEvent)
+	var wg sync.WaitGroup
+	for _, member := range memberSchemas(apiOp) {
+		memberOp := memberRequest(apiOp, member, nil)
+		if err := memberOp.AccessControl.CanWatch(memberOp, member); err != nil {
+			continue
+		}
+		c, err := member.Store.Watch(memberOp, member, types.WatchRequest{Selector: w.Selector})
+		if err != nil {
+			logrus.Debugf("Failed to watch %s: %v", member.ID, err)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for event := range c {
+				if event.Error == nil && event.Object.Object != nil {
+					event.Object = types.APIObject{
+						Type:   member.ID,
+						ID:     event.Object.ID,
+						Object: toWorkload(member, event.Object),
+					}
+				}
+				select {
+				case result <- event:
+				case <-apiOp.Context().Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(result)
+	}()
+	return result, nil
+}
+
+// memberSchemas returns the schemas of the workload types the user has access to
+func memberSchemas(apiOp *types.APIRequest) []*types.APISchema {
+	var result []*types.APISchema
+	for _, id := range memberSchemaIDs {
+		schema := apiOp.Schemas.LookupSchema(id)
+		if schema == nil || schema.Store == nil {
+			continue
+		}
+		result = append(result, schema)
+	}
+	return result
+}
+
+// memberRequest builds a list request for a workload type with the given parameters, keeping the projectsornamespaces
+// parameter, as the other list parameters apply to the Workloads
+func memberRequest(apiOp *types.APIRequest, member *types.APISchema, params url.Values) *types.APIRequest {
+	query := url.Values{}
+	for name, values := range params {
+		query[name] = values
+	}
+	if values, ok := apiOp.Query[projectsOrNamespacesParam]; ok {
+		query[projectsOrNamespacesParam] = values
+	}
+	if values, ok := apiOp.Query[projectsOrNamespacesParam+"!"]; ok {
+		query[projectsOrNamespacesParam+"!"] = values
+	}
+
+	req := apiOp.Request.Clone(apiOp.Context())
+	req.URL.RawQuery = query.Encode()
+
+	memberOp := *apiOp
+	memberOp.Type = member.ID
+	memberOp.Schema = member
+	memberOp.Name = ""
+	memberOp.Query = query
+	memberOp.Request = req
+	return &memberOp
+}
+
+// toWorkload returns the fields of a Workload from an object of the given workload type
+func toWorkload(member *types.APISchema, obj types.APIObject) map[string]interface{} {
+	d := obj.Data()
+	kind := attributes.GVK(member).Kind
+
+	state := d.Map("metadata", "state")
+	if state == nil {
+		// the state is added by the formatter when the SQL cache isn't used
+		s := summary.Summarize(&unstructured.Unstructured{Object: d})
+		state = map[string]interface{}{
+			"name":          s.State,
+			"error":         s.Error,
+			"transitioning": s.Transitioning,
+			"message":       strings.Join(s.Message, ":"),
+		}
+	}
+
+	var images []interface{}
+	for _, container := range data.Object(d.Map(podTemplatePaths[kind]...)).Slice("containers") {
+		images = append(images, container.String("image"))
+	}
+
+	var ready, desired int64
+	switch kind {
+	case "Deployment", "StatefulSet", "ReplicationController":
+		ready = number(d, 0, "status", "readyReplicas")
+		desired = number(d, 1, "spec", "replicas")
+	case "DaemonSet":
+		ready = number(d, 0, "status", "numberReady")
+		desired = number(d, 0, "status", "desiredNumberScheduled")
+	case "Job":
+		ready = number(d, 0, "status", "succeeded")
+		desired = number(d, 1, "spec", "completions")
+	case "CronJob":
+		ready = int64(len(d.Slice("status", "active")))
+	}
+
+	return map[string]interface{}{
+		"id":   obj.ID,
+		"type": member.ID,
+		"kind": kind,
+		"metadata": map[string]interface{}{
+			"name":              d.String("metadata", "name"),
+			"namespace":         d.String("metadata", "namespace"),
+			"creationTimestamp": d.String("metadata", "creationTimestamp"),
+			"state":             map[string]interface{}(state),
+		},
+		"images":  images,
+		"ready":   int(ready),
+		"desired": int(desired),
+	}
+}
+
+// number returns the number at the given path, or the given default if it isn't set
+func number(d data.Object, defaultValue int64, names ...string) int64 {
+	value, ok := data.GetValue(d, names...)
+	if !ok || value == nil {
+		return defaultValue
+	}
+	n, err := convert.ToNumber(value)
+	if err != nil {
+		return defaultValue
+	}
+	return n
+}
@@ -0,0 +1,334 @@
+package workloads
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/server"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/partition/listprocessor"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// memberStore returns its objects in the namespaces requested with projectsornamespaces, filtered, sorted and
+// paginated as by the stores of steve
+type memberStore struct {
+	empty.Store
+	objects []types.APIObject
+	listOps []*types.APIRequest
+	events  chan types.APIEvent
+}
+
+func (m *memberStore) List(apiOp *types.APIRequest, _ *types.APISchema) (types.APIObjectList, error) {
+	m.listOps = append(m.listOps, apiOp)
+	namespace := apiOp.Query.Get(projectsOrNamespacesParam)
+	var items []unstructured.Unstructured
+	for _, obj := range m.objects {
+		if namespace != "" && obj.Data().String("metadata", "namespace") != namespace {
+			continue
+		}
+		items = append(items, *obj.Object.(*unstructured.Unstructured))
+	}
+
+	opts := listprocessor.ParseQuery(apiOp)
+	list := make(chan []unstructured.Unstructured, 1)
+	list <- items
+	close(list)
+	filtered := listprocessor.SortList(listprocessor.FilterList(list, opts.Filters), opts.Sort)
+	page, pages := listprocessor.PaginateList(filtered, opts.Pagination)
+
+	result := types.APIObjectList{Revision: "10", Count: len(filtered), Pages: pages}
+	for _, item := range page {
+		result.Objects = append(result.Objects, types.APIObject{ID: item.GetNamespace() + "/" + item.GetName(), Object: &item})
+	}
+	return result, nil
+}
+
+func (m *memberStore) Watch(_ *types.APIRequest, _ *types.APISchema, _ types.WatchRequest) (chan types.APIEvent, error) {
+	return m.events, nil
+}
+
+func workloadObject(kind, namespace, name string, spec, status map[string]interface{}) types.APIObject {
+	return types.APIObject{
+		ID: namespace + "/" + name,
+		Object: &unstructured.Unstructured{Object: map[string]interface{}{
+			"kind": kind,
+			"metadata": map[string]interface{}{
+				"name":              name,
+				"namespace":         namespace,
+				"creationTimestamp": "2024-05-01T10:00:00Z",
+			},
+			"spec":   spec,
+			"status": status,
+		}},
+	}
+}
+
+func podTemplate(images ...string) map[string]interface{} {
+	var containers []interface{}
+	for _, image := range images {
+		containers = append(containers, map[string]interface{}{"image": image})
+	}
+	return map[string]interface{}{"spec": map[string]interface{}{"containers": containers}}
+}
+
+func testMemberStores() map[string]*memberStore {
+	return map[string]*memberStore{
+		"apps.deployment": {objects: []types.APIObject{
+			workloadObject("Deployment", "web", "nginx",
+				map[string]interface{}{"replicas": int64(3), "template": podTemplate("nginx:1.25", "envoy:1.30")},
+				map[string]interface{}{"readyReplicas": int64(2)}),
+		}},
+		"apps.statefulset": {objects: []types.APIObject{
+			workloadObject("StatefulSet", "db", "postgres",
+				map[string]interface{}{"template": podTemplate("postgres:16")},
+				map[string]interface{}{"readyReplicas": int64(1)}),
+		}},
+		"apps.daemonset": {objects: []types.APIObject{
+			workloadObject("DaemonSet", "kube-system", "proxy",
+				map[string]interface{}{"template": podTemplate("kube-proxy:1.30")},
+				map[string]interface{}{"numberReady": int64(4), "desiredNumberScheduled": int64(5)}),
+		}},
+		"batch.job": {objects: []types.APIObject{
+			workloadObject("Job", "db", "migrate",
+				map[string]interface{}{"completions": int64(2), "template": podTemplate("migrate:2")},
+				map[string]interface{}{"succeeded": int64(1)}),
+		}},
+		"batch.cronjob": {objects: []types.APIObject{
+			workloadObject("CronJob", "db", "backup",
+				map[string]interface{}{"jobTemplate": map[string]interface{}{"spec": map[string]interface{}{"template": podTemplate("backup:1")}}},
+				map[string]interface{}{"active": []interface{}{map[string]interface{}{"name": "backup-1"}}}),
+		}},
+	}
+}
+
+func newAggregateRequest(stores map[string]*memberStore, query string) *types.APIRequest {
+	kinds := map[string]schema2.GroupVersionKind{
+		"apps.deployment":  {Group: "apps", Version: "v1", Kind: "Deployment"},
+		"apps.statefulset": {Group: "apps", Version: "v1", Kind: "StatefulSet"},
+		"apps.daemonset":   {Group: "apps", Version: "v1", Kind: "DaemonSet"},
+		"batch.job":        {Group: "batch", Version: "v1", Kind: "Job"},
+		"batch.cronjob":    {Group: "batch", Version: "v1", Kind: "CronJob"},
+	}
+	apiSchemas := types.EmptyAPISchemas()
+	Register(apiSchemas)
+	for id, store := range stores {
+		s := types.APISchema{
+			Schema: &schemas.Schema{
+				ID:                id,
+				CollectionMethods: []string{http.MethodGet},
+			},
+			Store: store,
+		}
+		attributes.SetGVK(&s, kinds[id])
+		apiSchemas.MustAddSchema(s)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/v1/workloads?"+query, nil)
+	return &types.APIRequest{
+		Type:          workloadSchemaID,
+		Schema:        apiSchemas.LookupSchema(workloadSchemaID),
+		Schemas:       apiSchemas,
+		Method:        http.MethodGet,
+		Query:         req.URL.Query(),
+		Request:       req,
+		AccessControl: &server.SchemaBasedAccess{},
+	}
+}
+
+func TestAggregateList(t *testing.T) {
+	defaultSort := []string{"metadata.namespace,metadata.name"}
+	tests := []struct {
+		name      string
+		query     string
+		wantIDs   []string
+		wantTypes []string
+		wantCount int
+		wantPages int
+		// wantListed are the types listed with the given parameters, or all of them if nil
+		wantListed []string
+		wantParams url.Values
+	}{
+		{
+			name:       "all workloads sorted by namespace and name",
+			wantIDs:    []string{"db/backup", "db/migrate", "db/postgres", "kube-system/proxy", "web/nginx"},
+			wantTypes:  []string{"batch.cronjob", "batch.job", "apps.statefulset", "apps.daemonset", "apps.deployment"},
+			wantCount:  5,
+			wantParams: url.Values{"sort": defaultSort},
+		},
+		{
+			name:       "filtered by kind",
+			query:      "filter=kind=CronJob",
+			wantIDs:    []string{"db/backup"},
+			wantTypes:  []string{"batch.cronjob"},
+			wantCount:  1,
+			wantListed: []string{"batch.cronjob"},
+			wantParams: url.Values{"sort": defaultSort},
+		},
+		{
+			name:       "filtered by name, sorted and paged by the stores of the types",
+			query:      "filter=metadata.name=o&sort=-metadata.name&pagesize=1&page=2",
+			wantIDs:    []string{"db/postgres"},
+			wantTypes:  []string{"apps.statefulset"},
+			wantCount:  2,
+			wantPages:  2,
+			wantParams: url.Values{"filter": {"metadata.name=o"}, "sort": {"-metadata.name"}, "pagesize": {"2"}, "page": {"1"}},
+		},
+		{
+			name:       "filtered by kind and namespace",
+			query:      "filter=kind=Job,kind=CronJob&filter=metadata.namespace=db&pagesize=1",
+			wantIDs:    []string{"db/backup"},
+			wantTypes:  []string{"batch.cronjob"},
+			wantCount:  2,
+			wantPages:  2,
+			wantListed: []string{"batch.cronjob", "batch.job"},
+			wantParams: url.Values{"filter": {"metadata.namespace=db"}, "sort": defaultSort, "pagesize": {"1"}, "page": {"1"}},
+		},
+		{
+			name:       "filtered by image",
+			query:      "filter=images=envoy",
+			wantIDs:    []string{"web/nginx"},
+			wantTypes:  []string{"apps.deployment"},
+			wantCount:  1,
+			wantParams: url.Values{},
+		},
+		{
+			name:       "sorted by ready descending and paged",
+			query:      "sort=-ready&pagesize=2",
+			wantIDs:    []string{"kube-system/proxy", "web/nginx"},
+			wantTypes:  []string{"apps.daemonset", "apps.deployment"},
+			wantCount:  5,
+			wantPages:  3,
+			wantParams: url.Values{},
+		},
+		{
+			name:       "restricted to namespaces",
+			query:      "projectsornamespaces=web",
+			wantIDs:    []string{"web/nginx"},
+			wantTypes:  []string{"apps.deployment"},
+			wantCount:  1,
+			wantParams: url.Values{projectsOrNamespacesParam: {"web"}, "sort": defaultSort},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stores := testMemberStores()
+			apiOp := newAggregateRequest(stores, test.query)
+
+			list, err := apiOp.Schema.Store.List(apiOp, apiOp.Schema)
+			require.NoError(t, err)
+
+			var gotIDs, gotTypes []string
+			for _, obj := range list.Objects {
+				gotIDs = append(gotIDs, obj.ID)
+				gotTypes = append(gotTypes, obj.Type)
+			}
+			assert.Equal(t, test.wantIDs, gotIDs)
+			assert.Equal(t, test.wantTypes, gotTypes)
+			assert.Equal(t, test.wantCount, list.Count)
+			assert.Equal(t, test.wantPages, list.Pages)
+
+			var listed []string
+			for id, store := range stores {
+				if len(store.listOps) == 0 {
+					continue
+				}
+				listed = append(listed, id)
+				require.Len(t, store.listOps, 1)
+				assert.Equal(t, test.wantParams, store.listOps[0].Query)
+				assert.Equal(t, test.wantParams, store.listOps[0].Request.URL.Query())
+			}
+			if test.wantListed != nil {
+				assert.ElementsMatch(t, test.wantListed, listed)
+			} else {
+				assert.Len(t, listed, len(stores))
+			}
+		})
+	}
+}
+
+func TestAggregateListFields(t *testing.T) {
+	apiOp := newAggregateRequest(testMemberStores(), "")
+
+	list, err := apiOp.Schema.Store.List(apiOp, apiOp.Schema)
+	require.NoError(t, err)
+
+	type fields struct {
+		kind    string
+		images  []interface{}
+		ready   int
+		desired int
+	}
+	got := map[string]fields{}
+	for _, obj := range list.Objects {
+		d := obj.Data()
+		images, _ := d["images"].([]interface{})
+		got[obj.ID] = fields{kind: d.String("kind"), images: images, ready: d["ready"].(int), desired: d["desired"].(int)}
+		assert.NotNil(t, d.Map("metadata", "state"))
+	}
+	assert.Equal(t, map[string]fields{
+		"web/nginx":         {kind: "Deployment", images: []interface{}{"nginx:1.25", "envoy:1.30"}, ready: 2, desired: 3},
+		"db/postgres":       {kind: "StatefulSet", images: []interface{}{"postgres:16"}, ready: 1, desired: 1},
+		"kube-system/proxy": {kind: "DaemonSet", images: []interface{}{"kube-proxy:1.30"}, ready: 4, desired: 5},
+		"db/migrate":        {kind: "Job", images: []interface{}{"migrate:2"}, ready: 1, desired: 2},
+		"db/backup":         {kind: "CronJob", images: []interface{}{"backup:1"}, ready: 1, desired: 0},
+	}, got)
+}
+
+func TestAggregateListWithoutAccess(t *testing.T) {
+	stores := testMemberStores()
+	apiOp := newAggregateRequest(stores, "")
+	// the user can only get deployments by name
+	apiOp.Schemas.LookupSchema("apps.deployment").CollectionMethods = nil
+
+	list, err := apiOp.Schema.Store.List(apiOp, apiOp.Schema)
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, list.Count)
+	assert.Empty(t, stores["apps.deployment"].listOps)
+	for _, obj := range list.Objects {
+		assert.NotEqual(t, "apps.deployment", obj.Type)
+	}
+}
+
+func TestAggregateWatch(t *testing.T) {
+	stores := testMemberStores()
+	for _, store := range stores {
+		store.events = make(chan types.APIEvent, 1)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	apiOp := newAggregateRequest(stores, "")
+	apiOp.Request = apiOp.Request.WithContext(ctx)
+
+	c, err := apiOp.Schema.Store.Watch(apiOp, apiOp.Schema, types.WatchRequest{})
+	require.NoError(t, err)
+
+	stores["apps.deployment"].events <- types.APIEvent{Name: types.ChangeAPIEvent, Object: stores["apps.deployment"].objects[0]}
+	stores["batch.job"].events <- types.APIEvent{Name: types.ChangeAPIEvent, Object: stores["batch.job"].objects[0]}
+	got := map[string]string{}
+	for range 2 {
+		select {
+		case event := <-c:
+			got[event.Object.Type] = event.Object.Data().String("kind")
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for events")
+		}
+	}
+	assert.Equal(t, map[string]string{"apps.deployment": "Deployment", "batch.job": "Job"}, got)
+
+	for _, store := range stores {
+		close(store.events)
+	}
+	_, ok := <-c
+	assert.False(t, ok)
+}
@@ -0,0 +1,112 @@
+// Package workloads registers lifecycle actions, such as scale, restart or rollback, on the built-in workload types.
+package workloads
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	scaleAction    = "scale"
+	restartAction  = "restart"
+	pauseAction    = "pause"
+	resumeAction   = "resume"
+	rollbackAction = "rollback"
+	triggerAction  = "trigger"
+
+	jobSchemaID = "batch.job"
+)
+
+var (
+	deploymentGVK  = schema2.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	statefulSetGVK = schema2.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}
+	daemonSetGVK   = schema2.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"}
+	replicaSetGVK  = schema2.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}
+	cronJobGVK     = schema2.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"}
+	jobGVK         = schema2.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}
+
+	actionsByGVK = map[schema2.GroupVersionKind][]string{
+		deploymentGVK:  {scaleAction, restartAction, pauseAction, resumeAction, rollbackAction},
+		statefulSetGVK: {scaleAction, restartAction, rollbackAction},
+		daemonSetGVK:   {restartAction, rollbackAction},
+		replicaSetGVK:  {scaleAction},
+		cronJobGVK:     {pauseAction, resumeAction, triggerAction},
+	}
+
+	actionInputs = map[string]string{
+		scaleAction:    "scaleInput",
+		rollbackAction: "rollbackInput",
+	}
+)
+
+// ScaleInput is the input of the scale action
+type ScaleInput struct {
+	Replicas *int32 `json:"replicas" norman:"required"`
+}
+
+// RollbackInput is the input of the rollback action
+type RollbackInput struct {
+	// Revision is the name of the ReplicaSet, for Deployments, or of the ControllerRevision, for StatefulSets and
+	// DaemonSets, to roll back to
+	Revision string `json:"revision" norman:"required"`
+}
+
+// Register adds the schemas of the inputs of the workload actions, and the workload schema
+func Register(apiSchemas *types.APISchemas) {
+	apiSchemas.MustImportAndCustomize(&ScaleInput{}, nil)
+	apiSchemas.MustImportAndCustomize(&RollbackInput{}, nil)
+	registerAggregate(apiSchemas)
+}
+
+// Templates returns the templates adding the actions to the workload schemas. Actions are run with the client of the
+// user making the request.
+func Templates(cg proxy.ClientGetter) []schema.Template {
+	var result []schema.Template
+	for gvk := range actionsByGVK {
+		result = append(result, schema.Template{
+			Group: gvk.Group,
+			Kind:  gvk.Kind,
+			Customize: func(apiSchema *types.APISchema) {
+				AddActions(cg, apiSchema)
+			},
+		})
+	}
+	return result
+}
+
+// AddActions adds the actions supported by the type of the given schema, if any
+func AddActions(cg proxy.ClientGetter, apiSchema *types.APISchema) {
+	gvk := attributes.GVK(apiSchema)
+	names, ok := actionsByGVK[gvk]
+	if !ok {
+		return
+	}
+
+	if apiSchema.ActionHandlers == nil {
+		apiSchema.ActionHandlers = map[string]http.Handler{}
+	}
+	if apiSchema.ResourceActions == nil {
+		apiSchema.ResourceActions = map[string]schemas.Action{}
+	}
+	for _, name := range names {
+		output := apiSchema.ID
+		if name == triggerAction {
+			output = jobSchemaID
+		}
+		apiSchema.ActionHandlers[name] = &Action{
+			cg:   cg,
+			name: name,
+			gvk:  gvk,
+		}
+		apiSchema.ResourceActions[name] = schemas.Action{
+			Input:  actionInputs[name],
+			Output: output,
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package workloads
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+type testClientGetter struct {
+	proxy.ClientGetter
+	client kubernetes.Interface
+}
+
+func (t *testClientGetter) K8sInterface(_ *types.APIRequest) (kubernetes.Interface, error) {
+	return t.client, nil
+}
+
+type testResponseWriter struct {
+	code   int
+	object types.APIObject
+}
+
+func (t *testResponseWriter) Write(_ *types.APIRequest, code int, obj types.APIObject) {
+	t.code = code
+	t.object = obj
+}
+
+func (t *testResponseWriter) WriteList(_ *types.APIRequest, _ int, _ types.APIObjectList) {}
+
+func newSchema(id string, gvk schema2.GroupVersionKind) *types.APISchema {
+	s := &types.APISchema{
+		Schema: &schemas.Schema{ID: id},
+	}
+	attributes.SetGVK(s, gvk)
+	return s
+}
+
+// runAction runs the given action on the default/test object, returning the written object or the error
+func runAction(t *testing.T, client kubernetes.Interface, apiSchema *types.APISchema, action, body string) (*testResponseWriter, error) {
+	t.Helper()
+	AddActions(&testClientGetter{client: client}, apiSchema)
+	handler, ok := apiSchema.ActionHandlers[action]
+	require.True(t, ok, "action %s is not registered on %s", action, apiSchema.ID)
+
+	var handlerErr error
+	writer := &testResponseWriter{}
+	req := httptest.NewRequest(http.MethodPost, "/v1/"+apiSchema.ID+"/default/test?action="+action, strings.NewReader(body))
+	apiOp := types.StoreAPIContext(&types.APIRequest{
+		Action:         action,
+		Type:           apiSchema.ID,
+		Schema:         apiSchema,
+		Namespace:      "default",
+		Name:           "test",
+		Method:         http.MethodPost,
+		Request:        req,
+		Response:       httptest.NewRecorder(),
+		ResponseWriter: writer,
+		ErrorHandler: func(_ *types.APIRequest, err error) {
+			handlerErr = err
+		},
+	})
+	handler.ServeHTTP(apiOp.Response, apiOp.Request)
+	return writer, handlerErr
+}
+
+func templateLabels() map[string]string {
+	return map[string]string{"app": "test"}
+}
+
+func TestAddActions(t *testing.T) {
+	tests := []struct {
+		name        string
+		gvk         schema2.GroupVersionKind
+		wantActions map[string]schemas.Action
+	}{
+		{
+			name: "deployment",
+			gvk:  deploymentGVK,
+			wantActions: map[string]schemas.Action{
+				"scale":    {Input: "scaleInput", Output: "test"},
+				"restart":  {Output: "test"},
+				"pause":    {Output: "test"},
+				"resume":   {Output: "test"},
+				"rollback": {Input: "rollbackInput", Output: "test"},
+			},
+		},
+		{
+			name: "daemonset",
+			gvk:  daemonSetGVK,
+			wantActions: map[string]schemas.Action{
+				"restart":  {Output: "test"},
+				"rollback": {Input: "rollbackInput", Output: "test"},
+			},
+		},
+		{
+			name: "cronjob",
+			gvk:  cronJobGVK,
+			wantActions: map[string]schemas.Action{
+				"pause":   {Output: "test"},
+				"resume":  {Output: "test"},
+				"trigger": {Output: "batch.job"},
+			},
+		},
+		{
+			name: "other types are left untouched",
+			gvk:  schema2.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			apiSchema := newSchema("test", test.gvk)
+			AddActions(&testClientGetter{}, apiSchema)
+			assert.Equal(t, test.wantActions, apiSchema.ResourceActions)
+			assert.Len(t, apiSchema.ActionHandlers, len(test.wantActions))
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	apiSchemas := types.EmptyAPISchemas()
+	Register(apiSchemas)
+	for _, input := range actionInputs {
+		assert.NotNil(t, apiSchemas.LookupSchema(input), input)
+	}
+}
+
+func TestScaleAndPause(t *testing.T) {
+	client := fake.NewClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](1)},
+	})
+	apiSchema := newSchema("apps.deployment", deploymentGVK)
+
+	writer, err := runAction(t, client, apiSchema, "scale", `{"replicas": 3}`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, writer.code)
+	assert.Equal(t, "default/test", writer.object.ID)
+	assert.Equal(t, "apps.deployment", writer.object.Type)
+	unstr := writer.object.Object.(*unstructured.Unstructured)
+	assert.Equal(t, "Deployment", unstr.GetKind())
+	replicas, _, _ := unstructured.NestedInt64(unstr.Object, "spec", "replicas")
+	assert.Equal(t, int64(3), replicas)
+
+	_, err = runAction(t, client, apiSchema, "scale", `{"replicas": -1}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, err.(*apierror.APIError).Code.Status)
+	_, err = runAction(t, client, apiSchema, "scale", `{}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, err.(*apierror.APIError).Code.Status)
+
+	_, err = runAction(t, client, apiSchema, "pause", "")
+	require.NoError(t, err)
+	deployment, err := client.AppsV1().Deployments("default").Get(t.Context(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, deployment.Spec.Paused)
+
+	_, err = runAction(t, client, apiSchema, "resume", "")
+	require.NoError(t, err)
+	deployment, err = client.AppsV1().Deployments("default").Get(t.Context(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.False(t, deployment.Spec.Paused)
+}
+
+func TestRestart(t *testing.T) {
+	client := fake.NewClientset(&appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+	})
+	_, err := runAction(t, client, newSchema("apps.daemonset", daemonSetGVK), "restart", "")
+	require.NoError(t, err)
+
+	daemonSet, err := client.AppsV1().DaemonSets("default").Get(t.Context(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, daemonSet.Spec.Template.Annotations[restartedAtAnnotation])
+}
+
+func TestRollbackDeployment(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "deployment-uid"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: templateLabels()},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:v2"}}},
+			},
+		},
+	}
+	old := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test-1",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(deployment, deploymentGVK)},
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "test", appsv1.DefaultDeploymentUniqueLabelKey: "1"}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:v1"}}},
+			},
+		},
+	}
+	other := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+	}
+	client := fake.NewClientset(deployment, old, other)
+	apiSchema := newSchema("apps.deployment", deploymentGVK)
+
+	_, err := runAction(t, client, apiSchema, "rollback", `{"revision": "other"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, err.(*apierror.APIError).Code.Status)
+	_, err = runAction(t, client, apiSchema, "rollback", `{"revision": "missing"}`)
+	assert.Equal(t, http.StatusNotFound, err.(*apierror.APIError).Code.Status)
+
+	_, err = runAction(t, client, apiSchema, "rollback", `{"revision": "test-1"}`)
+	require.NoError(t, err)
+	result, err := client.AppsV1().Deployments("default").Get(t.Context(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "app:v1", result.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, templateLabels(), result.Spec.Template.Labels)
+}
+
+func TestRollbackStatefulSet(t *testing.T) {
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "statefulset-uid"},
+		Spec: appsv1.StatefulSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:v2"}}},
+			},
+		},
+	}
+	revision := &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test-1",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(statefulSet, statefulSetGVK)},
+		},
+		Data: runtime.RawExtension{
+			Raw: []byte(`{"spec":{"template":{"spec":{"containers":[{"name":"app","image":"app:v1"}]}}}}`),
+		},
+	}
+	client := fake.NewClientset(statefulSet, revision)
+
+	_, err := runAction(t, client, newSchema("apps.statefulset", statefulSetGVK), "rollback", `{"revision": "test-1"}`)
+	require.NoError(t, err)
+	result, err := client.AppsV1().StatefulSets("default").Get(t.Context(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "app:v1", result.Spec.Template.Spec.Containers[0].Image)
+}
+
+func TestTrigger(t *testing.T) {
+	client := fake.NewClientset(&batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "cronjob-uid"},
+		Spec: batchv1.CronJobSpec{
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: templateLabels()},
+				Spec:       batchv1.JobSpec{BackoffLimit: ptr.To[int32](2)},
+			},
+		},
+	})
+
+	writer, err := runAction(t, client, newSchema("batch.cronjob", cronJobGVK), "trigger", "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, writer.code)
+	assert.Equal(t, "batch.job", writer.object.Type)
+
+	jobs, err := client.BatchV1().Jobs("default").List(t.Context(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, jobs.Items, 1)
+	job := jobs.Items[0]
+	assert.Equal(t, "default/"+job.Name, writer.object.ID)
+	assert.True(t, strings.HasPrefix(job.Name, "test-manual-"))
+	assert.Equal(t, "manual", job.Annotations[instantiateAnnotation])
+	assert.Equal(t, templateLabels(), job.Labels)
+	assert.Equal(t, ptr.To[int32](2), job.Spec.BackoffLimit)
+	require.Len(t, job.OwnerReferences, 1)
+	assert.Equal(t, "cronjob-uid", string(job.OwnerReferences[0].UID))
+}
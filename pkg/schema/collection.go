@@ -33,6 +33,13 @@ type Collection struct {
 	ctx     context.Context
 	running map[string]func()
 	as      accesscontrol.AccessSetLookup
+
+	// revision is incremented every time the schemas are reset, so that
+	// per-user schemas built from different generations can be told apart
+	revision int64
+
+	// readOnly strips the mutating methods, actions and links from the per-user schemas
+	readOnly bool
 }
 
 type Template struct {
@@ -68,8 +75,14 @@ func WrapServer(factory Factory, server *apiserver.Server) http.Handler {
 	})
 }
 
+// userRecord is the ID of the last AccessSet of a user, and the groups it was computed for
+type userRecord struct {
+	accessID string
+	groups   []string
+}
+
 func NewCollection(ctx context.Context, baseSchema *types.APISchemas, access accesscontrol.AccessSetLookup) *Collection {
-	return &Collection{
+	c := &Collection{
 		baseSchema: baseSchema,
 		schemas:    map[string]*types.APISchema{},
 		templates:  map[string][]*Template{},
@@ -82,6 +95,21 @@ func NewCollection(ctx context.Context, baseSchema *types.APISchemas, access acc
 		as:         access,
 		running:    map[string]func(){},
 	}
+	if notifier, ok := access.(accesscontrol.AccessChangeNotifier); ok {
+		notifier.OnAccessChange(ctx, c.accessChanged)
+	}
+	return c
+}
+
+// SetReadOnly makes the per-user schemas read-only, whatever the access of the users: they only allow GET, and have no
+// actions nor links executing commands in pods. It's meant to be called before serving requests.
+func (c *Collection) SetReadOnly(readOnly bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.readOnly = readOnly
+	for _, k := range c.cache.Keys() {
+		c.cache.Remove(k)
+	}
 }
 
 func (c *Collection) OnChange(ctx context.Context, cb func()) {
@@ -119,6 +147,7 @@ func (c *Collection) Reset(schemas map[string]*types.APISchema) {
 	c.lock.Lock()
 	c.startStopTemplate(schemas)
 	c.schemas = schemas
+	c.revision++
 	c.byGVR = byGVR
 	c.byGVK = byGVK
 	for _, k := range c.cache.Keys() {
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/rancher/apiserver/pkg/builtin"
@@ -15,6 +16,8 @@ import (
 	"k8s.io/apiserver/pkg/authentication/user"
 )
 
+const revisionAttribute = "schemasRevision"
+
 type Factory interface {
 	Schemas(user user.Info) (*types.APISchemas, error)
 	ByGVR(gvr schema.GroupVersionResource) string
@@ -52,11 +55,11 @@ func (c *Collection) Schemas(user user.Info) (*types.APISchemas, error) {
 func (c *Collection) removeOldRecords(access *accesscontrol.AccessSet, user user.Info) {
 	current, ok := c.userCache.Get(user.GetName())
 	if ok {
-		currentID, cOk := current.(string)
-		if cOk && currentID != access.ID {
+		record, cOk := current.(userRecord)
+		if cOk && record.accessID != access.ID {
 			// we only want to keep around one record per user. If our current access record is invalid, purge the
 			//record of it from the cache, so we don't keep duplicates
-			c.purgeUserRecords(currentID)
+			c.purgeUserRecords(record.accessID)
 			c.userCache.Remove(user.GetName())
 		}
 	}
@@ -64,7 +67,22 @@ func (c *Collection) removeOldRecords(access *accesscontrol.AccessSet, user user
 
 func (c *Collection) addToCache(access *accesscontrol.AccessSet, user user.Info, schemas *types.APISchemas) {
 	c.cache.Add(access.ID, schemas, 24*time.Hour)
-	c.userCache.Add(user.GetName(), access.ID, 24*time.Hour)
+	c.userCache.Add(user.GetName(), userRecord{accessID: access.ID, groups: user.GetGroups()}, 24*time.Hour)
+}
+
+// accessChanged purges the records of the users whose access may have changed, without waiting for their next request
+func (c *Collection) accessChanged(change accesscontrol.AccessChange) {
+	for _, name := range c.userCache.Keys() {
+		current, ok := c.userCache.Get(name)
+		if !ok {
+			continue
+		}
+		record, _ := current.(userRecord)
+		if change.Affects(&user.DefaultInfo{Name: name.(string), Groups: record.groups}) {
+			c.purgeUserRecords(record.accessID)
+			c.userCache.Remove(name)
+		}
+	}
 }
 
 // PurgeUserRecords removes a record from the backing LRU cache before expiry
@@ -171,10 +189,27 @@ func (c *Collection) schemasForSubject(access *accesscontrol.AccessSet) (*types.
 		}
 	}
 
+	if c.readOnly {
+		for _, s := range result.Schemas {
+			makeReadOnly(s)
+		}
+	}
+
 	accesscontrol.SetAccessSetAttribute(result, access)
+	result.Attributes[revisionAttribute] = strconv.FormatInt(c.revision, 10)
 	return result, nil
 }
 
+// RevisionFromSchemas returns the revision of the Collection the given per-user schemas were built from, or an empty
+// string if they weren't built by a Collection. The revision changes every time the Collection is reset.
+func RevisionFromSchemas(schemas *types.APISchemas) string {
+	if schemas == nil {
+		return ""
+	}
+	revision, _ := schemas.Attributes[revisionAttribute].(string)
+	return revision
+}
+
 func (c *Collection) defaultStore() types.Store {
 	templates := c.templates[""]
 	if len(templates) > 0 {
@@ -2,11 +2,15 @@ package schema
 
 import (
 	"context"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
 	"github.com/rancher/wrangler/v3/pkg/schemas"
 	k8sSchema "k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apiserver/pkg/authentication/user"
@@ -176,3 +180,92 @@ func makeSchema(resourceType string) *types.APISchema {
 		},
 	}
 }
+
+// notifyingAccessSetLookup is a mockAccessSetLookup notifying of changes to the access of users
+type notifyingAccessSetLookup struct {
+	*mockAccessSetLookup
+	listeners []func(accesscontrol.AccessChange)
+}
+
+func (n *notifyingAccessSetLookup) OnAccessChange(_ context.Context, cb func(accesscontrol.AccessChange)) {
+	n.listeners = append(n.listeners, cb)
+}
+
+func TestSchemaCacheAccessChange(t *testing.T) {
+	lookup := &notifyingAccessSetLookup{mockAccessSetLookup: newMockAccessSetLookup()}
+	collection := NewCollection(context.TODO(), types.EmptyAPISchemas(), lookup)
+	require.Len(t, lookup.listeners, 1)
+
+	alice := &user.DefaultInfo{Name: "alice", Groups: []string{"devs"}}
+	bob := &user.DefaultInfo{Name: "bob"}
+	config := schemaTestConfig{
+		permissionVerbs:        []string{"get"},
+		desiredResourceVerbs:   []string{"GET"},
+		desiredCollectionVerbs: []string{"GET"},
+	}
+	runSchemaTest(t, config, lookup.mockAccessSetLookup, collection, alice)
+	lookup.AddAccessForUser(bob, "list", k8sSchema.GroupResource{Group: testGroup, Resource: "testCRD"}, "*", "*")
+	_, err := collection.Schemas(bob)
+	require.NoError(t, err)
+	assert.Len(t, collection.cache.Keys(), 2)
+
+	// a change to the group of alice purges her records, but not the ones of bob
+	lookup.listeners[0](accesscontrol.AccessChange{Groups: []string{"devs"}})
+	assert.Len(t, collection.cache.Keys(), 1)
+	_, ok := collection.userCache.Get("alice")
+	assert.False(t, ok)
+	_, ok = collection.userCache.Get("bob")
+	assert.True(t, ok)
+}
+
+func TestSchemasReadOnly(t *testing.T) {
+	lookup := newMockAccessSetLookup()
+	baseSchemas := types.EmptyAPISchemas()
+	baseSchemas.MustAddSchema(types.APISchema{
+		Schema: &schemas.Schema{
+			ID:                "cluster",
+			ResourceMethods:   []string{http.MethodGet, http.MethodPut},
+			CollectionMethods: []string{http.MethodGet, http.MethodPost},
+			ResourceActions:   map[string]schemas.Action{"apply": {}},
+		},
+		ActionHandlers: map[string]http.Handler{"apply": http.NotFoundHandler()},
+	})
+	collection := NewCollection(context.TODO(), baseSchemas, lookup)
+	collection.SetReadOnly(true)
+
+	pod := makeSchema("pods")
+	pod.LinkHandlers = map[string]http.Handler{
+		"exec": http.NotFoundHandler(),
+		"log":  http.NotFoundHandler(),
+	}
+	// blocked methods are removed as well
+	attributes.AddDisallowMethods(pod, http.MethodDelete)
+	collection.schemas = map[string]*types.APISchema{"pods": pod}
+
+	admin := &user.DefaultInfo{Name: "admin"}
+	for _, verb := range []string{"get", "list", "create", "update", "patch", "delete"} {
+		lookup.AddAccessForUser(admin, verb, k8sSchema.GroupResource{Group: testGroup, Resource: "pods"}, "*", "*")
+	}
+	userSchemas, err := collection.Schemas(admin)
+	require.NoError(t, err)
+
+	podSchema := userSchemas.LookupSchema("pods")
+	require.NotNil(t, podSchema)
+	assert.Equal(t, []string{http.MethodGet}, podSchema.ResourceMethods)
+	assert.Equal(t, []string{http.MethodGet}, podSchema.CollectionMethods)
+	assert.Contains(t, podSchema.LinkHandlers, "log")
+	assert.NotContains(t, podSchema.LinkHandlers, "exec")
+
+	clusterSchema := userSchemas.LookupSchema("cluster")
+	require.NotNil(t, clusterSchema)
+	assert.Equal(t, []string{http.MethodGet}, clusterSchema.ResourceMethods)
+	assert.Equal(t, []string{http.MethodGet}, clusterSchema.CollectionMethods)
+	assert.Empty(t, clusterSchema.ResourceActions)
+	assert.Empty(t, clusterSchema.ActionHandlers)
+
+	// the shared schemas are left untouched
+	assert.Contains(t, pod.LinkHandlers, "exec")
+	base := baseSchemas.LookupSchema("cluster")
+	assert.Equal(t, []string{http.MethodGet, http.MethodPut}, base.ResourceMethods)
+	assert.Contains(t, base.ActionHandlers, "apply")
+}
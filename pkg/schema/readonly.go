@@ -0,0 +1,46 @@
+package schema
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+var (
+	mutatingMethods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	// mutatingLinks run commands in, or connect to, the pods of the cluster
+	mutatingLinks = []string{"exec", "attach", "portforward"}
+)
+
+// makeReadOnly removes the mutating methods, the actions and the mutating links of a per-user schema. The schema
+// shares its slices and maps with the base schemas, so they're replaced rather than modified.
+func makeReadOnly(s *types.APISchema) {
+	s.ResourceMethods = withoutMutatingMethods(s.ResourceMethods)
+	s.CollectionMethods = withoutMutatingMethods(s.CollectionMethods)
+	s.ResourceActions = nil
+	s.CollectionActions = nil
+	s.ActionHandlers = nil
+
+	if len(s.LinkHandlers) == 0 {
+		return
+	}
+	linkHandlers := make(map[string]http.Handler, len(s.LinkHandlers))
+	for name, handler := range s.LinkHandlers {
+		if !slices.Contains(mutatingLinks, name) {
+			linkHandlers[name] = handler
+		}
+	}
+	s.LinkHandlers = linkHandlers
+}
+
+func withoutMutatingMethods(methods []string) []string {
+	var result []string
+	for _, method := range methods {
+		if !slices.Contains(mutatingMethods, strings.TrimPrefix(method, "blocked-")) {
+			result = append(result, method)
+		}
+	}
+	return result
+}
@@ -2,10 +2,15 @@ package cli
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
 	"time"
 
+	dlserver "github.com/rancher/dynamiclistener/server"
+	"github.com/rancher/steve/pkg/audit"
 	steveauth "github.com/rancher/steve/pkg/auth"
 	authcli "github.com/rancher/steve/pkg/auth/cli"
+	"github.com/rancher/steve/pkg/redaction"
 	"github.com/rancher/steve/pkg/server"
 	"github.com/rancher/steve/pkg/sqlcache/informer/factory"
 	"github.com/rancher/steve/pkg/ui"
@@ -20,8 +25,23 @@ type Config struct {
 	HTTPSListenPort int
 	HTTPListenPort  int
 	UIPath          string
+	// RedactionRules is the path of a YAML or JSON file listing the redaction rules
+	RedactionRules string
+
+	// AuditPolicy is the path of a YAML or JSON file with the audit policy
+	AuditPolicy        string
+	AuditLogPath       string
+	AuditLogMaxSize    int
+	AuditLogMaxBackups int
+	AuditLogMaxAge     int
+	AuditWebhookURL    string
+
+	RateLimit RateLimitConfig
+	ReadOnly  bool
 
 	WebhookConfig authcli.WebhookConfig
+	OIDCConfig    authcli.OIDCConfig
+	StaticConfig  authcli.StaticConfig
 }
 
 func (c *Config) MustServer(ctx context.Context) *server.Server {
@@ -33,27 +53,46 @@ func (c *Config) MustServer(ctx context.Context) *server.Server {
 }
 
 func (c *Config) ToServer(ctx context.Context, sqlCache bool) (*server.Server, error) {
-	var (
-		auth steveauth.Middleware
-	)
-
 	restConfig, err := kubeconfig.GetNonInteractiveClientConfigWithContext(c.KubeConfig, c.Context).ClientConfig()
 	if err != nil {
 		return nil, err
 	}
 	restConfig.RateLimiter = ratelimit.None
 
-	if c.WebhookConfig.WebhookAuthentication {
-		auth, err = c.WebhookConfig.WebhookMiddleware()
+	var redactionRules []redaction.Rule
+	if c.RedactionRules != "" {
+		redactionRules, err = redaction.LoadRules(c.RedactionRules)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	auditSink, err := c.auditSink(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var auditPolicy audit.Policy
+	if auditSink != nil && c.AuditPolicy != "" {
+		auditPolicy, err = audit.LoadPolicy(c.AuditPolicy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	auth, err := c.authMiddleware(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	return server.New(ctx, restConfig, &server.Options{
 		AuthMiddleware: auth,
 		Next:           ui.New(c.UIPath),
 		SQLCache:       sqlCache,
+		Redaction:      redactionRules,
+		AuditSink:      auditSink,
+		AuditPolicy:    auditPolicy,
+		RateLimit:      c.RateLimit.Options(),
+		ReadOnly:       c.ReadOnly,
 		SQLCacheFactoryOptions: factory.CacheFactoryOptions{
 			GCInterval:  15 * time.Minute,
 			GCKeepCount: 1000,
@@ -61,6 +100,65 @@ func (c *Config) ToServer(ctx context.Context, sqlCache bool) (*server.Server, e
 	})
 }
 
+// authMiddleware returns the middleware authenticating requests with all the configured authenticators, or nil if none
+// is configured. The authenticators verifying requests locally, client certificates, the token file and OIDC, are
+// tried first, so that the tokens they accept are never sent to the TokenReview webhook.
+func (c *Config) authMiddleware(ctx context.Context) (steveauth.Middleware, error) {
+	authenticators, err := c.StaticConfig.Authenticators(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oidcAuth, err := c.OIDCConfig.OIDCAuthenticator()
+	if err != nil {
+		return nil, err
+	}
+	if oidcAuth != nil {
+		authenticators = append(authenticators, oidcAuth)
+	}
+	webhookAuth, err := c.WebhookConfig.WebhookAuthenticator()
+	if err != nil {
+		return nil, err
+	}
+	if webhookAuth != nil {
+		authenticators = append(authenticators, webhookAuth)
+	}
+
+	switch len(authenticators) {
+	case 0:
+		return nil, nil
+	case 1:
+		return steveauth.ToMiddleware(authenticators[0]), nil
+	}
+	return steveauth.ToMiddleware(steveauth.NewUnionAuthenticator(authenticators...)), nil
+}
+
+// ListenOpts returns the options of the HTTPS listener, requesting client certificates if they're verified
+func (c *Config) ListenOpts() *dlserver.ListenOpts {
+	opts := &dlserver.ListenOpts{DisplayServerLogs: true}
+	if c.StaticConfig.ClientCAFile != "" {
+		opts.TLSListenerConfig.TLSConfig = &tls.Config{ClientAuth: tls.RequestClientCert}
+	}
+	return opts
+}
+
+// auditSink returns the sink of the audit log, nil if it's disabled
+func (c *Config) auditSink(ctx context.Context) (audit.Sink, error) {
+	switch {
+	case c.AuditLogPath != "" && c.AuditWebhookURL != "":
+		return nil, fmt.Errorf("only one of audit-log-path and audit-webhook-url can be set")
+	case c.AuditLogPath != "":
+		return audit.NewFileSink(audit.FileSinkOptions{
+			Path:       c.AuditLogPath,
+			MaxSize:    c.AuditLogMaxSize,
+			MaxBackups: c.AuditLogMaxBackups,
+			MaxAge:     c.AuditLogMaxAge,
+		}), nil
+	case c.AuditWebhookURL != "":
+		return audit.NewWebhookSink(ctx, audit.WebhookSinkOptions{URL: c.AuditWebhookURL})
+	}
+	return nil, nil
+}
+
 func Flags(config *Config) []cli.Flag {
 	flags := []cli.Flag{
 		&cli.StringFlag{
@@ -77,6 +175,45 @@ func Flags(config *Config) []cli.Flag {
 			Name:        "ui-path",
 			Destination: &config.UIPath,
 		},
+		&cli.StringFlag{
+			Name:        "redaction-rules",
+			EnvVars:     []string{"REDACTION_RULES"},
+			Destination: &config.RedactionRules,
+		},
+		&cli.StringFlag{
+			Name:        "audit-policy",
+			EnvVars:     []string{"AUDIT_POLICY"},
+			Destination: &config.AuditPolicy,
+		},
+		&cli.StringFlag{
+			Name:        "audit-log-path",
+			EnvVars:     []string{"AUDIT_LOG_PATH"},
+			Destination: &config.AuditLogPath,
+		},
+		&cli.IntFlag{
+			Name:        "audit-log-maxsize",
+			Value:       100,
+			Destination: &config.AuditLogMaxSize,
+		},
+		&cli.IntFlag{
+			Name:        "audit-log-maxbackup",
+			Value:       10,
+			Destination: &config.AuditLogMaxBackups,
+		},
+		&cli.IntFlag{
+			Name:        "audit-log-maxage",
+			Destination: &config.AuditLogMaxAge,
+		},
+		&cli.StringFlag{
+			Name:        "audit-webhook-url",
+			EnvVars:     []string{"AUDIT_WEBHOOK_URL"},
+			Destination: &config.AuditWebhookURL,
+		},
+		&cli.BoolFlag{
+			Name:        "read-only",
+			EnvVars:     []string{"READ_ONLY"},
+			Destination: &config.ReadOnly,
+		},
 		&cli.IntFlag{
 			Name:        "https-listen-port",
 			Value:       9443,
@@ -89,5 +226,8 @@ func Flags(config *Config) []cli.Flag {
 		},
 	}
 
-	return append(flags, authcli.Flags(&config.WebhookConfig)...)
+	flags = append(flags, RateLimitFlags(&config.RateLimit)...)
+	flags = append(flags, authcli.Flags(&config.WebhookConfig)...)
+	flags = append(flags, authcli.OIDCFlags(&config.OIDCConfig)...)
+	return append(flags, authcli.StaticFlags(&config.StaticConfig)...)
 }
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	authcli "github.com/rancher/steve/pkg/auth/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func TestAuthMiddlewareOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var reviews atomic.Int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		reviews.Add(1)
+		review := authenticationv1.TokenReview{}
+		if err := json.NewDecoder(req.Body).Decode(&review); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		review.Status = authenticationv1.TokenReviewStatus{
+			Authenticated: true,
+			User:          authenticationv1.UserInfo{Username: "bob"},
+		}
+		_ = json.NewEncoder(rw).Encode(review)
+	}))
+	defer webhook.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "tokens.csv")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("static-token,alice,1\n"), 0600))
+
+	c := &Config{
+		WebhookConfig: authcli.WebhookConfig{WebhookAuthentication: true, WebhookURL: webhook.URL},
+		StaticConfig:  authcli.StaticConfig{TokenAuthFile: tokenFile},
+	}
+	middleware, err := c.authMiddleware(ctx)
+	require.NoError(t, err)
+
+	var username string
+	handler := middleware(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		info, _ := request.UserFrom(req.Context())
+		username = info.GetName()
+	}))
+	serve := func(token string) {
+		username = ""
+		req := httptest.NewRequest(http.MethodGet, "/v1/pods", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// tokens from the token file are never sent to the webhook
+	serve("static-token")
+	assert.Equal(t, "alice", username)
+	assert.Equal(t, int32(0), reviews.Load())
+
+	serve("other-token")
+	assert.Equal(t, "bob", username)
+	assert.Equal(t, int32(1), reviews.Load())
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"time"
+
+	"github.com/rancher/steve/pkg/throttle"
+	"github.com/urfave/cli/v2"
+)
+
+type RateLimitConfig struct {
+	ListQPS     float64
+	ListBurst   int
+	GetQPS      float64
+	GetBurst    int
+	MutateQPS   float64
+	MutateBurst int
+	WatchQPS    float64
+	WatchBurst  int
+
+	ExemptGroups       cli.StringSlice
+	MaxConcurrentLists int
+	ListQueueLength    int
+	ListQueueTimeout   time.Duration
+}
+
+// Options returns the options of the rate limiter, nil if no limit is set
+func (r *RateLimitConfig) Options() *throttle.Options {
+	opts := &throttle.Options{
+		Budgets: throttle.Budgets{
+			List:   throttle.Budget{QPS: r.ListQPS, Burst: r.ListBurst},
+			Get:    throttle.Budget{QPS: r.GetQPS, Burst: r.GetBurst},
+			Mutate: throttle.Budget{QPS: r.MutateQPS, Burst: r.MutateBurst},
+			Watch:  throttle.Budget{QPS: r.WatchQPS, Burst: r.WatchBurst},
+		},
+		ExemptGroups:       r.ExemptGroups.Value(),
+		MaxConcurrentLists: r.MaxConcurrentLists,
+		ListQueueLength:    r.ListQueueLength,
+		ListQueueTimeout:   r.ListQueueTimeout,
+	}
+	if opts.Budgets == (throttle.Budgets{}) && opts.MaxConcurrentLists == 0 {
+		return nil
+	}
+	return opts
+}
+
+func RateLimitFlags(config *RateLimitConfig) []cli.Flag {
+	budgetFlags := func(category string, qps *float64, burst *int) []cli.Flag {
+		return []cli.Flag{
+			&cli.Float64Flag{
+				Name:        "rate-limit-" + category + "-qps",
+				Destination: qps,
+			},
+			&cli.IntFlag{
+				Name:        "rate-limit-" + category + "-burst",
+				Destination: burst,
+			},
+		}
+	}
+
+	var flags []cli.Flag
+	flags = append(flags, budgetFlags("list", &config.ListQPS, &config.ListBurst)...)
+	flags = append(flags, budgetFlags("get", &config.GetQPS, &config.GetBurst)...)
+	flags = append(flags, budgetFlags("mutate", &config.MutateQPS, &config.MutateBurst)...)
+	flags = append(flags, budgetFlags("watch", &config.WatchQPS, &config.WatchBurst)...)
+	return append(flags,
+		&cli.StringSliceFlag{
+			Name:        "rate-limit-exempt-groups",
+			Value:       cli.NewStringSlice("system:masters"),
+			Destination: &config.ExemptGroups,
+		},
+		&cli.IntFlag{
+			Name:        "max-concurrent-lists",
+			Destination: &config.MaxConcurrentLists,
+		},
+		&cli.IntFlag{
+			Name:        "list-queue-length",
+			Value:       10,
+			Destination: &config.ListQueueLength,
+		},
+		&cli.DurationFlag{
+			Name:        "list-queue-timeout",
+			Value:       30 * time.Second,
+			Destination: &config.ListQueueTimeout,
+		},
+	)
+}
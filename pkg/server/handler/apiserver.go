@@ -1,33 +1,59 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	apiserver "github.com/rancher/apiserver/pkg/server"
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/apiserver/pkg/urlbuilder"
 	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/audit"
 	"github.com/rancher/steve/pkg/auth"
 	k8sproxy "github.com/rancher/steve/pkg/proxy"
 	"github.com/rancher/steve/pkg/schema"
 	"github.com/rancher/steve/pkg/server/router"
+	"github.com/rancher/steve/pkg/throttle"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/client-go/rest"
 )
 
+// Options configures the optional features of the handler returned by NewWithOptions
+type Options struct {
+	// Health serves the /healthz and /readyz endpoints, which aren't served if nil
+	Health http.Handler
+	// AuditLogger records the requests served through /v1, none are recorded if nil
+	AuditLogger *audit.Logger
+	// Limiter limits the rate of the requests served through /v1, which aren't limited if nil
+	Limiter *throttle.Limiter
+	// ReadOnly refuses the requests proxied to Kubernetes and to the extension API server that may modify resources
+	ReadOnly bool
+}
+
 func New(cfg *rest.Config, sf schema.Factory, authMiddleware auth.Middleware, next http.Handler,
 	routerFunc router.RouterFunc, extensionAPIServer http.Handler) (*apiserver.Server, http.Handler, error) {
+	return NewWithOptions(cfg, sf, authMiddleware, next, routerFunc, extensionAPIServer, Options{})
+}
+
+// NewWithOptions is New with the given optional features
+func NewWithOptions(cfg *rest.Config, sf schema.Factory, authMiddleware auth.Middleware, next http.Handler,
+	routerFunc router.RouterFunc, extensionAPIServer http.Handler, opts Options) (*apiserver.Server, http.Handler, error) {
 	var (
 		proxy http.Handler
 		err   error
 	)
 
 	a := &apiServer{
-		sf:     sf,
-		server: apiserver.DefaultAPIServer(),
+		sf:      sf,
+		server:  apiserver.DefaultAPIServer(),
+		audit:   opts.AuditLogger,
+		limiter: opts.Limiter,
 	}
 	a.server.AccessControl = accesscontrol.NewAccessControl()
+	for format, writer := range a.server.ResponseWriters {
+		a.server.ResponseWriters[format] = &etagWriter{ResponseWriter: writer}
+	}
 
 	if authMiddleware == nil {
 		proxy, err = k8sproxy.Handler("/", cfg)
@@ -38,6 +64,12 @@ func New(cfg *rest.Config, sf schema.Factory, authMiddleware auth.Middleware, ne
 	} else {
 		proxy = k8sproxy.ImpersonatingHandler("/", cfg)
 	}
+	if opts.ReadOnly {
+		proxy = k8sproxy.ReadOnlyHandler(proxy)
+		if extensionAPIServer != nil {
+			extensionAPIServer = k8sproxy.ReadOnlyHandler(extensionAPIServer)
+		}
+	}
 
 	w := authMiddleware
 	handlers := router.Handlers{
@@ -45,6 +77,7 @@ func New(cfg *rest.Config, sf schema.Factory, authMiddleware auth.Middleware, ne
 		K8sResource: w(a.apiHandler(k8sAPI)),
 		K8sProxy:    w(proxy),
 		APIRoot:     w(a.apiHandler(apiRoot)),
+		Health:      opts.Health,
 	}
 	if extensionAPIServer != nil {
 		handlers.ExtensionAPIServer = w(extensionAPIServer)
@@ -56,8 +89,10 @@ func New(cfg *rest.Config, sf schema.Factory, authMiddleware auth.Middleware, ne
 }
 
 type apiServer struct {
-	sf     schema.Factory
-	server *apiserver.Server
+	sf      schema.Factory
+	server  *apiserver.Server
+	audit   *audit.Logger
+	limiter *throttle.Limiter
 }
 
 func (a *apiServer) common(rw http.ResponseWriter, req *http.Request) (*types.APIRequest, bool) {
@@ -73,6 +108,20 @@ func (a *apiServer) common(rw http.ResponseWriter, req *http.Request) (*types.AP
 		rw.WriteHeader(http.StatusInternalServerError)
 	}
 
+	req, impersonated, err := impersonate(req, user, accesscontrol.AccessSetFromAPIRequest(&types.APIRequest{Schemas: schemas}))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusForbidden)
+		return nil, false
+	}
+	if impersonated != nil {
+		setImpersonatedHeaders(rw, impersonated)
+		if schemas, err = a.sf.Schemas(impersonated); err != nil {
+			logrus.Errorf("HTTP request failed: %v", err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return nil, false
+		}
+	}
+
 	urlBuilder, err := urlbuilder.NewPrefixed(req, schemas, "v1")
 	if err != nil {
 		rw.Write([]byte(err.Error()))
@@ -92,12 +141,44 @@ type APIFunc func(schema.Factory, *types.APIRequest)
 
 func (a *apiServer) apiHandler(apiFunc APIFunc) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw, req, done := a.audit.Start(rw, req)
 		apiOp, ok := a.common(rw, req)
 		if ok {
 			if apiFunc != nil {
 				apiFunc(a.sf, apiOp)
 			}
-			a.server.Handle(apiOp)
+			a.serve(rw, apiOp)
 		}
+		done(apiOp)
 	})
 }
+
+// serve handles the request if it's admitted by the rate limits, freeing its slot even if the handler panics
+func (a *apiServer) serve(rw http.ResponseWriter, apiOp *types.APIRequest) {
+	release, ok := a.admit(rw, apiOp)
+	if !ok {
+		return
+	}
+	defer release()
+	a.server.Handle(apiOp)
+}
+
+// admit checks the request against the rate limits of the authenticated caller, the impersonating user if any, writing
+// a 429 response if it's over them. The release function must be called once the request is served.
+func (a *apiServer) admit(rw http.ResponseWriter, apiOp *types.APIRequest) (func(), bool) {
+	caller, ok := auth.ImpersonatorFrom(apiOp.Context())
+	if !ok {
+		caller, _ = request.UserFrom(apiOp.Context())
+	}
+	release, err := a.limiter.Admit(apiOp.Context(), caller, throttle.RequestCategory(apiOp))
+	if err != nil {
+		var limitErr *throttle.LimitError
+		if errors.As(err, &limitErr) {
+			limitErr.WriteResponse(rw)
+		} else {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return release, true
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/parse"
+	apiserver "github.com/rancher/apiserver/pkg/server"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/auth"
+	"github.com/rancher/steve/pkg/throttle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func TestAdmit(t *testing.T) {
+	limiter, err := throttle.New(throttle.Options{
+		Budgets: throttle.Budgets{List: throttle.Budget{QPS: 0.001, Burst: 1}},
+	})
+	require.NoError(t, err)
+	a := &apiServer{limiter: limiter}
+
+	do := func(info, impersonator user.Info) (*httptest.ResponseRecorder, bool) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/pods", nil)
+		ctx := request.WithUser(req.Context(), info)
+		if impersonator != nil {
+			ctx = auth.WithImpersonator(ctx, impersonator)
+		}
+		rw := httptest.NewRecorder()
+		release, ok := a.admit(rw, &types.APIRequest{Request: req.WithContext(ctx), Type: "pod"})
+		if ok {
+			release()
+		}
+		return rw, ok
+	}
+
+	alice := &user.DefaultInfo{Name: "alice"}
+	_, ok := do(alice, nil)
+	assert.True(t, ok)
+
+	rw, ok := do(alice, nil)
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusTooManyRequests, rw.Code)
+	assert.NotEmpty(t, rw.Header().Get("Retry-After"))
+
+	// requests made as another user count against the budget of the impersonating user
+	_, ok = do(&user.DefaultInfo{Name: "bob"}, alice)
+	assert.False(t, ok)
+	_, ok = do(alice, &user.DefaultInfo{Name: "admin"})
+	assert.True(t, ok)
+}
+
+func TestServeReleasesOnPanic(t *testing.T) {
+	limiter, err := throttle.New(throttle.Options{MaxConcurrentLists: 1, ListQueueTimeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+	a := &apiServer{
+		limiter: limiter,
+		server: &apiserver.Server{
+			Parser: func(*types.APIRequest, parse.URLParser) error {
+				panic("handler failed")
+			},
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/pods", nil)
+	req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: "alice"}))
+	apiOp := &types.APIRequest{Request: req, Type: "pod"}
+	assert.Panics(t, func() {
+		a.serve(httptest.NewRecorder(), apiOp)
+	})
+
+	// the slot of the list that panicked is free again
+	release, ok := a.admit(httptest.NewRecorder(), apiOp)
+	require.True(t, ok)
+	release()
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/resources/counts"
+	"github.com/rancher/steve/pkg/schema"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// etagWriter adds an ETag header to successful GET responses and answers requests with a matching If-None-Match
+// header with 304 Not Modified, without sending the body.
+//
+// The ETag combines the revision of the returned data (the resourceVersion of an object, the revision of a list or of
+// the counts, or the revision of the schema Collection for schemas), the ID of the requester's access set and the
+// request URI. Single objects are rendered before, and a hash of their body is part of their ETag, because formatters
+// add data that changes without a new revision, such as the state and relationships from the summary cache or the
+// resource usage of pods and nodes. Lists are never buffered, so their ETag is computed before rendering them.
+type etagWriter struct {
+	types.ResponseWriter
+}
+
+func (e *etagWriter) Write(apiOp *types.APIRequest, code int, obj types.APIObject) {
+	if !cacheable(apiOp, code) {
+		e.ResponseWriter.Write(apiOp, code, obj)
+		return
+	}
+	e.buffered(apiOp, objectRevision(apiOp, obj), func() {
+		e.ResponseWriter.Write(apiOp, code, obj)
+	})
+}
+
+func (e *etagWriter) WriteList(apiOp *types.APIRequest, code int, list types.APIObjectList) {
+	if !cacheable(apiOp, code) {
+		e.ResponseWriter.WriteList(apiOp, code, list)
+		return
+	}
+	revision := list.Revision
+	if apiOp.Type == "schema" {
+		revision = schema.RevisionFromSchemas(apiOp.Schemas)
+	} else if revision == "" && len(list.Objects) == 1 {
+		revision = countsRevision(list.Objects[0])
+	}
+	if etag := computeETag(apiOp, revision, nil); etag != "" && notModified(apiOp, apiOp.Response, etag) {
+		return
+	}
+	e.ResponseWriter.WriteList(apiOp, code, list)
+}
+
+func cacheable(apiOp *types.APIRequest, code int) bool {
+	return code == http.StatusOK && apiOp.Method == http.MethodGet && apiOp.Request != nil && apiOp.Response != nil
+}
+
+// buffered renders the response into a buffer, then sets the ETag header and either writes a 304 status code if the
+// client already has that version, or the rendered response.
+func (e *etagWriter) buffered(apiOp *types.APIRequest, revision string, render func()) {
+	response := apiOp.Response
+	buffer := &bufferedResponse{ResponseWriter: response}
+	apiOp.Response = buffer
+	render()
+	apiOp.Response = response
+
+	code := buffer.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+	if code == http.StatusOK {
+		if etag := computeETag(apiOp, revision, buffer.body.Bytes()); etag != "" && notModified(apiOp, response, etag) {
+			return
+		}
+	}
+	response.WriteHeader(code)
+	_, _ = response.Write(buffer.body.Bytes())
+}
+
+// notModified sets the ETag header, and writes a 304 status code if the client already has that version
+func notModified(apiOp *types.APIRequest, response http.ResponseWriter, etag string) bool {
+	response.Header().Set("ETag", etag)
+	if etagMatches(apiOp.Request.Header.Get("If-None-Match"), etag) {
+		response.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// bufferedResponse holds back the status code and body of a response, while sharing the headers of the wrapped
+// http.ResponseWriter
+type bufferedResponse struct {
+	http.ResponseWriter
+	code int
+	body bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	if b.code == 0 {
+		b.code = code
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
+
+func objectRevision(apiOp *types.APIRequest, obj types.APIObject) string {
+	if apiOp.Type == "schema" {
+		return schema.RevisionFromSchemas(apiOp.Schemas)
+	}
+	if revision := countsRevision(obj); revision != "" {
+		return revision
+	}
+	if obj.Object == nil {
+		return ""
+	}
+	m, err := meta.Accessor(obj.Object)
+	if err != nil {
+		return ""
+	}
+	return m.GetResourceVersion()
+}
+
+// countsRevision combines the revisions of all the types in a counts object, or returns an empty string if obj isn't
+// one
+func countsRevision(obj types.APIObject) string {
+	c, ok := obj.Object.(counts.Count)
+	if !ok {
+		return ""
+	}
+	ids := make([]string, 0, len(c.Counts))
+	for id := range c.Counts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	buf := &strings.Builder{}
+	for _, id := range ids {
+		buf.WriteString(id)
+		buf.WriteString("=")
+		buf.WriteString(strconv.Itoa(c.Counts[id].Revision))
+		buf.WriteString(",")
+	}
+	return buf.String()
+}
+
+// computeETag returns a strong ETag for the given request, data revision and rendered body if not nil, or an empty
+// string if the response can't be identified reliably.
+func computeETag(apiOp *types.APIRequest, revision string, body []byte) string {
+	if revision == "" {
+		return ""
+	}
+	schemasRevision := schema.RevisionFromSchemas(apiOp.Schemas)
+	if schemasRevision == "" {
+		return ""
+	}
+	accessSet := accesscontrol.AccessSetFromAPIRequest(apiOp)
+	if accessSet == nil {
+		return ""
+	}
+
+	gzip := strings.Contains(apiOp.Request.Header.Get("Accept-Encoding"), "gzip")
+	actionLinks := apiOp.Request.Header.Get("X-API-Action-Links") != ""
+	parts := []string{
+		revision,
+		schemasRevision,
+		accessSet.ID,
+		apiOp.Request.URL.RequestURI(),
+		apiOp.ResponseFormat,
+		boolString(gzip),
+		boolString(actionLinks),
+	}
+	if body != nil {
+		bodyHash := sha256.Sum256(body)
+		parts = append(parts, string(bodyHash[:]))
+	}
+
+	h := sha256.New()
+	for _, s := range parts {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches implements the weak comparison used for If-None-Match, as per RFC 9110 section 13.1.2
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func boolString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/resources/counts"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+type fakeAccessSetLookup struct {
+	id string
+}
+
+func (f *fakeAccessSetLookup) AccessFor(_ user.Info) *accesscontrol.AccessSet {
+	return &accesscontrol.AccessSet{ID: f.id}
+}
+
+func (f *fakeAccessSetLookup) PurgeUserData(_ string) {}
+
+type recordingWriter struct {
+	objects int
+	lists   int
+}
+
+func (r *recordingWriter) Write(apiOp *types.APIRequest, code int, obj types.APIObject) {
+	r.objects++
+	apiOp.Response.WriteHeader(code)
+	if u, ok := obj.Object.(*unstructured.Unstructured); ok {
+		_, _ = apiOp.Response.Write([]byte(u.GetLabels()["state"]))
+	}
+}
+
+func (r *recordingWriter) WriteList(apiOp *types.APIRequest, code int, _ types.APIObjectList) {
+	r.lists++
+	apiOp.Response.WriteHeader(code)
+}
+
+func TestETagWriter(t *testing.T) {
+	obj := func(rv string) types.APIObject {
+		u := &unstructured.Unstructured{}
+		u.SetName("foo")
+		u.SetResourceVersion(rv)
+		return types.APIObject{Type: "pods", ID: "foo", Object: u}
+	}
+
+	newCollection := func(accessID string) *schema.Collection {
+		collection := schema.NewCollection(context.Background(), types.EmptyAPISchemas(), &fakeAccessSetLookup{id: accessID})
+		collection.Reset(map[string]*types.APISchema{})
+		return collection
+	}
+
+	type request struct {
+		collection  *schema.Collection
+		method      string
+		url         string
+		ifNoneMatch string
+	}
+	do := func(t *testing.T, req request, write func(w types.ResponseWriter, apiOp *types.APIRequest)) (*httptest.ResponseRecorder, *recordingWriter) {
+		t.Helper()
+		schemas, err := req.collection.Schemas(&user.DefaultInfo{Name: "user"})
+		require.NoError(t, err)
+
+		method := req.method
+		if method == "" {
+			method = http.MethodGet
+		}
+		httpReq := httptest.NewRequest(method, req.url, nil)
+		if req.ifNoneMatch != "" {
+			httpReq.Header.Set("If-None-Match", req.ifNoneMatch)
+		}
+		rw := httptest.NewRecorder()
+		recorder := &recordingWriter{}
+		apiOp := &types.APIRequest{
+			Method:   method,
+			Type:     "pods",
+			Schemas:  schemas,
+			Request:  httpReq,
+			Response: rw,
+		}
+		write(&etagWriter{ResponseWriter: recorder}, apiOp)
+		return rw, recorder
+	}
+	writeObj := func(rv string) func(w types.ResponseWriter, apiOp *types.APIRequest) {
+		return func(w types.ResponseWriter, apiOp *types.APIRequest) {
+			w.Write(apiOp, http.StatusOK, obj(rv))
+		}
+	}
+	writeList := func(revision string) func(w types.ResponseWriter, apiOp *types.APIRequest) {
+		return func(w types.ResponseWriter, apiOp *types.APIRequest) {
+			w.WriteList(apiOp, http.StatusOK, types.APIObjectList{Revision: revision})
+		}
+	}
+
+	t.Run("object not modified", func(t *testing.T) {
+		collection := newCollection("access1")
+		rw, _ := do(t, request{collection: collection, url: "/v1/pods/default/foo"}, writeObj("10"))
+		etag := rw.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		rw, _ = do(t, request{collection: collection, url: "/v1/pods/default/foo", ifNoneMatch: etag}, writeObj("10"))
+		assert.Equal(t, http.StatusNotModified, rw.Code)
+		assert.Equal(t, etag, rw.Header().Get("ETag"))
+		assert.Empty(t, rw.Body.String())
+
+		rw, _ = do(t, request{collection: collection, url: "/v1/pods/default/foo", ifNoneMatch: "W/" + etag}, writeObj("10"))
+		assert.Equal(t, http.StatusNotModified, rw.Code)
+		assert.Empty(t, rw.Body.String())
+	})
+	t.Run("object modified", func(t *testing.T) {
+		collection := newCollection("access1")
+		rw, _ := do(t, request{collection: collection, url: "/v1/pods/default/foo"}, writeObj("10"))
+		etag := rw.Header().Get("ETag")
+
+		rw, recorder := do(t, request{collection: collection, url: "/v1/pods/default/foo", ifNoneMatch: etag}, writeObj("11"))
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.NotEqual(t, etag, rw.Header().Get("ETag"))
+		assert.Equal(t, 1, recorder.objects)
+	})
+	t.Run("derived data changed", func(t *testing.T) {
+		collection := newCollection("access1")
+		write := func(state string) func(w types.ResponseWriter, apiOp *types.APIRequest) {
+			return func(w types.ResponseWriter, apiOp *types.APIRequest) {
+				o := obj("10")
+				o.Object.(*unstructured.Unstructured).SetLabels(map[string]string{"state": state})
+				w.Write(apiOp, http.StatusOK, o)
+			}
+		}
+		rw, _ := do(t, request{collection: collection, url: "/v1/pods/default/foo"}, write("active"))
+		etag := rw.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+		assert.Equal(t, "active", rw.Body.String())
+
+		rw, recorder := do(t, request{collection: collection, url: "/v1/pods/default/foo", ifNoneMatch: etag}, write("updating"))
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.NotEqual(t, etag, rw.Header().Get("ETag"))
+		assert.Equal(t, "updating", rw.Body.String())
+		assert.Equal(t, 1, recorder.objects)
+	})
+	t.Run("counts", func(t *testing.T) {
+		collection := newCollection("access1")
+		write := func(revision int) func(w types.ResponseWriter, apiOp *types.APIRequest) {
+			return func(w types.ResponseWriter, apiOp *types.APIRequest) {
+				w.Write(apiOp, http.StatusOK, types.APIObject{Type: "count", ID: "count", Object: counts.Count{
+					ID:     "count",
+					Counts: map[string]counts.ItemCount{"pods": {Revision: revision}},
+				}})
+			}
+		}
+		rw, _ := do(t, request{collection: collection, url: "/v1/counts/count"}, write(10))
+		etag := rw.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		rw, _ = do(t, request{collection: collection, url: "/v1/counts/count", ifNoneMatch: etag}, write(10))
+		assert.Equal(t, http.StatusNotModified, rw.Code)
+		assert.Empty(t, rw.Body.String())
+
+		rw, recorder := do(t, request{collection: collection, url: "/v1/counts/count", ifNoneMatch: etag}, write(11))
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.Equal(t, 1, recorder.objects)
+	})
+	t.Run("list depends on query and revision", func(t *testing.T) {
+		collection := newCollection("access1")
+		rw, _ := do(t, request{collection: collection, url: "/v1/pods?filter=a"}, writeList("100"))
+		etag := rw.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		// lists aren't rendered when not modified
+		rw, recorder := do(t, request{collection: collection, url: "/v1/pods?filter=a", ifNoneMatch: etag}, writeList("100"))
+		assert.Equal(t, http.StatusNotModified, rw.Code)
+		assert.Empty(t, rw.Body.String())
+		assert.Equal(t, 0, recorder.lists)
+
+		rw, recorder = do(t, request{collection: collection, url: "/v1/pods?filter=b", ifNoneMatch: etag}, writeList("100"))
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.Equal(t, 1, recorder.lists)
+
+		rw, recorder = do(t, request{collection: collection, url: "/v1/pods?filter=a", ifNoneMatch: etag}, writeList("101"))
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.Equal(t, 1, recorder.lists)
+	})
+	t.Run("different access set", func(t *testing.T) {
+		rw, _ := do(t, request{collection: newCollection("access1"), url: "/v1/pods"}, writeList("100"))
+		etag := rw.Header().Get("ETag")
+
+		rw, recorder := do(t, request{collection: newCollection("access2"), url: "/v1/pods", ifNoneMatch: etag}, writeList("100"))
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.Equal(t, 1, recorder.lists)
+	})
+	t.Run("schemas reset", func(t *testing.T) {
+		collection := newCollection("access1")
+		rw, _ := do(t, request{collection: collection, url: "/v1/pods"}, writeList("100"))
+		etag := rw.Header().Get("ETag")
+
+		collection.Reset(map[string]*types.APISchema{})
+		rw, recorder := do(t, request{collection: collection, url: "/v1/pods", ifNoneMatch: etag}, writeList("100"))
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.Equal(t, 1, recorder.lists)
+	})
+	t.Run("no revision", func(t *testing.T) {
+		rw, recorder := do(t, request{collection: newCollection("access1"), url: "/v1/pods"}, writeList(""))
+		assert.Empty(t, rw.Header().Get("ETag"))
+		assert.Equal(t, 1, recorder.lists)
+	})
+	t.Run("not a GET", func(t *testing.T) {
+		rw, recorder := do(t, request{collection: newCollection("access1"), method: http.MethodPut, url: "/v1/pods/default/foo", ifNoneMatch: "*"}, writeObj("10"))
+		assert.Empty(t, rw.Header().Get("ETag"))
+		assert.Equal(t, 1, recorder.objects)
+	})
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/auth"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	impersonateUserParam  = "impersonate-user"
+	impersonateGroupParam = "impersonate-group"
+
+	// ImpersonatedUserHeader is set on the responses to requests made as another user, with the name of that user
+	ImpersonatedUserHeader = "X-API-Impersonated-User"
+	// ImpersonatedGroupHeader is set on the responses to requests made as another user, once for each of their groups
+	ImpersonatedGroupHeader = "X-API-Impersonated-Group"
+)
+
+// impersonate returns the request made as the user and groups given by the impersonate-user and impersonate-group
+// query parameters, if any, after checking that the caller, with the given AccessSet, is allowed the impersonate verb
+// on them. As with kubernetes impersonation, the user is added to the system:authenticated group.
+func impersonate(req *http.Request, caller user.Info, accessSet *accesscontrol.AccessSet) (*http.Request, user.Info, error) {
+	query := req.URL.Query()
+	name := query.Get(impersonateUserParam)
+	groups := query[impersonateGroupParam]
+	if name == "" {
+		if len(groups) > 0 {
+			return nil, nil, fmt.Errorf("%s is required to impersonate groups", impersonateUserParam)
+		}
+		return req, nil, nil
+	}
+
+	canImpersonate := func(resource, namespace, name string) bool {
+		return accessSet != nil && accessSet.Grants("impersonate", schema.GroupResource{Resource: resource}, namespace, name)
+	}
+	if namespace, saName, err := serviceaccount.SplitUsername(name); err == nil {
+		if !canImpersonate("serviceaccounts", namespace, saName) {
+			return nil, nil, fmt.Errorf("%s cannot impersonate serviceaccount %s/%s", caller.GetName(), namespace, saName)
+		}
+	} else if !canImpersonate("users", "", name) {
+		return nil, nil, fmt.Errorf("%s cannot impersonate user %s", caller.GetName(), name)
+	}
+	for _, group := range groups {
+		if !canImpersonate("groups", "", group) {
+			return nil, nil, fmt.Errorf("%s cannot impersonate group %s", caller.GetName(), group)
+		}
+	}
+
+	groups = slices.Clone(groups)
+	if name != user.Anonymous && !slices.Contains(groups, user.AllAuthenticated) {
+		groups = append(groups, user.AllAuthenticated)
+	}
+	impersonated := &user.DefaultInfo{
+		Name:   name,
+		Groups: groups,
+	}
+
+	ctx := request.WithUser(req.Context(), impersonated)
+	ctx = auth.WithImpersonator(ctx, caller)
+	return req.WithContext(ctx), impersonated, nil
+}
+
+// setImpersonatedHeaders records the user a request is made as in its response
+func setImpersonatedHeaders(rw http.ResponseWriter, impersonated user.Info) {
+	rw.Header().Set(ImpersonatedUserHeader, impersonated.GetName())
+	for _, group := range impersonated.GetGroups() {
+		rw.Header().Add(ImpersonatedGroupHeader, group)
+	}
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/auth"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+type userAccessSetLookup map[string]*accesscontrol.AccessSet
+
+func (u userAccessSetLookup) AccessFor(user user.Info) *accesscontrol.AccessSet {
+	if accessSet, ok := u[user.GetName()]; ok {
+		return accessSet
+	}
+	return &accesscontrol.AccessSet{ID: "none"}
+}
+
+func (u userAccessSetLookup) PurgeUserData(_ string) {}
+
+func impersonator(namespace string, resource string, names ...string) *accesscontrol.AccessSet {
+	accessSet := &accesscontrol.AccessSet{ID: "impersonator"}
+	for _, name := range names {
+		accessSet.Add("impersonate", k8sschema.GroupResource{Resource: resource},
+			accesscontrol.Access{Namespace: namespace, ResourceName: name})
+	}
+	return accessSet
+}
+
+func TestImpersonate(t *testing.T) {
+	caller := &user.DefaultInfo{Name: "admin"}
+	tests := []struct {
+		name      string
+		query     string
+		accessSet *accesscontrol.AccessSet
+		wantUser  user.Info
+		wantErr   bool
+	}{
+		{
+			name:      "not impersonating",
+			query:     "",
+			accessSet: &accesscontrol.AccessSet{},
+		},
+		{
+			name:      "user",
+			query:     "impersonate-user=alice",
+			accessSet: impersonator(accesscontrol.All, "users", "alice"),
+			wantUser:  &user.DefaultInfo{Name: "alice", Groups: []string{user.AllAuthenticated}},
+		},
+		{
+			name:  "user and groups",
+			query: "impersonate-user=alice&impersonate-group=devs&impersonate-group=system:authenticated",
+			accessSet: func() *accesscontrol.AccessSet {
+				accessSet := impersonator(accesscontrol.All, "users", "alice")
+				accessSet.Merge(impersonator(accesscontrol.All, "groups", "devs", user.AllAuthenticated))
+				return accessSet
+			}(),
+			wantUser: &user.DefaultInfo{Name: "alice", Groups: []string{"devs", user.AllAuthenticated}},
+		},
+		{
+			name:      "service account",
+			query:     "impersonate-user=system:serviceaccount:prod:cleaner",
+			accessSet: impersonator("prod", "serviceaccounts", "cleaner"),
+			wantUser:  &user.DefaultInfo{Name: "system:serviceaccount:prod:cleaner", Groups: []string{user.AllAuthenticated}},
+		},
+		{
+			name:      "user not allowed",
+			query:     "impersonate-user=bob",
+			accessSet: impersonator(accesscontrol.All, "users", "alice"),
+			wantErr:   true,
+		},
+		{
+			name:      "group not allowed",
+			query:     "impersonate-user=alice&impersonate-group=admins",
+			accessSet: impersonator(accesscontrol.All, "users", "alice"),
+			wantErr:   true,
+		},
+		{
+			name:      "service account in another namespace",
+			query:     "impersonate-user=system:serviceaccount:dev:cleaner",
+			accessSet: impersonator("prod", "serviceaccounts", "cleaner"),
+			wantErr:   true,
+		},
+		{
+			name:      "groups without user",
+			query:     "impersonate-group=devs",
+			accessSet: impersonator(accesscontrol.All, "groups", "devs"),
+			wantErr:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/pods?"+test.query, nil)
+			req = req.WithContext(request.WithUser(req.Context(), caller))
+
+			got, impersonated, err := impersonate(req, caller, test.accessSet)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if test.wantUser == nil {
+				assert.Nil(t, impersonated)
+				assert.Same(t, req, got)
+				return
+			}
+
+			assert.Equal(t, test.wantUser, impersonated)
+			ctxUser, _ := request.UserFrom(got.Context())
+			assert.Equal(t, test.wantUser, ctxUser)
+			ctxImpersonator, ok := auth.ImpersonatorFrom(got.Context())
+			require.True(t, ok)
+			assert.Equal(t, caller, ctxImpersonator)
+		})
+	}
+}
+
+func TestCommonImpersonation(t *testing.T) {
+	lookup := userAccessSetLookup{
+		"admin": impersonator(accesscontrol.All, "users", "alice"),
+		"alice": {ID: "alice"},
+	}
+	collection := schema.NewCollection(context.Background(), types.EmptyAPISchemas(), lookup)
+	collection.Reset(map[string]*types.APISchema{})
+	a := &apiServer{sf: collection}
+
+	do := func(url string) (*httptest.ResponseRecorder, *types.APIRequest, bool) {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: "admin"}))
+		rw := httptest.NewRecorder()
+		apiOp, ok := a.common(rw, req)
+		return rw, apiOp, ok
+	}
+
+	rw, apiOp, ok := do("/v1/pods?impersonate-user=alice")
+	require.True(t, ok)
+	assert.Equal(t, "alice", accesscontrol.AccessSetFromAPIRequest(apiOp).ID)
+	ctxUser, _ := request.UserFrom(apiOp.Context())
+	assert.Equal(t, "alice", ctxUser.GetName())
+	assert.Equal(t, "alice", rw.Header().Get(ImpersonatedUserHeader))
+	assert.Equal(t, []string{user.AllAuthenticated}, rw.Header().Values(ImpersonatedGroupHeader))
+
+	rw, apiOp, ok = do("/v1/pods")
+	require.True(t, ok)
+	assert.Equal(t, "impersonator", accesscontrol.AccessSetFromAPIRequest(apiOp).ID)
+	assert.Empty(t, rw.Header().Get(ImpersonatedUserHeader))
+
+	rw, _, ok = do("/v1/pods?impersonate-user=bob")
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusForbidden, rw.Code)
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"k8s.io/apiserver/pkg/server/healthz"
+)
+
+// newHealthHandler serves /healthz, which only checks that the server is responding, and /readyz, which aggregates
+// the given checks. Each check is also served individually under /readyz/<name>.
+func newHealthHandler(readyChecks ...healthz.HealthChecker) http.Handler {
+	mux := http.NewServeMux()
+	healthz.InstallHandler(mux, healthz.PingHealthz)
+	healthz.InstallReadyzHandler(mux, readyChecks...)
+	return mux
+}
+
+func extensionAPIServerCheck(extensionAPIServer ExtensionAPIServer) healthz.HealthChecker {
+	return healthz.NamedCheck("extension-apiserver", func(_ *http.Request) error {
+		select {
+		case <-extensionAPIServer.Registered():
+			return nil
+		default:
+			return errors.New("extension API server not registered")
+		}
+	})
+}
@@ -17,6 +17,9 @@ type Handlers struct {
 	// ExtensionAPIServer serves under /ext. If nil, the default unknown path
 	// handler is served.
 	ExtensionAPIServer http.Handler
+	// Health serves the unauthenticated /healthz and /readyz endpoints. If nil,
+	// the default unknown path handler is served.
+	Health http.Handler
 }
 
 func Routes(h Handlers) http.Handler {
@@ -28,6 +31,11 @@ func Routes(h Handlers) http.Handler {
 	m.Path("/").Handler(h.APIRoot).HeadersRegexp("Accept", ".*json.*")
 	m.Path("/{name:v1}").Handler(h.APIRoot)
 
+	if h.Health != nil {
+		m.PathPrefix("/healthz").Handler(h.Health)
+		m.PathPrefix("/readyz").Handler(h.Health)
+	}
+
 	if h.ExtensionAPIServer != nil {
 		m.Path("/ext").Handler(http.StripPrefix("/ext", h.ExtensionAPIServer))
 		m.PathPrefix("/ext/").Handler(http.StripPrefix("/ext", h.ExtensionAPIServer))
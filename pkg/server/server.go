@@ -11,25 +11,34 @@ import (
 	"github.com/rancher/dynamiclistener/server"
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/aggregation"
+	"github.com/rancher/steve/pkg/audit"
 	"github.com/rancher/steve/pkg/auth"
 	"github.com/rancher/steve/pkg/client"
 	"github.com/rancher/steve/pkg/clustercache"
 	schemacontroller "github.com/rancher/steve/pkg/controllers/schema"
 	"github.com/rancher/steve/pkg/ext"
+	"github.com/rancher/steve/pkg/redaction"
 	"github.com/rancher/steve/pkg/resources"
+	"github.com/rancher/steve/pkg/resources/accessreviews"
 	"github.com/rancher/steve/pkg/resources/common"
+	"github.com/rancher/steve/pkg/resources/images"
 	"github.com/rancher/steve/pkg/resources/schemas"
+	"github.com/rancher/steve/pkg/resources/virtual/usage"
 	"github.com/rancher/steve/pkg/schema"
 	"github.com/rancher/steve/pkg/schema/definitions"
 	"github.com/rancher/steve/pkg/server/handler"
 	"github.com/rancher/steve/pkg/server/router"
 	"github.com/rancher/steve/pkg/sqlcache/informer/factory"
+	"github.com/rancher/steve/pkg/sqlcache/prewarm"
 	"github.com/rancher/steve/pkg/sqlcache/schematracker"
 	metricsStore "github.com/rancher/steve/pkg/stores/metrics"
 	"github.com/rancher/steve/pkg/stores/proxy"
 	"github.com/rancher/steve/pkg/stores/sqlpartition"
 	"github.com/rancher/steve/pkg/stores/sqlproxy"
 	"github.com/rancher/steve/pkg/summarycache"
+	"github.com/rancher/steve/pkg/throttle"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/server/healthz"
 	"k8s.io/client-go/rest"
 )
 
@@ -62,7 +71,13 @@ type Server struct {
 	ClusterRegistry string
 	Version         string
 
-	cacheFactory *factory.CacheFactory
+	cacheFactory    *factory.CacheFactory
+	sqlCachePrewarm prewarm.Options
+	sqlCacheUsage   bool
+	redaction       *redaction.Policy
+	audit           *audit.Logger
+	limiter         *throttle.Limiter
+	readOnly        bool
 
 	extensionAPIServer            ExtensionAPIServer
 	SkipWaitForExtensionAPIServer bool
@@ -75,6 +90,7 @@ type Server struct {
 
 	aggregationSecretNamespace string
 	aggregationSecretName      string
+	aggregationStatus          *aggregation.Status
 	SQLCache                   bool
 }
 
@@ -95,6 +111,32 @@ type Options struct {
 
 	SQLCacheFactoryOptions factory.CacheFactoryOptions
 
+	// SQLCachePrewarm lists the types whose SQL cache is warmed up in the background as soon as their schemas are
+	// discovered, instead of on the first request for them. Only used if SQLCache is enabled.
+	SQLCachePrewarm prewarm.Options
+
+	// SQLCacheUsage caches the metrics of pods and nodes served by metrics.k8s.io, making their usage available as the
+	// metadata.usage.cpu and metadata.usage.memory fields to filter and sort them. Only used if SQLCache is enabled.
+	SQLCacheUsage bool
+
+	// Redaction lists the fields redacted from the objects returned to users, unless they are allowed the reveal verb
+	// on them
+	Redaction []redaction.Rule
+
+	// AuditSink enables the audit log of the requests served through /v1, written to this sink. AuditPolicy chooses
+	// the level of details recorded for each request, Metadata by default.
+	AuditSink   audit.Sink
+	AuditPolicy audit.Policy
+
+	// RateLimit limits the requests of each user to /v1, and the number of lists served at once. Requests aren't
+	// limited if nil.
+	RateLimit *throttle.Options
+
+	// ReadOnly prevents any change to the cluster through the server, whatever the RBAC of the users allows: the
+	// schemas only allow GET and have no actions, pods can't be exec'd into or attached to, and the Kubernetes API and
+	// extension API server proxies only serve reads.
+	ReadOnly bool
+
 	// ExtensionAPIServer enables an extension API server that will be served
 	// under /ext
 	// If nil, Steve's default http handler for unknown routes will be served.
@@ -112,10 +154,39 @@ func New(ctx context.Context, restConfig *rest.Config, opts *Options) (*Server,
 		opts = &Options{}
 	}
 
+	redactionPolicy, err := redaction.NewPolicy(opts.Redaction)
+	if err != nil {
+		return nil, err
+	}
+
+	var auditLogger *audit.Logger
+	if opts.AuditSink != nil {
+		auditLogger, err = audit.NewLogger(opts.AuditPolicy, opts.AuditSink, redactionPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("creating audit logger: %w", err)
+		}
+	}
+
+	var limiter *throttle.Limiter
+	if opts.RateLimit != nil {
+		limiter, err = throttle.New(*opts.RateLimit)
+		if err != nil {
+			return nil, fmt.Errorf("creating rate limiter: %w", err)
+		}
+	}
+
 	var cacheFactory *factory.CacheFactory
 	if opts.SQLCache {
-		var err error
-		cacheFactory, err = factory.NewCacheFactory(opts.SQLCacheFactoryOptions)
+		factoryOpts := opts.SQLCacheFactoryOptions
+		// the redacted fields are never written to disk in clear
+		if encrypt := factoryOpts.Encrypt; encrypt != nil {
+			factoryOpts.Encrypt = func(gvk k8sschema.GroupVersionKind) bool {
+				return encrypt(gvk) || redactionPolicy.Matches(gvk)
+			}
+		} else {
+			factoryOpts.Encrypt = redactionPolicy.Matches
+		}
+		cacheFactory, err = factory.NewCacheFactory(factoryOpts)
 		if err != nil {
 			return nil, fmt.Errorf("creating SQL cache factory: %w", err)
 		}
@@ -131,11 +202,18 @@ func New(ctx context.Context, restConfig *rest.Config, opts *Options) (*Server,
 		router:                     opts.Router,
 		aggregationSecretNamespace: opts.AggregationSecretNamespace,
 		aggregationSecretName:      opts.AggregationSecretName,
+		aggregationStatus:          &aggregation.Status{},
 		ClusterRegistry:            opts.ClusterRegistry,
 		Version:                    opts.ServerVersion,
 		// SQLCache enables the SQLite-based lasso caching mechanism
 		SQLCache:                      opts.SQLCache,
 		cacheFactory:                  cacheFactory,
+		sqlCachePrewarm:               opts.SQLCachePrewarm,
+		sqlCacheUsage:                 opts.SQLCacheUsage,
+		redaction:                     redactionPolicy,
+		audit:                         auditLogger,
+		limiter:                       limiter,
+		readOnly:                      opts.ReadOnly,
 		extensionAPIServer:            opts.ExtensionAPIServer,
 		SkipWaitForExtensionAPIServer: opts.SkipWaitForExtensionAPIServer,
 	}
@@ -195,10 +273,14 @@ func setup(ctx context.Context, server *Server) error {
 	ccache := clustercache.NewClusterCache(ctx, cf.AdminDynamicClient())
 	server.ClusterCache = ccache
 	sf := schema.NewCollection(ctx, server.BaseSchemas, asl)
+	sf.SetReadOnly(server.readOnly)
 
-	if err = resources.DefaultSchemas(ctx, server.BaseSchemas, ccache, server.ClientFactory, sf, server.Version); err != nil {
+	if err = resources.DefaultSchemas(ctx, server.BaseSchemas, ccache, server.ClientFactory, sf, server.Version, server.redaction); err != nil {
 		return err
 	}
+	if explainer, ok := asl.(accesscontrol.AccessExplainer); ok {
+		accessreviews.Register(server.BaseSchemas, explainer)
+	}
 	definitions.Register(ctx, server.BaseSchemas, server.controllers.K8s.Discovery(),
 		server.controllers.CRD.CustomResourceDefinition(), server.controllers.API.APIService())
 
@@ -209,20 +291,23 @@ func setup(ctx context.Context, server *Server) error {
 		return err
 	}
 
-	var onSchemasHandler schemacontroller.SchemasHandlerFunc
+	var (
+		onSchemasHandler schemacontroller.SchemasHandlerFunc
+		sqlCacheCheck    healthz.HealthChecker
+	)
 	if server.SQLCache {
 		sqlStore, err := sqlproxy.NewProxyStore(ctx, cols, cf, summaryCache, summaryCache, server.cacheFactory, false)
 		if err != nil {
 			return err
 		}
+		sqlStore.SetRedaction(server.redaction)
+		partitionStore := sqlpartition.NewStore(sqlStore, asl)
+		images.SetFieldValuesLister(server.BaseSchemas, partitionStore)
 
 		errStore := proxy.NewErrorStore(
 			proxy.NewUnformatterStore(
 				proxy.NewWatchRefresh(
-					sqlpartition.NewStore(
-						sqlStore,
-						asl,
-					),
+					partitionStore,
 					asl,
 				),
 			),
@@ -230,11 +315,20 @@ func setup(ctx context.Context, server *Server) error {
 		store := metricsStore.NewMetricsStore(errStore)
 		// end store setup code
 
-		for _, template := range resources.DefaultSchemaTemplatesForStore(store, server.BaseSchemas, summaryCache, asl, server.controllers.K8s.Discovery(), common.TemplateOptions{InSQLMode: true}) {
+		for _, template := range resources.DefaultSchemaTemplatesForStore(store, server.BaseSchemas, summaryCache, asl, server.controllers.K8s.Discovery(), common.TemplateOptions{InSQLMode: true, Redaction: server.redaction, ClientGetter: cf}) {
 			sf.AddTemplate(template)
 		}
 
 		sqlSchemaTracker := schematracker.NewSchemaTracker(sqlStore)
+		// the namespace cache is needed by most queries, so it's always warmed up
+		prewarmOpts := server.sqlCachePrewarm
+		prewarmOpts.GVKs = append([]k8sschema.GroupVersionKind{{Version: "v1", Kind: "Namespace"}}, prewarmOpts.GVKs...)
+		prewarmer := prewarm.NewPrewarmer(ctx, sqlStore, prewarmOpts)
+		sqlCacheCheck = prewarm.NewReadyCheck(prewarmer)
+		var usagePoller *usage.Poller
+		if server.sqlCacheUsage {
+			usagePoller = usage.NewPoller(ctx, sqlStore)
+		}
 
 		onSchemasHandler = func(schemas *schema.Collection) error {
 			var retErr error
@@ -245,10 +339,19 @@ func setup(ctx context.Context, server *Server) error {
 			err = sqlSchemaTracker.OnSchemas(schemas)
 			retErr = errors.Join(retErr, err)
 
+			// must come after the schema tracker, which resets the caches of new types
+			err = prewarmer.OnSchemas(schemas)
+			retErr = errors.Join(retErr, err)
+
+			if usagePoller != nil {
+				err = usagePoller.OnSchemas(schemas)
+				retErr = errors.Join(retErr, err)
+			}
+
 			return retErr
 		}
 	} else {
-		for _, template := range resources.DefaultSchemaTemplates(cf, server.BaseSchemas, summaryCache, asl, server.controllers.K8s.Discovery(), server.controllers.Core.Namespace().Cache(), common.TemplateOptions{InSQLMode: false}) {
+		for _, template := range resources.DefaultSchemaTemplates(cf, server.BaseSchemas, summaryCache, asl, server.controllers.K8s.Discovery(), server.controllers.Core.Namespace().Cache(), common.TemplateOptions{InSQLMode: false, Redaction: server.redaction}) {
 			sf.AddTemplate(template)
 		}
 		onSchemasHandler = ccache.OnSchemas
@@ -256,7 +359,7 @@ func setup(ctx context.Context, server *Server) error {
 
 	schemas.SetupWatcher(ctx, server.BaseSchemas, asl, sf)
 
-	schemacontroller.Register(ctx,
+	schemasCheck := schemacontroller.Register(ctx,
 		cols,
 		server.controllers.K8s.Discovery(),
 		server.controllers.CRD.CustomResourceDefinition(),
@@ -279,7 +382,24 @@ func setup(ctx context.Context, server *Server) error {
 		}
 	})
 
-	apiServer, handler, err := handler.New(server.RESTConfig, sf, server.authMiddleware, next, server.router, server.extensionAPIServer)
+	readyChecks := []healthz.HealthChecker{
+		schemasCheck,
+		clustercache.NewReadyCheck(ccache),
+		server.aggregationStatus,
+	}
+	if sqlCacheCheck != nil {
+		readyChecks = append(readyChecks, sqlCacheCheck)
+	}
+	if server.extensionAPIServer != nil && !server.SkipWaitForExtensionAPIServer {
+		readyChecks = append(readyChecks, extensionAPIServerCheck(server.extensionAPIServer))
+	}
+
+	apiServer, handler, err := handler.NewWithOptions(server.RESTConfig, sf, server.authMiddleware, next, server.router, server.extensionAPIServer, handler.Options{
+		Health:      newHealthHandler(readyChecks...),
+		AuditLogger: server.audit,
+		Limiter:     server.limiter,
+		ReadOnly:    server.readOnly,
+	})
 	if err != nil {
 		return err
 	}
@@ -306,8 +426,8 @@ func (c *Server) start(ctx context.Context) error {
 }
 
 func (c *Server) StartAggregation(ctx context.Context) {
-	aggregation.Watch(ctx, c.controllers.Core.Secret(), c.aggregationSecretNamespace,
-		c.aggregationSecretName, c)
+	aggregation.WatchWithStatus(ctx, c.controllers.Core.Secret(), c.aggregationSecretNamespace,
+		c.aggregationSecretName, c, c.aggregationStatus)
 }
 
 func (c *Server) ListenAndServe(ctx context.Context, httpsPort, httpPort int, opts *server.ListenOpts) error {
@@ -35,6 +35,7 @@ type CacheFactory struct {
 	cancel context.CancelFunc
 
 	encryptAll bool
+	encrypt    func(gvk schema.GroupVersionKind) bool
 
 	gcInterval  time.Duration
 	gcKeepCount int
@@ -91,6 +92,9 @@ type CacheFactoryOptions struct {
 	GCInterval time.Duration
 	// GCKeepCount is how many events to keep in _events table when gc runs
 	GCKeepCount int
+	// Encrypt, if set, returns whether the data blobs of a type are encrypted in SQLite, in addition to the types
+	// always encrypted
+	Encrypt func(gvk schema.GroupVersionKind) bool
 }
 
 // NewCacheFactory returns an informer factory instance
@@ -111,6 +115,7 @@ func NewCacheFactory(opts CacheFactoryOptions) (*CacheFactory, error) {
 		cancel: cancel,
 
 		encryptAll: os.Getenv(EncryptAllEnvVar) == "true",
+		encrypt:    opts.Encrypt,
 		dbClient:   dbClient,
 
 		gcInterval:  opts.GCInterval,
@@ -171,7 +176,7 @@ func (f *CacheFactory) cacheForLocked(ctx context.Context, gi *guardedInformer,
 		}()
 
 		_, encryptResourceAlways := defaultEncryptedResourceTypes[gvk]
-		shouldEncrypt := f.encryptAll || encryptResourceAlways
+		shouldEncrypt := f.encryptAll || encryptResourceAlways || (f.encrypt != nil && f.encrypt(gvk))
 		// In non-test code this invokes pkg/sqlcache/informer/informer.go: NewInformer()
 		// search for "func NewInformer(ctx"
 		i, err := f.newInformer(gi.ctx, client, fields, externalUpdateInfo, selfUpdateInfo, transform, gvk, f.dbClient, shouldEncrypt, typeGuidance, namespaced, watchable, f.gcInterval, f.gcKeepCount)
@@ -217,6 +222,35 @@ func (f *CacheFactory) cacheForLocked(ctx context.Context, gi *guardedInformer,
 	return &Cache{ByOptionsLister: gi.informer, gvk: gvk}, nil
 }
 
+// SyncedCacheFor returns the informer for the given GVK only if it was already created by a previous CacheFor call
+// and has completed its initial sync. Unlike CacheFor, it never creates an informer nor waits for one to sync.
+//
+// Don't forget to call DoneWithCache with the given informer once done with it, when the second return value is true.
+func (f *CacheFactory) SyncedCacheFor(gvk schema.GroupVersionKind) (*Cache, bool) {
+	f.informersMutex.Lock()
+	gi, ok := f.informers[gvk]
+	f.informersMutex.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	// A Stop call is ongoing for that GVK, don't wait for it
+	if !gi.stopMutex.TryRLock() {
+		return nil, false
+	}
+
+	gi.informerMutex.Lock()
+	i := gi.informer
+	gi.informerMutex.Unlock()
+
+	if i == nil || !i.HasSynced() {
+		gi.stopMutex.RUnlock()
+		return nil, false
+	}
+
+	return &Cache{ByOptionsLister: i, gvk: gvk}, true
+}
+
 // DoneWithCache must be called for every successful CacheFor call. The Cache should
 // no longer be used after DoneWithCache is called.
 //
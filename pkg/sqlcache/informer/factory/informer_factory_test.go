@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -413,6 +414,57 @@ func TestCacheFor(t *testing.T) {
 		time.Sleep(1 * time.Second)
 	}})
 
+	tests = append(tests, testCase{description: "CacheFor() should encrypt the types selected by the encrypt option", test: func(t *testing.T) {
+		dbClient := NewMockClient(gomock.NewController(t))
+		dynamicClient := NewMockResourceInterface(gomock.NewController(t))
+		fields := [][]string{{"something"}}
+		typeGuidance := map[string]string{}
+		expectedGVK := schema.GroupVersionKind{
+			Version: "v1",
+			Kind:    "ConfigMap",
+		}
+		bloi := NewMockByOptionsLister(gomock.NewController(t))
+		bloi.EXPECT().RunGC(gomock.Any()).AnyTimes()
+		bloi.EXPECT().DropAll(gomock.Any()).AnyTimes()
+		sii := NewMockSharedIndexInformer(gomock.NewController(t))
+		sii.EXPECT().HasSynced().Return(true)
+		sii.EXPECT().Run(gomock.Any()).MinTimes(1).AnyTimes()
+		sii.EXPECT().SetWatchErrorHandler(gomock.Any())
+		i := &informer.Informer{
+			// need to set this so Run function is not nil
+			SharedIndexInformer: sii,
+			ByOptionsLister:     bloi,
+		}
+		expectedC := &Cache{
+			ByOptionsLister: i,
+			gvk:             expectedGVK,
+		}
+		testNewInformer := func(ctx context.Context, client dynamic.ResourceInterface, fields [][]string, externalUpdateInfo *sqltypes.ExternalGVKUpdates, selfUpdateInfo *sqltypes.ExternalGVKUpdates, transform cache.TransformFunc, gvk schema.GroupVersionKind, db db.Client, shouldEncrypt bool, typeGuidance map[string]string, namespaced bool, watchable bool, gcInterval time.Duration, gcKeepCount int) (*informer.Informer, error) {
+			assert.Equal(t, expectedGVK, gvk)
+			assert.Equal(t, true, shouldEncrypt)
+			return i, nil
+		}
+		f := &CacheFactory{
+			dbClient:    dbClient,
+			newInformer: testNewInformer,
+			encryptAll:  false,
+			encrypt: func(gvk schema.GroupVersionKind) bool {
+				return gvk.Kind == "ConfigMap"
+			},
+			informers: map[schema.GroupVersionKind]*guardedInformer{},
+		}
+		f.ctx, f.cancel = context.WithCancel(context.Background())
+
+		go func() {
+			time.Sleep(10 * time.Second)
+			f.Stop(expectedGVK)
+		}()
+		c, err := f.CacheFor(context.Background(), fields, nil, nil, nil, dynamicClient, expectedGVK, typeGuidance, false, true)
+		assert.Nil(t, err)
+		assert.Equal(t, expectedC, c)
+		time.Sleep(1 * time.Second)
+	}})
+
 	tests = append(tests, testCase{description: "CacheFor() with no errors returned, HasSync returning true, ctx not canceled, and transform func should return no error", test: func(t *testing.T) {
 		dbClient := NewMockClient(gomock.NewController(t))
 		dynamicClient := NewMockResourceInterface(gomock.NewController(t))
@@ -554,3 +606,76 @@ func TestCacheFor(t *testing.T) {
 		t.Run(test.description, func(t *testing.T) { test.test(t) })
 	}
 }
+
+func TestSyncedCacheFor(t *testing.T) {
+	type testCase struct {
+		description string
+		test        func(t *testing.T)
+	}
+
+	var tests []testCase
+
+	tests = append(tests, testCase{description: "SyncedCacheFor() for a GVK with no informer should return false", test: func(t *testing.T) {
+		f := &CacheFactory{
+			informers: map[schema.GroupVersionKind]*guardedInformer{},
+		}
+		c, ok := f.SyncedCacheFor(schema.GroupVersionKind{Version: "v1", Kind: "Pod"})
+		assert.False(t, ok)
+		assert.Nil(t, c)
+	}})
+	tests = append(tests, testCase{description: "SyncedCacheFor() for a GVK whose informer has not synced should return false and release the stop lock", test: func(t *testing.T) {
+		gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+		sii := NewMockSharedIndexInformer(gomock.NewController(t))
+		sii.EXPECT().HasSynced().Return(false)
+		gi := &guardedInformer{
+			informer:      &informer.Informer{SharedIndexInformer: sii},
+			informerMutex: &sync.Mutex{},
+			stopMutex:     &sync.RWMutex{},
+		}
+		f := &CacheFactory{
+			informers: map[schema.GroupVersionKind]*guardedInformer{gvk: gi},
+		}
+		c, ok := f.SyncedCacheFor(gvk)
+		assert.False(t, ok)
+		assert.Nil(t, c)
+		assert.True(t, gi.stopMutex.TryLock())
+	}})
+	tests = append(tests, testCase{description: "SyncedCacheFor() for a GVK whose informer has synced should return the cache, which is released by DoneWithCache()", test: func(t *testing.T) {
+		gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+		sii := NewMockSharedIndexInformer(gomock.NewController(t))
+		sii.EXPECT().HasSynced().Return(true)
+		i := &informer.Informer{SharedIndexInformer: sii}
+		gi := &guardedInformer{
+			informer:      i,
+			informerMutex: &sync.Mutex{},
+			stopMutex:     &sync.RWMutex{},
+		}
+		f := &CacheFactory{
+			informers: map[schema.GroupVersionKind]*guardedInformer{gvk: gi},
+		}
+		c, ok := f.SyncedCacheFor(gvk)
+		assert.True(t, ok)
+		assert.Equal(t, &Cache{ByOptionsLister: i, gvk: gvk}, c)
+		assert.False(t, gi.stopMutex.TryLock())
+		f.DoneWithCache(c)
+		assert.True(t, gi.stopMutex.TryLock())
+	}})
+	tests = append(tests, testCase{description: "SyncedCacheFor() for a GVK being stopped should return false without blocking", test: func(t *testing.T) {
+		gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+		gi := &guardedInformer{
+			informerMutex: &sync.Mutex{},
+			stopMutex:     &sync.RWMutex{},
+		}
+		gi.stopMutex.Lock()
+		f := &CacheFactory{
+			informers: map[schema.GroupVersionKind]*guardedInformer{gvk: gi},
+		}
+		c, ok := f.SyncedCacheFor(gvk)
+		assert.False(t, ok)
+		assert.Nil(t, c)
+	}})
+	t.Parallel()
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) { test.test(t) })
+	}
+}
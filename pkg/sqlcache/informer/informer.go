@@ -48,7 +48,9 @@ type WatchFilter struct {
 
 type ByOptionsLister interface {
 	ListByOptions(ctx context.Context, lo *sqltypes.ListOptions, partitions []partition.Partition, namespace string) (*unstructured.UnstructuredList, int, string, error)
+	ListFieldValues(ctx context.Context, lo *sqltypes.ListOptions, partitions []partition.Partition, namespace string, field []string) ([][]string, error)
 	Watch(ctx context.Context, options WatchOptions, eventsCh chan<- watch.Event) error
+	GetByKey(key string) (item any, exists bool, err error)
 	GetLatestResourceVersion() []string
 	RunGC(context.Context)
 	DropAll(context.Context) error
@@ -171,6 +173,12 @@ func (i *Informer) ListByOptions(ctx context.Context, lo *sqltypes.ListOptions,
 	return i.ByOptionsLister.ListByOptions(ctx, lo, partitions, namespace)
 }
 
+// ListFieldValues returns the key of the objects matching the specified list options and partitions, along with the
+// value of the given indexed field, without reading the objects
+func (i *Informer) ListFieldValues(ctx context.Context, lo *sqltypes.ListOptions, partitions []partition.Partition, namespace string, field []string) ([][]string, error) {
+	return i.ByOptionsLister.ListFieldValues(ctx, lo, partitions, namespace, field)
+}
+
 // SetSyntheticWatchableInterval - call this function to override the default interval time of 5 seconds
 func SetSyntheticWatchableInterval(interval time.Duration) {
 	defaultRefreshTime = interval
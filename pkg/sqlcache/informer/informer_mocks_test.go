@@ -58,6 +58,22 @@ func (mr *MockByOptionsListerMockRecorder) DropAll(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DropAll", reflect.TypeOf((*MockByOptionsLister)(nil).DropAll), arg0)
 }
 
+// GetByKey mocks base method.
+func (m *MockByOptionsLister) GetByKey(key string) (any, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByKey", key)
+	ret0, _ := ret[0].(any)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetByKey indicates an expected call of GetByKey.
+func (mr *MockByOptionsListerMockRecorder) GetByKey(key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByKey", reflect.TypeOf((*MockByOptionsLister)(nil).GetByKey), key)
+}
+
 // GetLatestResourceVersion mocks base method.
 func (m *MockByOptionsLister) GetLatestResourceVersion() []string {
 	m.ctrl.T.Helper()
@@ -89,6 +105,21 @@ func (mr *MockByOptionsListerMockRecorder) ListByOptions(ctx, lo, partitions, na
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByOptions", reflect.TypeOf((*MockByOptionsLister)(nil).ListByOptions), ctx, lo, partitions, namespace)
 }
 
+// ListFieldValues mocks base method.
+func (m *MockByOptionsLister) ListFieldValues(ctx context.Context, lo *sqltypes.ListOptions, partitions []partition.Partition, namespace string, field []string) ([][]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFieldValues", ctx, lo, partitions, namespace, field)
+	ret0, _ := ret[0].([][]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFieldValues indicates an expected call of ListFieldValues.
+func (mr *MockByOptionsListerMockRecorder) ListFieldValues(ctx, lo, partitions, namespace, field any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFieldValues", reflect.TypeOf((*MockByOptionsLister)(nil).ListFieldValues), ctx, lo, partitions, namespace, field)
+}
+
 // RunGC mocks base method.
 func (m *MockByOptionsLister) RunGC(arg0 context.Context) {
 	m.ctrl.T.Helper()
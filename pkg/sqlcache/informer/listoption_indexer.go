@@ -595,6 +595,12 @@ func (l *ListOptionIndexer) addIndexFields(key string, obj any, tx db.TxClient)
 			args = append(args, fmt.Sprint(typedValue))
 		case []string:
 			args = append(args, strings.Join(typedValue, "|"))
+		case []interface{}:
+			values := make([]string, len(typedValue))
+			for i, v := range typedValue {
+				values[i] = fmt.Sprint(v)
+			}
+			args = append(args, strings.Join(values, "|"))
 		default:
 			err2 := fmt.Errorf("field %v has a non-supported type value: %v", field, value)
 			return err2
@@ -665,7 +671,48 @@ type QueryInfo struct {
 	offset      int
 }
 
+// ListFieldValues returns the key of the objects matching the specified list options and partitions, along with the
+// value of the given indexed field, without reading the objects. The values of a field in a list, such as
+// spec.containers.image, are |-separated.
+func (l *ListOptionIndexer) ListFieldValues(ctx context.Context, lo *sqltypes.ListOptions, partitions []partition.Partition, namespace string, field []string) (result [][]string, err error) {
+	column, err := l.getValidFieldEntry("f", field)
+	if err != nil {
+		return nil, err
+	}
+	queryInfo, err := l.constructQueryFor(lo, partitions, namespace, db.Sanitize(l.GetName()), fmt.Sprintf(`o.key, COALESCE(%s, '')`, column))
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := l.Prepare(queryInfo.query)
+	defer func() {
+		if cerr := stmt.Close(); cerr != nil && err == nil {
+			err = errors.Join(err, cerr)
+		}
+	}()
+	err = l.WithTransaction(ctx, false, func(tx db.TxClient) error {
+		now := time.Now()
+		rows, err := tx.Stmt(stmt).QueryContext(ctx, queryInfo.params...)
+		if err != nil {
+			return err
+		}
+		logLongQuery(time.Since(now), queryInfo.query, queryInfo.params)
+		result, err = l.ReadStrings2(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func (l *ListOptionIndexer) constructQuery(lo *sqltypes.ListOptions, partitions []partition.Partition, namespace string, dbName string) (*QueryInfo, error) {
+	return l.constructQueryFor(lo, partitions, namespace, dbName, "o.object, o.objectnonce, o.dekid")
+}
+
+// constructQueryFor builds the query selecting the given columns of the objects matching the list options and
+// partitions
+func (l *ListOptionIndexer) constructQueryFor(lo *sqltypes.ListOptions, partitions []partition.Partition, namespace string, dbName string, columns string) (*QueryInfo, error) {
 	unboundSortLabels := getUnboundSortLabels(lo)
 	queryInfo := &QueryInfo{}
 	queryUsesLabels := hasLabelFilter(lo.Filters) || len(lo.ProjectsOrNamespaces.Filters) > 0
@@ -713,7 +760,7 @@ func (l *ListOptionIndexer) constructQuery(lo *sqltypes.ListOptions, partitions
 	if queryUsesLabels {
 		query += "DISTINCT "
 	}
-	query += fmt.Sprintf(`o.object, o.objectnonce, o.dekid FROM "%s" o`, dbName)
+	query += fmt.Sprintf(`%s FROM "%s" o`, columns, dbName)
 	query += "\n  "
 	query += fmt.Sprintf(`JOIN "%s_fields" f ON o.key = f.key`, dbName)
 	if len(joinPartsToUse) > 0 {
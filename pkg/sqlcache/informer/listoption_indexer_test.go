@@ -1899,6 +1899,110 @@ func TestUserDefinedExtractFunction(t *testing.T) {
 	}
 }
 
+func TestListFieldValues(t *testing.T) {
+	makeObj := func(namespace, name string, images ...string) map[string]any {
+		var containers []any
+		for _, image := range images {
+			containers = append(containers, map[string]any{"image": image})
+		}
+		return map[string]any{
+			"metadata": map[string]any{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"spec": map[string]any{
+				"containers": containers,
+			},
+		}
+	}
+	ctx := context.Background()
+	itemList := makeList(t,
+		makeObj("ns1", "obj01", "nginx:1.25", "envoy:1.30"),
+		makeObj("ns1", "obj02", "redis:7"),
+		makeObj("ns2", "obj03", "postgres:16"),
+		makeObj("ns2", "obj04"),
+	)
+
+	tests := []struct {
+		description string
+		listOptions sqltypes.ListOptions
+		partitions  []partition.Partition
+		ns          string
+		field       []string
+		expected    [][]string
+		expectedErr bool
+	}{
+		{
+			description: "values of all the objects",
+			partitions:  []partition.Partition{{All: true}},
+			field:       []string{"spec", "containers", "image"},
+			expected: [][]string{
+				{"ns1/obj01", "nginx:1.25|envoy:1.30"},
+				{"ns1/obj02", "redis:7"},
+				{"ns2/obj03", "postgres:16"},
+				{"ns2/obj04", ""},
+			},
+		},
+		{
+			description: "values in a namespace and partition",
+			partitions:  []partition.Partition{{Namespace: "ns1", Names: sets.New("obj02")}},
+			ns:          "ns1",
+			field:       []string{"spec", "containers", "image"},
+			expected: [][]string{
+				{"ns1/obj02", "redis:7"},
+			},
+		},
+		{
+			description: "values of filtered objects",
+			listOptions: sqltypes.ListOptions{Filters: []sqltypes.OrFilter{
+				{
+					Filters: []sqltypes.Filter{
+						{
+							Field:   []string{"metadata", "namespace"},
+							Matches: []string{"ns2"},
+							Op:      sqltypes.Eq,
+						},
+					},
+				},
+			}},
+			partitions: []partition.Partition{{All: true}},
+			field:      []string{"spec", "containers", "image"},
+			expected: [][]string{
+				{"ns2/obj03", "postgres:16"},
+				{"ns2/obj04", ""},
+			},
+		},
+		{
+			description: "field not indexed",
+			partitions:  []partition.Partition{{All: true}},
+			field:       []string{"spec", "nodeName"},
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			opts := ListOptionIndexerOptions{
+				Fields:       [][]string{{"spec", "containers", "image"}},
+				IsNamespaced: true,
+			}
+			loi, dbPath, err := makeListOptionIndexer(ctx, opts, false, emptyNamespaceList)
+			defer cleanTempFiles(dbPath)
+			require.NoError(t, err)
+			for _, item := range itemList.Items {
+				require.NoError(t, loi.Add(&item))
+			}
+
+			values, err := loi.ListFieldValues(ctx, &test.listOptions, test.partitions, test.ns, test.field)
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, values)
+		})
+	}
+}
+
 func TestUserDefinedInetToAnonFunction(t *testing.T) {
 	makeObj := func(name string, ipAddr string) map[string]any {
 		h1 := map[string]any{
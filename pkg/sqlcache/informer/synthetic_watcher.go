@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/watch"
@@ -83,8 +84,10 @@ func (rw *SyntheticWatcher) receive(client dynamic.ResourceInterface, options me
 						rw.resultChan <- w
 					} else {
 						delete(previousState, key)
-						if oldItem.version != newObject.version {
-							w, err := createWatchEvent(watch.Modified, oldItem.unstructuredObject)
+						if oldItem.version != newObject.version || (newObject.version == "" && contentChanged(oldItem.unstructuredObject, newObject.unstructuredObject)) {
+							// like real watches, Modified events carry the new state of the object, which is what
+							// informers store
+							w, err := createWatchEvent(watch.Modified, newObject.unstructuredObject)
 							if err != nil {
 								logrus.Errorf("can't convert unstructured obj into runtime: %s", err)
 								continue
@@ -114,6 +117,15 @@ func (rw *SyntheticWatcher) receive(client dynamic.ResourceInterface, options me
 	}()
 }
 
+// contentChanged compares objects having no resource version, such as the ones served by metrics.k8s.io. Their
+// creation timestamp is ignored, as such APIs usually set it to the time of the request.
+func contentChanged(oldObj, newObj *unstructured.Unstructured) bool {
+	oldObj, newObj = oldObj.DeepCopy(), newObj.DeepCopy()
+	unstructured.RemoveNestedField(oldObj.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(newObj.Object, "metadata", "creationTimestamp")
+	return !equality.Semantic.DeepEqual(oldObj.Object, newObj.Object)
+}
+
 func createWatchEvent(event watch.EventType, u *unstructured.Unstructured) (watch.Event, error) {
 	return watch.Event{Type: event, Object: u}, nil
 }
@@ -114,6 +114,12 @@ func TestSyntheticWatcher(t *testing.T) {
 	}
 	assert.Equal(t, "modified-result", results[len(list.Items)].eventName)
 	assert.Equal(t, "modified-result", results[len(list.Items)+1].eventName)
+	// modified events carry the new version of the objects
+	var modifiedVersions []string
+	for _, result := range results[len(list.Items) : len(list.Items)+2] {
+		modifiedVersions = append(modifiedVersions, result.payload.(*watch.Event).Object.(*unstructured.Unstructured).GetResourceVersion())
+	}
+	assert.ElementsMatch(t, []string{"rv1.2", "rv2.2"}, modifiedVersions)
 	assert.Equal(t, "deleted-result", results[len(list.Items)+2].eventName)
 	assert.Equal(t, "stop", results[7].eventName)
 	// We can't really assert that the events get the correct timestamps on them
@@ -188,3 +194,59 @@ loop:
 	}
 	return results, nil
 }
+
+func TestSyntheticWatcherWithoutResourceVersion(t *testing.T) {
+	dynamicClient := NewMockResourceInterface(gomock.NewController(t))
+	newMetrics := func(name, cpu, creationTimestamp string) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "metrics.k8s.io/v1beta1",
+			"kind":       "NodeMetrics",
+			"metadata": map[string]interface{}{
+				"name":              name,
+				"creationTimestamp": creationTimestamp,
+			},
+			"usage": map[string]interface{}{"cpu": cpu},
+		}}
+	}
+	dynamicClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(&unstructured.UnstructuredList{
+		Items: []unstructured.Unstructured{
+			newMetrics("node1", "100m", "2024-01-01T00:00:00Z"),
+			newMetrics("node2", "100m", "2024-01-01T00:00:00Z"),
+		},
+	}, nil)
+	// only the usage of node2 changed, the creation timestamp being the time of the request
+	dynamicClient.EXPECT().List(gomock.Any(), gomock.Any()).AnyTimes().Return(&unstructured.UnstructuredList{
+		Items: []unstructured.Unstructured{
+			newMetrics("node1", "100m", "2024-01-01T00:00:05Z"),
+			newMetrics("node2", "200m", "2024-01-01T00:00:05Z"),
+		},
+	}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sw := newSyntheticWatcher(ctx, cancel)
+	w, err := sw.watch(dynamicClient, metav1.ListOptions{}, 10*time.Millisecond)
+	assert.Nil(t, err)
+	var results []processedObjectInfo
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		results, err = handleAnyWatch(w, make(chan error), sw.stopChan)
+		wg.Done()
+	}()
+	go func() {
+		time.Sleep(40 * time.Millisecond)
+		sw.stopChan <- struct{}{}
+		wg.Done()
+	}()
+	wg.Wait()
+
+	assert.Len(t, results, 4)
+	assert.Equal(t, "added-result", results[0].eventName)
+	assert.Equal(t, "added-result", results[1].eventName)
+	assert.Equal(t, "modified-result", results[2].eventName)
+	modified := results[2].payload.(*watch.Event).Object.(*unstructured.Unstructured)
+	assert.Equal(t, "node2", modified.GetName())
+	cpu, _, _ := unstructured.NestedString(modified.Object, "usage", "cpu")
+	assert.Equal(t, "200m", cpu)
+	assert.Equal(t, "stop", results[3].eventName)
+}
@@ -0,0 +1,232 @@
+/*
+Package prewarm creates SQL cache informers for a configured set of types as soon as their schemas are discovered, so
+that the first request for one of them doesn't have to wait for a full list.
+*/
+package prewarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	controllerschema "github.com/rancher/steve/pkg/controllers/schema"
+	"github.com/rancher/steve/pkg/metrics"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/server/healthz"
+)
+
+const (
+	defaultConcurrency = 4
+	defaultTimeout     = 5 * time.Minute
+)
+
+// Warmer warms up the cache of a given type
+type Warmer interface {
+	Prewarm(ctx context.Context, schema *types.APISchema) error
+}
+
+type Options struct {
+	// GVKs lists the types whose cache should be warmed up
+	GVKs []k8sschema.GroupVersionKind
+	// SchemaIDs lists the IDs of the schemas whose cache should be warmed up. Entries can also be patterns, as
+	// understood by path.Match, eg. "management.cattle.io.*"
+	SchemaIDs []string
+	// Concurrency is the maximum number of caches being warmed up at the same time, defaults to 4
+	Concurrency int
+	// Timeout is how long to wait for a single cache to be warmed up, defaults to 5 minutes. A cache that takes longer
+	// keeps syncing in the background, but no longer holds back readiness.
+	Timeout time.Duration
+}
+
+// Prewarmer warms up the caches of the types matching its options every time schemas change. Caches that are already
+// warm are left untouched.
+type Prewarmer struct {
+	ctx    context.Context
+	warmer Warmer
+	opts   Options
+
+	// lock protects next, running and pending, making sure only one run happens at a time
+	lock    sync.Mutex
+	next    []*types.APISchema
+	running bool
+	// pending holds the IDs of the schemas the first run is still warming up. Once it's done, it's set to an empty
+	// non-nil map and stays so
+	pending map[string]bool
+}
+
+func NewPrewarmer(ctx context.Context, warmer Warmer, opts Options) *Prewarmer {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultConcurrency
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	return &Prewarmer{
+		ctx:    ctx,
+		warmer: warmer,
+		opts:   opts,
+	}
+}
+
+// OnSchemas warms up the caches of the matching schemas in the background. If a previous run is still ongoing, the new
+// one starts once it's done.
+func (p *Prewarmer) OnSchemas(schemas *schema.Collection) error {
+	toWarm := p.match(schemas)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.pending == nil {
+		p.pending = map[string]bool{}
+		for _, s := range toWarm {
+			p.pending[s.ID] = true
+		}
+	}
+	if len(toWarm) == 0 {
+		return nil
+	}
+	p.next = toWarm
+	if !p.running {
+		p.running = true
+		go p.loop()
+	}
+	return nil
+}
+
+func (p *Prewarmer) match(schemas *schema.Collection) []*types.APISchema {
+	if len(p.opts.GVKs) == 0 && len(p.opts.SchemaIDs) == 0 {
+		return nil
+	}
+
+	gvks := map[k8sschema.GroupVersionKind]bool{}
+	for _, gvk := range p.opts.GVKs {
+		gvks[gvk] = true
+	}
+
+	var result []*types.APISchema
+	for _, id := range schemas.IDs() {
+		s := schemas.Schema(id)
+		if s == nil || !controllerschema.IsListWatchable(s) {
+			continue
+		}
+		if gvks[attributes.GVK(s)] || p.matchesSchemaID(id) {
+			result = append(result, s)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+func (p *Prewarmer) matchesSchemaID(id string) bool {
+	for _, pattern := range p.opts.SchemaIDs {
+		if ok, err := path.Match(pattern, id); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Prewarmer) loop() {
+	for {
+		p.lock.Lock()
+		toWarm := p.next
+		p.next = nil
+		if toWarm == nil {
+			p.running = false
+			p.lock.Unlock()
+			return
+		}
+		// types replaced by a later call before being warmed up no longer hold back readiness
+		warming := map[string]bool{}
+		for _, s := range toWarm {
+			warming[s.ID] = true
+		}
+		for id := range p.pending {
+			if !warming[id] {
+				delete(p.pending, id)
+			}
+		}
+		p.lock.Unlock()
+
+		p.run(toWarm)
+	}
+}
+
+func (p *Prewarmer) run(toWarm []*types.APISchema) {
+	start := time.Now()
+	logrus.Infof("Pre-warming SQL cache for %d types", len(toWarm))
+
+	var pending, failed atomic.Int32
+	pending.Store(int32(len(toWarm)))
+	metrics.SetSQLCachePrewarmPending(len(toWarm))
+
+	eg := errgroup.Group{}
+	eg.SetLimit(p.opts.Concurrency)
+	for _, s := range toWarm {
+		if p.ctx.Err() != nil {
+			break
+		}
+		eg.Go(func() error {
+			typeStart := time.Now()
+			// the informer isn't stopped on timeout, only the wait for its initial sync is
+			ctx, cancel := context.WithTimeout(p.ctx, p.opts.Timeout)
+			err := p.warmer.Prewarm(ctx, s)
+			cancel()
+			p.done(s.ID)
+			metrics.RecordSQLCachePrewarm(s.ID, err, float64(time.Since(typeStart).Milliseconds()))
+			metrics.SetSQLCachePrewarmPending(int(pending.Add(-1)))
+			if err != nil {
+				failed.Add(1)
+				logrus.Warnf("Failed to pre-warm SQL cache for %s: %v", s.ID, err)
+				return nil
+			}
+			logrus.Debugf("Pre-warmed SQL cache for %s in %v", s.ID, time.Since(typeStart))
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	metrics.SetSQLCachePrewarmPending(0)
+	logrus.Infof("Pre-warmed SQL cache for %d types in %v, %d failed", len(toWarm), time.Since(start), failed.Load())
+}
+
+// done removes the given schema from the ones holding back readiness
+func (p *Prewarmer) done(id string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.pending, id)
+}
+
+// NewReadyCheck returns a health check failing until the given Prewarmer is done with the types matching the first
+// schemas it received, whether warming them up succeeded, failed or timed out. Later changes to schemas, as well as
+// caches created on demand, don't affect it.
+func NewReadyCheck(p *Prewarmer) healthz.HealthChecker {
+	return healthz.NamedCheck("sqlcache", func(_ *http.Request) error {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		if p.pending == nil {
+			return errors.New("schemas not received yet")
+		}
+		if len(p.pending) > 0 {
+			ids := make([]string, 0, len(p.pending))
+			for id := range p.pending {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			return fmt.Errorf("caches not warmed up: %v", ids)
+		}
+		return nil
+	})
+}
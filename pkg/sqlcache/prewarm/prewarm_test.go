@@ -0,0 +1,213 @@
+package prewarm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type testWarmer struct {
+	lock    sync.Mutex
+	warmed  []string
+	fail    map[string]bool
+	block   chan struct{}
+	current atomic.Int32
+	peak    atomic.Int32
+}
+
+func (w *testWarmer) Prewarm(_ context.Context, s *types.APISchema) error {
+	current := w.current.Add(1)
+	defer w.current.Add(-1)
+	for {
+		peak := w.peak.Load()
+		if current <= peak || w.peak.CompareAndSwap(peak, current) {
+			break
+		}
+	}
+	if w.block != nil {
+		<-w.block
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.warmed = append(w.warmed, s.ID)
+	if w.fail[s.ID] {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func (w *testWarmer) Warmed() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return append([]string(nil), w.warmed...)
+}
+
+func newSchema(id string, gvk k8sschema.GroupVersionKind, verbs ...string) *types.APISchema {
+	s := &types.APISchema{
+		Schema: &schemas.Schema{ID: id},
+	}
+	attributes.SetGVK(s, gvk)
+	attributes.SetGVR(s, gvk.GroupVersion().WithResource(id))
+	attributes.SetVerbs(s, verbs)
+	return s
+}
+
+func newCollection(schemas ...*types.APISchema) *schema.Collection {
+	collection := schema.NewCollection(context.Background(), types.EmptyAPISchemas(), nil)
+	byID := map[string]*types.APISchema{}
+	for _, s := range schemas {
+		byID[s.ID] = s
+	}
+	collection.Reset(byID)
+	return collection
+}
+
+func TestPrewarmer(t *testing.T) {
+	pods := newSchema("pod", k8sschema.GroupVersionKind{Version: "v1", Kind: "Pod"}, "list", "watch")
+	deployments := newSchema("apps.deployment", k8sschema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, "list", "watch")
+	statefulsets := newSchema("apps.statefulset", k8sschema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}, "list", "watch")
+	configmaps := newSchema("configmap", k8sschema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, "list", "watch")
+	unwatchable := newSchema("apps.unwatchable", k8sschema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Unwatchable"}, "list")
+	collection := newCollection(pods, deployments, statefulsets, configmaps, unwatchable)
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected []string
+	}{
+		{
+			name: "nothing configured",
+		},
+		{
+			name: "by GVK",
+			opts: Options{
+				GVKs: []k8sschema.GroupVersionKind{{Version: "v1", Kind: "Pod"}},
+			},
+			expected: []string{"pod"},
+		},
+		{
+			name: "by schema ID and pattern",
+			opts: Options{
+				SchemaIDs: []string{"configmap", "apps.*"},
+			},
+			expected: []string{"apps.deployment", "apps.statefulset", "configmap"},
+		},
+		{
+			name: "both, without duplicates",
+			opts: Options{
+				GVKs:      []k8sschema.GroupVersionKind{{Group: "apps", Version: "v1", Kind: "Deployment"}},
+				SchemaIDs: []string{"apps.deployment", "[invalid"},
+			},
+			expected: []string{"apps.deployment"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			warmer := &testWarmer{}
+			p := NewPrewarmer(context.Background(), warmer, test.opts)
+			assert.NoError(t, p.OnSchemas(collection))
+			assert.EventuallyWithT(t, func(c *assert.CollectT) {
+				assert.ElementsMatch(c, test.expected, warmer.Warmed())
+				p.lock.Lock()
+				assert.False(c, p.running)
+				p.lock.Unlock()
+			}, 5*time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestPrewarmerConcurrency(t *testing.T) {
+	var all []*types.APISchema
+	for _, id := range []string{"a", "b", "c", "d", "e", "f"} {
+		all = append(all, newSchema(id, k8sschema.GroupVersionKind{Group: "test.io", Version: "v1", Kind: id}, "list", "watch"))
+	}
+	collection := newCollection(all...)
+
+	warmer := &testWarmer{
+		block: make(chan struct{}),
+		fail:  map[string]bool{"b": true},
+	}
+	p := NewPrewarmer(context.Background(), warmer, Options{SchemaIDs: []string{"*"}, Concurrency: 2})
+	assert.NoError(t, p.OnSchemas(collection))
+
+	assert.Eventually(t, func() bool {
+		return warmer.current.Load() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	// calls made while a run is ongoing are coalesced into a single run, queued after it
+	assert.NoError(t, p.OnSchemas(collection))
+	assert.NoError(t, p.OnSchemas(collection))
+	close(warmer.block)
+
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		assert.Len(c, warmer.Warmed(), 12)
+		p.lock.Lock()
+		assert.False(c, p.running)
+		p.lock.Unlock()
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), warmer.peak.Load())
+}
+
+func TestNewReadyCheck(t *testing.T) {
+	pods := newSchema("pod", k8sschema.GroupVersionKind{Version: "v1", Kind: "Pod"}, "list", "watch")
+	secrets := newSchema("secret", k8sschema.GroupVersionKind{Version: "v1", Kind: "Secret"}, "list", "watch")
+	configmaps := newSchema("configmap", k8sschema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, "list", "watch")
+
+	t.Run("ready once the first run is done", func(t *testing.T) {
+		warmer := &testWarmer{
+			block: make(chan struct{}),
+			fail:  map[string]bool{"secret": true},
+		}
+		p := NewPrewarmer(context.Background(), warmer, Options{SchemaIDs: []string{"pod", "secret"}})
+		check := NewReadyCheck(p)
+		assert.Equal(t, "sqlcache", check.Name())
+		assert.Error(t, check.Check(nil))
+
+		assert.NoError(t, p.OnSchemas(newCollection(pods, secrets)))
+		assert.ErrorContains(t, check.Check(nil), "[pod secret]")
+
+		close(warmer.block)
+		assert.EventuallyWithT(t, func(c *assert.CollectT) {
+			assert.NoError(c, check.Check(nil))
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// caches of new types don't affect readiness anymore
+		p.opts.SchemaIDs = append(p.opts.SchemaIDs, "configmap")
+		warmer.block = make(chan struct{})
+		assert.NoError(t, p.OnSchemas(newCollection(pods, secrets, configmaps)))
+		assert.NoError(t, check.Check(nil))
+		close(warmer.block)
+	})
+	t.Run("nothing to warm up", func(t *testing.T) {
+		p := NewPrewarmer(context.Background(), &testWarmer{}, Options{})
+		assert.NoError(t, p.OnSchemas(newCollection(pods)))
+		assert.NoError(t, NewReadyCheck(p).Check(nil))
+	})
+	t.Run("timed out", func(t *testing.T) {
+		warmer := &timeoutWarmer{}
+		p := NewPrewarmer(context.Background(), warmer, Options{SchemaIDs: []string{"pod"}, Timeout: 10 * time.Millisecond})
+		assert.NoError(t, p.OnSchemas(newCollection(pods)))
+		assert.EventuallyWithT(t, func(c *assert.CollectT) {
+			assert.NoError(c, NewReadyCheck(p).Check(nil))
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
+
+// timeoutWarmer never warms up a cache, like one whose initial list keeps failing
+type timeoutWarmer struct{}
+
+func (w *timeoutWarmer) Prewarm(ctx context.Context, _ *types.APISchema) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
@@ -1,6 +1,10 @@
 package sqltypes
 
-import "k8s.io/apimachinery/pkg/runtime/schema"
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
 
 type Op string
 
@@ -94,6 +98,9 @@ type ExternalGVKUpdates struct {
 	AffectedGVK               schema.GroupVersionKind
 	ExternalDependencies      []ExternalDependency
 	ExternalLabelDependencies []ExternalLabelDependency
+	// Debounce, if set, coalesces the updates triggered by upserts into a single one, run once Debounce has elapsed
+	// since the first of them. It is meant for types whose objects change in bursts, such as polled ones.
+	Debounce time.Duration
 }
 
 type ExternalGVKDependency map[schema.GroupVersionKind]*ExternalGVKUpdates
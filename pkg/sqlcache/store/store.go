@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/rancher/lasso/pkg/log"
 	"github.com/rancher/steve/pkg/sqlcache/db"
@@ -68,6 +70,10 @@ type Store struct {
 	afterDelete    []func(key string, obj any, tx db.TxClient) error
 	afterDeleteAll []func(tx db.TxClient) error
 	beforeDropAll  []func(tx db.TxClient) error
+
+	// scheduled holds the debounced external updates waiting to run
+	scheduled     map[*sqltypes.ExternalGVKUpdates]bool
+	scheduledLock sync.Mutex
 }
 
 // Test that Store implements cache.Indexer
@@ -93,6 +99,7 @@ func NewStore(ctx context.Context, example any, keyFunc cache.KeyFunc, c db.Clie
 		afterUpdate:        []func(key string, obj any, tx db.TxClient) error{},
 		afterDelete:        []func(key string, obj any, tx db.TxClient) error{},
 		afterDeleteAll:     []func(tx db.TxClient) error{},
+		scheduled:          map[*sqltypes.ExternalGVKUpdates]bool{},
 	}
 
 	dbName := db.Sanitize(s.name)
@@ -124,17 +131,49 @@ func isDBError(e error) bool {
 
 func (s *Store) checkUpdateExternalInfo(key string) {
 	for _, updateBlock := range []*sqltypes.ExternalGVKUpdates{s.externalUpdateInfo, s.selfUpdateInfo} {
-		if updateBlock != nil {
-			s.WithTransaction(s.ctx, true, func(tx db.TxClient) error {
-				err := s.updateExternalInfo(tx, key, updateBlock)
-				if err != nil && !isDBError(err) {
-					// Just report and ignore errors
-					logrus.Errorf("Error updating external info %v: %s", s.externalUpdateInfo, err)
-				}
-				return nil
-			})
+		if updateBlock == nil {
+			continue
+		}
+		if updateBlock.Debounce > 0 {
+			s.scheduleUpdateExternalInfo(key, updateBlock)
+			continue
+		}
+		s.runUpdateExternalInfo(key, updateBlock)
+	}
+}
+
+func (s *Store) runUpdateExternalInfo(key string, updateBlock *sqltypes.ExternalGVKUpdates) {
+	s.WithTransaction(s.ctx, true, func(tx db.TxClient) error {
+		err := s.updateExternalInfo(tx, key, updateBlock)
+		if err != nil && !isDBError(err) {
+			// Just report and ignore errors
+			logrus.Errorf("Error updating external info %v: %s", s.externalUpdateInfo, err)
 		}
+		return nil
+	})
+}
+
+// scheduleUpdateExternalInfo runs the given updates once its Debounce has elapsed, unless they are already scheduled.
+// As updateExternalInfo updates all the affected rows at once, the upserts made in the meantime are covered too.
+func (s *Store) scheduleUpdateExternalInfo(key string, updateBlock *sqltypes.ExternalGVKUpdates) {
+	s.scheduledLock.Lock()
+	defer s.scheduledLock.Unlock()
+	if s.scheduled[updateBlock] {
+		return
 	}
+	s.scheduled[updateBlock] = true
+
+	time.AfterFunc(updateBlock.Debounce, func() {
+		// unscheduled before running, so that upserts made while running are picked up by the next run
+		s.scheduledLock.Lock()
+		delete(s.scheduled, updateBlock)
+		s.scheduledLock.Unlock()
+
+		if s.ctx.Err() != nil {
+			return
+		}
+		s.runUpdateExternalInfo(key, updateBlock)
+	})
 }
 
 // This function is called in two different conditions:
@@ -17,6 +17,7 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rancher/steve/pkg/sqlcache/db"
 	"github.com/rancher/steve/pkg/sqlcache/sqltypes"
@@ -914,6 +915,54 @@ func TestAddWithExternalUpdates(t *testing.T) {
 	}
 }
 
+func TestAddWithDebouncedExternalUpdates(t *testing.T) {
+	c, txC := SetupMockDB(t)
+	updateInfo := &sqltypes.ExternalGVKUpdates{
+		AffectedGVK: schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"},
+		ExternalDependencies: []sqltypes.ExternalDependency{{
+			SourceGVK:            gvkKey("", "v1", "Pod"),
+			SourceFieldName:      "id",
+			TargetGVK:            gvkKey("metrics.k8s.io", "v1beta1", "PodMetrics"),
+			TargetKeyFieldName:   "id",
+			TargetFinalFieldName: "metadata.usage.cpu",
+		}},
+		Debounce: 50 * time.Millisecond,
+	}
+	store, err := NewStore(context.Background(), testStoreObject{}, testStoreKeyFunc, c, false, schema.GroupVersionKind{Version: "v1", Kind: "testStoreObject"}, "testStoreObject", updateInfo, nil)
+	assert.NoError(t, err)
+
+	testObjectSerialized := db.SerializedObject{Bytes: []byte("testobject")}
+	c.EXPECT().Serialize(gomock.Any(), false).Return(testObjectSerialized, nil).Times(3)
+	c.EXPECT().Upsert(txC, store.upsertStmt, gomock.Any(), testObjectSerialized).Return(nil).Times(3)
+	// three upserts, but a single update of the usage
+	c.EXPECT().WithTransaction(gomock.Any(), true, gomock.Any()).Return(nil).Do(
+		func(ctx context.Context, shouldEncrypt bool, f db.WithTransactionFunction) {
+			assert.NoError(t, f(txC))
+		}).Times(4)
+	rawStmt := `SELECT DISTINCT f.key, ex2."metadata.usage.cpu"
+         FROM "_v1_Pod_fields" f JOIN "metrics.k8s.io_v1beta1_PodMetrics_fields" ex2
+         ON f."id" = ex2."id"
+         WHERE f."metadata.usage.cpu" != ex2."metadata.usage.cpu"`
+	c.EXPECT().Prepare(WSIgnoringMatcher(rawStmt))
+	c.EXPECT().QueryForRows(gomock.Any(), gomock.Any(), []any{})
+	updated := make(chan struct{})
+	c.EXPECT().ReadStrings2(gomock.Any()).DoAndReturn(func(_ db.Rows) ([][]string, error) {
+		close(updated)
+		return nil, nil
+	})
+
+	for _, id := range []string{"pod1", "pod2", "pod3"} {
+		assert.NoError(t, store.Add(testStoreObject{Id: id, Val: "a"}))
+	}
+	select {
+	case <-updated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("external updates were not run")
+	}
+	// let a second, unexpected, run fail the test
+	time.Sleep(100 * time.Millisecond)
+}
+
 func TestAddWithSelfUpdates(t *testing.T) {
 	type testCase struct {
 		description string
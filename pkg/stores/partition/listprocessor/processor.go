@@ -2,6 +2,7 @@
 package listprocessor
 
 import (
+	"cmp"
 	"fmt"
 	"regexp"
 	"slices"
@@ -412,6 +413,34 @@ func SortList(list []unstructured.Unstructured, s Sort) []unstructured.Unstructu
 	return newList
 }
 
+// SortValues sorts the slice by the provided sort criteria, as SortList does, except that integers are compared as
+// numbers and that values of other types are converted to strings. It is meant for lists of objects built by steve
+// rather than read from Kubernetes, whose fields may not all be strings.
+func SortValues(list []unstructured.Unstructured, s Sort) {
+	slices.SortStableFunc(list, func(left, right unstructured.Unstructured) int {
+		for i, fields := range s.Fields {
+			leftValue, _ := data.GetValueFromAny(left.Object, fields...)
+			rightValue, _ := data.GetValueFromAny(right.Object, fields...)
+			var diff int
+			leftInt, leftIsInt := leftValue.(int)
+			rightInt, rightIsInt := rightValue.(int)
+			if leftIsInt && rightIsInt {
+				diff = cmp.Compare(leftInt, rightInt)
+			} else {
+				diff = strings.Compare(convert.ToString(leftValue), convert.ToString(rightValue))
+			}
+			if diff == 0 {
+				continue
+			}
+			if s.Orders[i] == DESC {
+				diff *= -1
+			}
+			return diff
+		}
+		return 0
+	})
+}
+
 // PaginateList returns a subset of the result based on the pagination criteria as well as the total number of pages the caller can expect.
 func PaginateList(list []unstructured.Unstructured, p Pagination) ([]unstructured.Unstructured, int) {
 	if p.pageSize <= 0 {
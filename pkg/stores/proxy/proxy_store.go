@@ -67,6 +67,12 @@ type ClientGetter interface {
 	TableAdminClientForWatch(ctx *types.APIRequest, schema *types.APISchema, namespace string, warningHandler rest.WarningHandler) (dynamic.ResourceInterface, error)
 }
 
+// RESTConfigGetter is optionally implemented by ClientGetters able to return the configuration of their clients, for
+// requests that can't go through a client, such as the streaming protocols of exec, attach and port-forward
+type RESTConfigGetter interface {
+	RESTConfig(ctx *types.APIRequest) (*rest.Config, error)
+}
+
 // WarningBuffer holds warnings that may be returned from the kubernetes api
 type WarningBuffer []types.Warning
 
@@ -2,7 +2,6 @@ package proxy
 
 import (
 	"context"
-	"time"
 
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/accesscontrol"
@@ -30,24 +29,14 @@ func (w *WatchRefresh) Watch(apiOp *types.APIRequest, schema *types.APISchema, w
 		return w.Store.Watch(apiOp, schema, wr)
 	}
 
-	as := w.asl.AccessFor(user)
 	ctx, cancel := context.WithCancel(apiOp.Context())
 	apiOp = apiOp.WithContext(ctx)
 
+	changes := accesscontrol.NotifyAccessChanges(ctx, w.asl, user)
 	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-time.After(2 * time.Second):
-			}
-
-			newAs := w.asl.AccessFor(user)
-			if as.ID != newAs.ID {
-				// RBAC changed
-				cancel()
-				return
-			}
+		if _, ok := <-changes; ok {
+			// RBAC changed
+			cancel()
 		}
 	}()
 
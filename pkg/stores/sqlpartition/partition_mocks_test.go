@@ -112,6 +112,22 @@ func (mr *MockUnstructuredStoreMockRecorder) ByID(apiOp, schema, id any) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ByID", reflect.TypeOf((*MockUnstructuredStore)(nil).ByID), apiOp, schema, id)
 }
 
+// ByIDByPartitions mocks base method.
+func (m *MockUnstructuredStore) ByIDByPartitions(apiOp *types.APIRequest, schema *types.APISchema, id string, partitions []partition.Partition) (*unstructured.Unstructured, []types.Warning, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ByIDByPartitions", apiOp, schema, id, partitions)
+	ret0, _ := ret[0].(*unstructured.Unstructured)
+	ret1, _ := ret[1].([]types.Warning)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ByIDByPartitions indicates an expected call of ByIDByPartitions.
+func (mr *MockUnstructuredStoreMockRecorder) ByIDByPartitions(apiOp, schema, id, partitions any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ByIDByPartitions", reflect.TypeOf((*MockUnstructuredStore)(nil).ByIDByPartitions), apiOp, schema, id, partitions)
+}
+
 // Create mocks base method.
 func (m *MockUnstructuredStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (*unstructured.Unstructured, []types.Warning, error) {
 	m.ctrl.T.Helper()
@@ -161,6 +177,21 @@ func (mr *MockUnstructuredStoreMockRecorder) ListByPartitions(apiOp, schema, par
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByPartitions", reflect.TypeOf((*MockUnstructuredStore)(nil).ListByPartitions), apiOp, schema, partitions)
 }
 
+// ListFieldValuesByPartitions mocks base method.
+func (m *MockUnstructuredStore) ListFieldValuesByPartitions(apiOp *types.APIRequest, schema *types.APISchema, partitions []partition.Partition, field []string) ([][]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFieldValuesByPartitions", apiOp, schema, partitions, field)
+	ret0, _ := ret[0].([][]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFieldValuesByPartitions indicates an expected call of ListFieldValuesByPartitions.
+func (mr *MockUnstructuredStoreMockRecorder) ListFieldValuesByPartitions(apiOp, schema, partitions, field any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFieldValuesByPartitions", reflect.TypeOf((*MockUnstructuredStore)(nil).ListFieldValuesByPartitions), apiOp, schema, partitions, field)
+}
+
 // Update mocks base method.
 func (m *MockUnstructuredStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (*unstructured.Unstructured, []types.Warning, error) {
 	m.ctrl.T.Helper()
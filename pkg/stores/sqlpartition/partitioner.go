@@ -25,11 +25,13 @@ var (
 // This interface exists in order for store to be mocked in tests
 type UnstructuredStore interface {
 	ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (*unstructured.Unstructured, []types.Warning, error)
+	ByIDByPartitions(apiOp *types.APIRequest, schema *types.APISchema, id string, partitions []partition.Partition) (*unstructured.Unstructured, []types.Warning, error)
 	Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (*unstructured.Unstructured, []types.Warning, error)
 	Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (*unstructured.Unstructured, []types.Warning, error)
 	Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (*unstructured.Unstructured, []types.Warning, error)
 
 	ListByPartitions(apiOp *types.APIRequest, schema *types.APISchema, partitions []partition.Partition) (*unstructured.UnstructuredList, int, string, error)
+	ListFieldValuesByPartitions(apiOp *types.APIRequest, schema *types.APISchema, partitions []partition.Partition, field []string) ([][]string, error)
 	WatchByPartitions(apiOp *types.APIRequest, schema *types.APISchema, wr types.WatchRequest, partitions []partition.Partition) (chan watch.Event, error)
 }
 
@@ -41,8 +43,11 @@ type rbacPartitioner struct {
 // All returns a slice of partitions applicable to the API schema and the user's access level.
 // For watching individual resources or for blanket access permissions, it returns the passthrough partition.
 // For more granular permissions, it returns a slice of partitions matching an allowed namespace or resource names.
+// For getting a single resource, it returns the partition for that resource, or none if it is not accessible.
 func (p *rbacPartitioner) All(apiOp *types.APIRequest, schema *types.APISchema, verb, id string) ([]partition.Partition, error) {
 	switch verb {
+	case "get":
+		return generatePartitionsByID(apiOp, schema, verb, id), nil
 	case "list":
 		fallthrough
 	case "watch":
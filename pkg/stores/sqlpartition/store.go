@@ -62,9 +62,14 @@ func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id stri
 
 // ByID looks up a single object by its ID.
 func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	partitions, err := s.Partitioner.All(apiOp, schema, "get", id)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+
 	target := s.Partitioner.Store()
 
-	obj, warnings, err := target.ByID(apiOp, schema, id)
+	obj, warnings, err := target.ByIDByPartitions(apiOp, schema, id, partitions)
 	if err != nil {
 		return types.APIObject{}, err
 	}
@@ -104,6 +109,16 @@ func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.AP
 	return result, nil
 }
 
+// ListFieldValues returns the ID and the value of an indexed field of each object of a list, across all applicable
+// partitions. The values of list fields are joined with "|".
+func (s *Store) ListFieldValues(apiOp *types.APIRequest, schema *types.APISchema, field []string) ([][]string, error) {
+	partitions, err := s.Partitioner.All(apiOp, schema, "list", "")
+	if err != nil {
+		return nil, err
+	}
+	return s.Partitioner.Store().ListFieldValuesByPartitions(apiOp, schema, partitions, field)
+}
+
 // Create creates a single object in the store.
 func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
 	target := s.Partitioner.Store()
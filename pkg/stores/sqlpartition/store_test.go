@@ -23,6 +23,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/apiserver/pkg/endpoints/request"
@@ -138,6 +139,121 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestListFieldValues(t *testing.T) {
+	type testCase struct {
+		description string
+		test        func(t *testing.T)
+	}
+	var tests []testCase
+	tests = append(tests, testCase{
+		description: "ListFieldValues() with no errors returned should return the values of the field in the partitions.",
+		test: func(t *testing.T) {
+			p := NewMockPartitioner(gomock.NewController(t))
+			us := NewMockUnstructuredStore(gomock.NewController(t))
+			s := Store{
+				Partitioner: p,
+			}
+			req := &types.APIRequest{}
+			schema := &types.APISchema{
+				Schema: &schemas.Schema{},
+			}
+			partitions := []partition.Partition{{Namespace: "fruitsnamespace", All: true}}
+			field := []string{"spec", "containers", "image"}
+			values := [][]string{{"fruitsnamespace/fuji", "nginx:1.25|envoy:1.30"}}
+			p.EXPECT().All(req, schema, "list", "").Return(partitions, nil)
+			p.EXPECT().Store().Return(us)
+			us.EXPECT().ListFieldValuesByPartitions(req, schema, partitions, field).Return(values, nil)
+			result, err := s.ListFieldValues(req, schema, field)
+			assert.Nil(t, err)
+			assert.Equal(t, values, result)
+		},
+	})
+	tests = append(tests, testCase{
+		description: "ListFieldValues() with partitioner All() error returned should returned an error.",
+		test: func(t *testing.T) {
+			p := NewMockPartitioner(gomock.NewController(t))
+			s := Store{
+				Partitioner: p,
+			}
+			req := &types.APIRequest{}
+			schema := &types.APISchema{
+				Schema: &schemas.Schema{},
+			}
+			p.EXPECT().All(req, schema, "list", "").Return(nil, fmt.Errorf("error"))
+			_, err := s.ListFieldValues(req, schema, []string{"spec", "containers", "image"})
+			assert.NotNil(t, err)
+		},
+	})
+	t.Parallel()
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) { test.test(t) })
+	}
+}
+
+func TestByID(t *testing.T) {
+	type testCase struct {
+		description string
+		test        func(t *testing.T)
+	}
+	var tests []testCase
+	tests = append(tests, testCase{
+		description: "ByID() with no errors returned should pass the get partitions to the store and return the object.",
+		test: func(t *testing.T) {
+			p := NewMockPartitioner(gomock.NewController(t))
+			us := NewMockUnstructuredStore(gomock.NewController(t))
+			s := Store{
+				Partitioner: p,
+			}
+			req := &types.APIRequest{}
+			schema := &types.APISchema{
+				Schema: &schemas.Schema{ID: "apple"},
+			}
+			partitions := []partition.Partition{
+				{
+					Namespace: "fruitsnamespace",
+					Names:     sets.New("fuji"),
+				},
+			}
+			obj := &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"kind": "apple",
+					"metadata": map[string]interface{}{
+						"name":      "fuji",
+						"namespace": "fruitsnamespace",
+					},
+				},
+			}
+			p.EXPECT().All(req, schema, "get", "fruitsnamespace/fuji").Return(partitions, nil)
+			p.EXPECT().Store().Return(us)
+			us.EXPECT().ByIDByPartitions(req, schema, "fruitsnamespace/fuji", partitions).Return(obj, nil, nil)
+			got, err := s.ByID(req, schema, "fruitsnamespace/fuji")
+			assert.Nil(t, err)
+			assert.Equal(t, "fruitsnamespace/fuji", got.ID)
+			assert.Equal(t, "apple", got.Type)
+		},
+	})
+	tests = append(tests, testCase{
+		description: "ByID() with partitioner All() error returned should return an error.",
+		test: func(t *testing.T) {
+			p := NewMockPartitioner(gomock.NewController(t))
+			s := Store{
+				Partitioner: p,
+			}
+			req := &types.APIRequest{}
+			schema := &types.APISchema{
+				Schema: &schemas.Schema{},
+			}
+			p.EXPECT().All(req, schema, "get", "fuji").Return(nil, fmt.Errorf("error"))
+			_, err := s.ByID(req, schema, "fuji")
+			assert.NotNil(t, err)
+		},
+	})
+	t.Parallel()
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) { test.test(t) })
+	}
+}
+
 type mockPartitioner struct {
 	store      sqlproxy.Store
 	partitions map[string][]partition.Partition
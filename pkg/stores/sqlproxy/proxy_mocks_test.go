@@ -307,6 +307,21 @@ func (mr *MockCacheFactoryMockRecorder) Stop(gvk any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*MockCacheFactory)(nil).Stop), gvk)
 }
 
+// SyncedCacheFor mocks base method.
+func (m *MockCacheFactory) SyncedCacheFor(gvk schema.GroupVersionKind) (*factory.Cache, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncedCacheFor", gvk)
+	ret0, _ := ret[0].(*factory.Cache)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// SyncedCacheFor indicates an expected call of SyncedCacheFor.
+func (mr *MockCacheFactoryMockRecorder) SyncedCacheFor(gvk any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncedCacheFor", reflect.TypeOf((*MockCacheFactory)(nil).SyncedCacheFor), gvk)
+}
+
 // MockSchemaColumnSetter is a mock of SchemaColumnSetter interface.
 type MockSchemaColumnSetter struct {
 	ctrl     *gomock.Controller
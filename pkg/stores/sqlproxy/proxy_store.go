@@ -10,13 +10,16 @@ import (
 	"io/ioutil"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rancher/apiserver/pkg/apierror"
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/redaction"
 	"github.com/rancher/steve/pkg/schema/table"
 	"github.com/rancher/steve/pkg/sqlcache/informer"
 	"github.com/rancher/steve/pkg/sqlcache/informer/factory"
@@ -35,7 +38,9 @@ import (
 	controllerschema "github.com/rancher/steve/pkg/controllers/schema"
 	"github.com/rancher/steve/pkg/resources/common"
 	"github.com/rancher/steve/pkg/resources/virtual"
+	"github.com/rancher/steve/pkg/resources/virtual/certificates"
 	virtualCommon "github.com/rancher/steve/pkg/resources/virtual/common"
+	"github.com/rancher/steve/pkg/resources/virtual/usage"
 	metricsStore "github.com/rancher/steve/pkg/stores/metrics"
 	"github.com/rancher/steve/pkg/stores/sqlpartition/listprocessor"
 	"github.com/rancher/steve/pkg/stores/sqlproxy/tablelistconvert"
@@ -60,6 +65,13 @@ const (
 	watchTimeoutEnv            = "CATTLE_WATCH_TIMEOUT_SECONDS"
 	errNamespaceRequired       = "metadata.namespace or apiOp.namespace are required"
 	errResourceVersionRequired = "metadata.resourceVersion is required for update"
+
+	// consistentReadParam is the query parameter used to bypass the cache in ByID and read from the Kubernetes API instead
+	consistentReadParam = "consistentRead"
+
+	// usageUpdateDebounce is how long changes to the metrics of pods and nodes, or to pods and nodes themselves, are
+	// gathered before copying the usage, short enough compared to the interval metrics are polled at
+	usageUpdateDebounce = time.Second
 )
 
 var (
@@ -71,6 +83,7 @@ var (
 			{"_type"},
 			{"involvedObject", "kind"},
 			{"involvedObject", "uid"},
+			{"lastTimestamp"},
 			{"message"},
 			{"reason"},
 		},
@@ -78,6 +91,8 @@ var (
 			{"spec", "displayName"},
 		},
 		gvkKey("", "v1", "Node"): {
+			{"metadata", "usage", "cpu"},
+			{"metadata", "usage", "memory"},
 			{"status", "nodeInfo", "kubeletVersion"},
 			{"status", "nodeInfo", "operatingSystem"}},
 		gvkKey("", "v1", "PersistentVolume"): {
@@ -87,14 +102,24 @@ var (
 		gvkKey("", "v1", "PersistentVolumeClaim"): {
 			{"spec", "volumeName"}},
 		gvkKey("", "v1", "Pod"): {
+			{"metadata", "usage", "cpu"},
+			{"metadata", "usage", "memory"},
 			{"spec", "containers", "image"},
+			{"spec", "ephemeralContainers", "image"},
+			{"spec", "initContainers", "image"},
 			{"spec", "nodeName"},
 			{"status", "podIP"},
 		},
 		gvkKey("", "v1", "ReplicationController"): {
-			{"spec", "template", "spec", "containers", "image"}},
+			{"spec", "template", "spec", "containers", "image"},
+			{"spec", "template", "spec", "initContainers", "image"},
+		},
 		gvkKey("", "v1", "Secret"): {
 			{"metadata", "annotations", "management.cattle.io/project-scoped-secret-copy"},
+			{"metadata", "certificate", "dnsNames"},
+			{"metadata", "certificate", "issuer"},
+			{"metadata", "certificate", "notAfter"},
+			{"metadata", "certificate", "subject"},
 		},
 		gvkKey("", "v1", "Service"): {
 			{"spec", "clusterIP"},
@@ -103,17 +128,21 @@ var (
 		gvkKey("apps", "v1", "DaemonSet"): {
 			{"metadata", "annotations", "field.cattle.io/publicEndpoints"},
 			{"spec", "template", "spec", "containers", "image"},
+			{"spec", "template", "spec", "initContainers", "image"},
 		},
 		gvkKey("apps", "v1", "Deployment"): {
 			{"metadata", "annotations", "field.cattle.io/publicEndpoints"},
 			{"spec", "template", "spec", "containers", "image"},
+			{"spec", "template", "spec", "initContainers", "image"},
 		},
 		gvkKey("apps", "v1", "ReplicaSet"): {
 			{"spec", "template", "spec", "containers", "image"},
+			{"spec", "template", "spec", "initContainers", "image"},
 		},
 		gvkKey("apps", "v1", "StatefulSet"): {
 			{"metadata", "annotations", "field.cattle.io/publicEndpoints"},
 			{"spec", "template", "spec", "containers", "image"},
+			{"spec", "template", "spec", "initContainers", "image"},
 		},
 		gvkKey("autoscaling", "v2", "HorizontalPodAutoscaler"): {
 			{"spec", "scaleTargetRef", "name"},
@@ -124,12 +153,14 @@ var (
 		gvkKey("batch", "v1", "CronJob"): {
 			{"metadata", "annotations", "field.cattle.io/publicEndpoints"},
 			{"spec", "jobTemplate", "spec", "template", "spec", "containers", "image"},
+			{"spec", "jobTemplate", "spec", "template", "spec", "initContainers", "image"},
 			{"status", "lastScheduleTime"},
 			{"status", "lastSuccessfulTime"},
 		},
 		gvkKey("batch", "v1", "Job"): {
 			{"metadata", "annotations", "field.cattle.io/publicEndpoints"},
 			{"spec", "template", "spec", "containers", "image"},
+			{"spec", "template", "spec", "initContainers", "image"},
 		},
 		gvkKey("catalog.cattle.io", "v1", "App"): {
 			{"spec", "chart", "metadata", "name"},
@@ -144,6 +175,12 @@ var (
 			{"status", "namespace"},
 			{"status", "releaseName"},
 		},
+		gvkKey("cert-manager.io", "v1", "Certificate"): {
+			{"metadata", "certificate", "dnsNames"},
+			{"metadata", "certificate", "issuer"},
+			{"metadata", "certificate", "notAfter"},
+			{"metadata", "certificate", "subject"},
+		},
 		gvkKey("cluster.x-k8s.io", "v1beta1", "Machine"): {
 			{"spec", "clusterName"}},
 		gvkKey("cluster.x-k8s.io", "v1beta1", "MachineDeployment"): {
@@ -190,6 +227,14 @@ var (
 		gvkKey("management.cattle.io", "v3", "RoleTemplate"): {
 			{"context"},
 		},
+		gvkKey("metrics.k8s.io", "v1beta1", "NodeMetrics"): {
+			{"metadata", "usage", "cpu"},
+			{"metadata", "usage", "memory"},
+		},
+		gvkKey("metrics.k8s.io", "v1beta1", "PodMetrics"): {
+			{"metadata", "usage", "cpu"},
+			{"metadata", "usage", "memory"},
+		},
 		gvkKey("networking.k8s.io", "v1", "Ingress"): {
 			{"spec", "rules", "host"},
 			{"spec", "ingressClassName"},
@@ -279,14 +324,35 @@ var (
 		ExternalDependencies:      provisionedClusterDependencies,
 		ExternalLabelDependencies: nil,
 	}
+
+	// The usage of pods and nodes is pulled from their metrics, which are only cached if metrics.k8s.io is served.
+	// All the metrics change on every poll, so the usage is copied once per burst of changes rather than once per
+	// metrics object.
+	podGVK          = schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"}
+	nodeGVK         = schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Node"}
+	podUsageUpdates = sqltypes.ExternalGVKUpdates{
+		AffectedGVK:          podGVK,
+		ExternalDependencies: usageDependencies(podGVK, usage.PodMetricsGVK),
+		Debounce:             usageUpdateDebounce,
+	}
+	nodeUsageUpdates = sqltypes.ExternalGVKUpdates{
+		AffectedGVK:          nodeGVK,
+		ExternalDependencies: usageDependencies(nodeGVK, usage.NodeMetricsGVK),
+		Debounce:             usageUpdateDebounce,
+	}
+
 	externalGVKDependencies = sqltypes.ExternalGVKDependency{
-		mcioProjectGvk: &namespaceUpdates,
-		pcioClusterGvk: &pcioClusterUpdates,
+		mcioProjectGvk:       &namespaceUpdates,
+		pcioClusterGvk:       &pcioClusterUpdates,
+		usage.PodMetricsGVK:  &podUsageUpdates,
+		usage.NodeMetricsGVK: &nodeUsageUpdates,
 	}
 	// When a namespace is updated, we need to pull in changes from mcio into the namespaces table
 	selfGVKDependencies = sqltypes.ExternalGVKDependency{
 		namespaceGVK:   &namespaceUpdates,
 		pcioClusterGvk: &pcioClusterUpdates,
+		podGVK:         &podUsageUpdates,
+		nodeGVK:        &nodeUsageUpdates,
 	}
 )
 
@@ -294,6 +360,21 @@ func init() {
 	metav1.AddToGroupVersion(paramScheme, metav1.SchemeGroupVersion)
 }
 
+// usageDependencies copies the usage fields of the metrics of an object into the object, both having the same id
+func usageDependencies(gvk, metricsGVK schema.GroupVersionKind) []sqltypes.ExternalDependency {
+	var result []sqltypes.ExternalDependency
+	for _, field := range [][]string{usage.CPUField, usage.MemoryField} {
+		result = append(result, sqltypes.ExternalDependency{
+			SourceGVK:            gvkKey(gvk.Group, gvk.Version, gvk.Kind),
+			SourceFieldName:      "id",
+			TargetGVK:            gvkKey(metricsGVK.Group, metricsGVK.Version, metricsGVK.Kind),
+			TargetKeyFieldName:   "id",
+			TargetFinalFieldName: strings.Join(field, "."),
+		})
+	}
+	return result
+}
+
 // ClientGetter is a dynamic kubernetes client factory.
 type ClientGetter interface {
 	IsImpersonating() bool
@@ -353,6 +434,7 @@ type Store struct {
 	lock             sync.Mutex
 	columnSetter     SchemaColumnSetter
 	transformBuilder TransformBuilder
+	redaction        *redaction.Policy
 
 	watchers *Watchers
 }
@@ -361,6 +443,7 @@ type CacheFactoryInitializer func() (CacheFactory, error)
 
 type CacheFactory interface {
 	CacheFor(ctx context.Context, fields [][]string, externalUpdateInfo *sqltypes.ExternalGVKUpdates, selfUpdateInfo *sqltypes.ExternalGVKUpdates, transform cache.TransformFunc, client dynamic.ResourceInterface, gvk schema.GroupVersionKind, typeGuidance map[string]string, namespaced bool, watchable bool) (*factory.Cache, error)
+	SyncedCacheFor(gvk schema.GroupVersionKind) (*factory.Cache, bool)
 	DoneWithCache(*factory.Cache)
 	Stop(gvk schema.GroupVersionKind) error
 }
@@ -393,6 +476,12 @@ func NewProxyStore(ctx context.Context, c SchemaColumnSetter, clientGetter Clien
 	return store, nil
 }
 
+// SetRedaction sets the policy of the fields users can't filter or sort lists on unless they're allowed to reveal them,
+// as the redacted values could be guessed from the results
+func (s *Store) SetRedaction(policy *redaction.Policy) {
+	s.redaction = policy
+}
+
 // Reset locks the store, resets the underlying cache factory, and warm the namespace cache.
 func (s *Store) Reset(gvk schema.GroupVersionKind) error {
 	s.lock.Lock()
@@ -524,6 +613,104 @@ func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string
 	return s.byID(apiOp, schema, apiOp.Namespace, id)
 }
 
+// ByIDByPartitions looks up a single object by its ID. If the cache for the schema's GVK has already been synced, and
+// the request doesn't require a live read, the object is read from the cache, as long as it belongs to one of the
+// given partitions. Otherwise, or if the object can't be found in the cache, the Kubernetes API is called.
+func (s *Store) ByIDByPartitions(apiOp *types.APIRequest, schema *types.APISchema, id string, partitions []partition.Partition) (*unstructured.Unstructured, []types.Warning, error) {
+	opts := metav1.GetOptions{}
+	if err := decodeParams(apiOp, &opts); err != nil {
+		return nil, nil, err
+	}
+
+	if len(partitions) == 0 || requiresLiveRead(apiOp, schema, opts) {
+		return s.byID(apiOp, schema, apiOp.Namespace, id)
+	}
+
+	if obj, ok := s.byIDFromCache(schema, apiOp.Namespace, id, partitions); ok {
+		return obj, nil, nil
+	}
+	return s.byID(apiOp, schema, apiOp.Namespace, id)
+}
+
+// requiresLiveRead returns true if the object must be fetched from the Kubernetes API rather than from the cache
+func requiresLiveRead(apiOp *types.APIRequest, schema *types.APISchema, opts metav1.GetOptions) bool {
+	if consistent, _ := strconv.ParseBool(apiOp.Request.URL.Query().Get(consistentReadParam)); consistent {
+		return true
+	}
+	// Any resourceVersion other than "0" (meaning any version) requires the Kubernetes API to check it
+	if opts.ResourceVersion != "" && opts.ResourceVersion != "0" {
+		return true
+	}
+	// Access to these types is further restricted by the extension API server itself, so RBAC is not enough
+	return attributes.Group(schema) == "ext.cattle.io"
+}
+
+// byIDFromCache looks up a single object in the cache for the schema's GVK, if it was already synced. The returned
+// bool is false if the object couldn't be returned from the cache, in which case the caller should fall back to the
+// Kubernetes API.
+func (s *Store) byIDFromCache(schema *types.APISchema, namespace, id string, partitions []partition.Partition) (*unstructured.Unstructured, bool) {
+	gvk := attributes.GVK(schema)
+	inf, ok := s.cacheFactory.SyncedCacheFor(gvk)
+	if !ok {
+		return nil, false
+	}
+	defer s.cacheFactory.DoneWithCache(inf)
+
+	idNamespace, name := kv.RSplit(id, "/")
+	if idNamespace == "" {
+		idNamespace = namespace
+	}
+	if !attributes.Namespaced(schema) {
+		idNamespace = ""
+	}
+	if !partitionsInclude(partitions, idNamespace, name) {
+		return nil, false
+	}
+
+	key := name
+	if idNamespace != "" {
+		key = idNamespace + "/" + name
+	}
+	item, exists, err := inf.GetByKey(key)
+	if err != nil {
+		logrus.Debugf("failed to get %s %s from cache, falling back to the Kubernetes API: %v", gvk, key, err)
+		return nil, false
+	}
+	if !exists {
+		return nil, false
+	}
+	obj, ok := item.(*unstructured.Unstructured)
+	if !ok {
+		return nil, false
+	}
+
+	// The transform function stores the steve ID under "id", keeping the original value, if any, under "_id".
+	// Restore the object to the shape returned by the Kubernetes API.
+	if originalID, ok := obj.Object["_id"]; ok {
+		obj.Object["id"] = originalID
+		delete(obj.Object, "_id")
+	} else {
+		delete(obj.Object, "id")
+	}
+	return obj, true
+}
+
+// partitionsInclude returns true if any of the partitions grants access to the object with the given namespace and name
+func partitionsInclude(partitions []partition.Partition, namespace, name string) bool {
+	for _, p := range partitions {
+		if p.Passthrough {
+			return true
+		}
+		if p.Namespace != "" && p.Namespace != namespace {
+			continue
+		}
+		if p.All || p.Names.Has(name) {
+			return true
+		}
+	}
+	return false
+}
+
 func decodeParams(apiOp *types.APIRequest, target runtime.Object) error {
 	return paramCodec.DecodeParameters(apiOp.Request.URL.Query(), metav1.SchemeGroupVersion, target)
 }
@@ -879,7 +1066,11 @@ var typeGuidanceTable = map[schema.GroupVersionKind]map[string]string{
 		"status.requested.pods":        "INT",
 	},
 	schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Secret"}: {
-		"metadata.fields[2]": "INT", // name: Data
+		"metadata.fields[2]":            "INT", // name: Data
+		"metadata.certificate.notAfter": "INT",
+	},
+	certificates.CertificateGVK: {
+		"metadata.certificate.notAfter": "INT",
 	},
 	schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ServiceAccount"}: {
 		"metadata.fields[1]": "INT", // name: Secrets
@@ -887,6 +1078,22 @@ var typeGuidanceTable = map[schema.GroupVersionKind]map[string]string{
 	schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}: {
 		"metadata.fields[1]": "INT", // name: Data
 	},
+	podGVK: {
+		"metadata.usage.cpu":    "INT",
+		"metadata.usage.memory": "INT",
+	},
+	nodeGVK: {
+		"metadata.usage.cpu":    "INT",
+		"metadata.usage.memory": "INT",
+	},
+	usage.PodMetricsGVK: {
+		"metadata.usage.cpu":    "INT",
+		"metadata.usage.memory": "INT",
+	},
+	usage.NodeMetricsGVK: {
+		"metadata.usage.cpu":    "INT",
+		"metadata.usage.memory": "INT",
+	},
 }
 
 func getTypeGuidance(cols []common.ColumnDefinition, gvk schema.GroupVersionKind) map[string]string {
@@ -951,27 +1158,12 @@ func (s *Store) ListByPartitions(apiOp *types.APIRequest, apiSchema *types.APISc
 		return nil, 0, "", err
 	}
 
-	if gvk.Group == "ext.cattle.io" && (gvk.Kind == "Token" || gvk.Kind == "Kubeconfig") {
-		accessSet := accesscontrol.AccessSetFromAPIRequest(apiOp)
-		// See https://github.com/rancher/rancher/blob/7266e5e624f0d610c76ab0af33e30f5b72e11f61/pkg/ext/stores/tokens/tokens.go#L1186C2-L1195C3
-		// for similar code on how we determine if a user is admin
-		if accessSet == nil || !accessSet.Grants("list", schema.GroupResource{
-			Resource: "*",
-		}, "", "") {
-			user, ok := request.UserFrom(apiOp.Request.Context())
-			if !ok {
-				return nil, 0, "", apierror.NewAPIError(validation.MissingRequired, "failed to get user info from the request.Context object")
-			}
-			opts.Filters = append(opts.Filters, sqltypes.OrFilter{
-				Filters: []sqltypes.Filter{
-					{
-						Field:   []string{"metadata", "labels", "cattle.io/user-id"},
-						Matches: []string{user.GetName()},
-						Op:      sqltypes.Eq,
-					},
-				},
-			})
-		}
+	if err := s.checkRedactedFields(apiOp, apiSchema, &opts); err != nil {
+		return nil, 0, "", err
+	}
+
+	if err := restrictToUser(apiOp, gvk, &opts); err != nil {
+		return nil, 0, "", err
 	}
 
 	list, total, continueToken, err := inf.ListByOptions(apiOp.Context(), &opts, partitions, apiOp.Namespace)
@@ -988,6 +1180,112 @@ func (s *Store) ListByPartitions(apiOp *types.APIRequest, apiSchema *types.APISc
 	return list, total, continueToken, nil
 }
 
+// ListFieldValuesByPartitions returns the key and the value of an indexed field of each object belonging to any of the
+// specified partitions, filtered like a list. The values of list fields are joined with "|".
+func (s *Store) ListFieldValuesByPartitions(apiOp *types.APIRequest, apiSchema *types.APISchema, partitions []partition.Partition, field []string) ([][]string, error) {
+	ctx, cancel := context.WithCancel(apiOp.Context())
+	defer cancel()
+
+	inf, doneFn, err := s.cacheForWithDeps(ctx, apiOp, apiSchema)
+	if err != nil {
+		return nil, err
+	}
+	defer doneFn()
+
+	gvk := attributes.GVK(apiSchema)
+	opts, err := listprocessor.ParseQuery(apiOp, gvk.Kind)
+	if err != nil {
+		var apiError *apierror.APIError
+		if errors.As(err, &apiError) && apiError.Code.Status == http.StatusNoContent {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := s.checkRedactedFields(apiOp, apiSchema, &opts); err != nil {
+		return nil, err
+	}
+	if redacted := s.redactedField(apiOp, apiSchema, [][]string{field}); redacted != nil {
+		return nil, apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("cannot list redacted field %s", strings.Join(redacted, ".")))
+	}
+	if err := restrictToUser(apiOp, gvk, &opts); err != nil {
+		return nil, err
+	}
+
+	values, err := inf.ListFieldValues(apiOp.Context(), &opts, partitions, apiOp.Namespace, field)
+	if err != nil {
+		if errors.Is(err, informer.ErrInvalidColumn) {
+			return nil, apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+		}
+		return nil, fmt.Errorf("listfieldvalues %v: %w", gvk, err)
+	}
+	return values, nil
+}
+
+// restrictToUser filters the lists of tokens and kubeconfigs on the objects of the user, unless they're an admin
+func restrictToUser(apiOp *types.APIRequest, gvk schema.GroupVersionKind, opts *sqltypes.ListOptions) error {
+	if gvk.Group != "ext.cattle.io" || (gvk.Kind != "Token" && gvk.Kind != "Kubeconfig") {
+		return nil
+	}
+	accessSet := accesscontrol.AccessSetFromAPIRequest(apiOp)
+	// See https://github.com/rancher/rancher/blob/7266e5e624f0d610c76ab0af33e30f5b72e11f61/pkg/ext/stores/tokens/tokens.go#L1186C2-L1195C3
+	// for similar code on how we determine if a user is admin
+	if accessSet != nil && accessSet.Grants("list", schema.GroupResource{
+		Resource: "*",
+	}, "", "") {
+		return nil
+	}
+	user, ok := request.UserFrom(apiOp.Request.Context())
+	if !ok {
+		return apierror.NewAPIError(validation.MissingRequired, "failed to get user info from the request.Context object")
+	}
+	opts.Filters = append(opts.Filters, sqltypes.OrFilter{
+		Filters: []sqltypes.Filter{
+			{
+				Field:   []string{"metadata", "labels", "cattle.io/user-id"},
+				Matches: []string{user.GetName()},
+				Op:      sqltypes.Eq,
+			},
+		},
+	})
+	return nil
+}
+
+// checkRedactedFields returns an error if the list is filtered or sorted on a field redacted for the user
+func (s *Store) checkRedactedFields(apiOp *types.APIRequest, apiSchema *types.APISchema, opts *sqltypes.ListOptions) error {
+	var fields [][]string
+	for _, orFilter := range opts.Filters {
+		for _, filter := range orFilter.Filters {
+			fields = append(fields, filter.Field)
+		}
+	}
+	for _, sort := range opts.SortList.SortDirectives {
+		fields = append(fields, sort.Fields)
+	}
+	if redacted := s.redactedField(apiOp, apiSchema, fields); redacted != nil {
+		return apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("cannot filter or sort on redacted field %s", strings.Join(redacted, ".")))
+	}
+	return nil
+}
+
+// redactedField returns the first of the fields which is redacted for the user, or nil if none is
+func (s *Store) redactedField(apiOp *types.APIRequest, apiSchema *types.APISchema, fields [][]string) []string {
+	gvk := attributes.GVK(apiSchema)
+	if !s.redaction.Matches(gvk) {
+		return nil
+	}
+	accessSet := accesscontrol.AccessSetFromAPIRequest(apiOp)
+	if accessSet != nil && accessSet.Grants(redaction.RevealVerb, attributes.GVR(apiSchema).GroupResource(), apiOp.Namespace, "") {
+		return nil
+	}
+	for _, field := range fields {
+		if s.redaction.Overlaps(gvk, field) {
+			return field
+		}
+	}
+	return nil
+}
+
 // WatchByPartitions returns a channel of events for a list or resource belonging to any of the specified partitions
 func (s *Store) WatchByPartitions(apiOp *types.APIRequest, schema *types.APISchema, wr types.WatchRequest, partitions []partition.Partition) (chan watch.Event, error) {
 	ctx, cancel := context.WithCancel(apiOp.Context())
@@ -1071,6 +1369,17 @@ func (s *Store) cacheForWithDeps(ctx context.Context, apiOp *types.APIRequest, a
 	return inf, doneCache, nil
 }
 
+// Prewarm creates the informer for the given schema, and those it depends on, and waits for it to be synced. The
+// informer is kept running afterwards.
+func (s *Store) Prewarm(ctx context.Context, apiSchema *types.APISchema) error {
+	_, doneCache, err := s.cacheForWithDeps(ctx, nil, apiSchema)
+	if err != nil {
+		return err
+	}
+	doneCache()
+	return nil
+}
+
 func (s *Store) cacheFor(ctx context.Context, apiOp *types.APIRequest, apiSchema *types.APISchema) (*factory.Cache, error) {
 	// warnings from inside the informer are discarded
 	buffer := WarningBuffer{}
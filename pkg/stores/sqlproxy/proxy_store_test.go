@@ -15,6 +15,7 @@ import (
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/attributes"
 	"github.com/rancher/steve/pkg/client"
+	"github.com/rancher/steve/pkg/redaction"
 	"github.com/rancher/steve/pkg/resources/common"
 	"github.com/rancher/steve/pkg/schema/table"
 	"github.com/rancher/steve/pkg/sqlcache/informer"
@@ -37,6 +38,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/apiserver/pkg/authentication/user"
 	krequest "k8s.io/apiserver/pkg/endpoints/request"
@@ -786,6 +788,145 @@ func TestListByPartitionWithUserAccess(t *testing.T) {
 	}
 }
 
+func TestListFieldValuesByPartitions(t *testing.T) {
+	policy, err := redaction.NewPolicy([]redaction.Rule{{Kind: "Pod", Paths: []string{".spec.containers"}}})
+	assert.NoError(t, err)
+	field := []string{"spec", "containers", "image"}
+
+	tests := []struct {
+		name       string
+		redaction  *redaction.Policy
+		values     [][]string
+		listErr    error
+		wantValues [][]string
+		wantStatus int
+	}{
+		{
+			name:       "values of the objects",
+			values:     [][]string{{"default/web", "nginx:1.25|envoy:1.30"}, {"default/db", "postgres:16"}},
+			wantValues: [][]string{{"default/web", "nginx:1.25|envoy:1.30"}, {"default/db", "postgres:16"}},
+		},
+		{
+			name:       "field not indexed",
+			listErr:    fmt.Errorf("column is invalid [spec.containers.image]: %w", informer.ErrInvalidColumn),
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "redacted field",
+			redaction:  policy,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cg := NewMockClientGetter(gomock.NewController(t))
+			cf := NewMockCacheFactory(gomock.NewController(t))
+			ri := NewMockResourceInterface(gomock.NewController(t))
+			bloi := NewMockByOptionsLister(gomock.NewController(t))
+			tb := NewMockTransformBuilder(gomock.NewController(t))
+			c := &factory.Cache{
+				ByOptionsLister: &informer.Informer{ByOptionsLister: bloi},
+			}
+			s := &Store{
+				ctx:              context.Background(),
+				namespaceCache:   &factory.Cache{ByOptionsLister: bloi},
+				clientGetter:     cg,
+				cacheFactory:     cf,
+				transformBuilder: tb,
+				redaction:        test.redaction,
+			}
+			partitions := []partition.Partition{{All: true}}
+			apiOpSchemas := &types.APISchemas{}
+			accesscontrol.SetAccessSetAttribute(apiOpSchemas, &accesscontrol.AccessSet{})
+			req := &types.APIRequest{
+				Schemas: apiOpSchemas,
+				Request: &http.Request{
+					URL: &url.URL{},
+				},
+			}
+			schema := &types.APISchema{
+				Schema: &schemas.Schema{Attributes: map[string]interface{}{
+					"verbs": []string{"list", "watch"},
+				}},
+			}
+			attributes.SetGVK(schema, schema2.GroupVersionKind{Version: "v1", Kind: "Pod"})
+			attributes.SetResource(schema, "pods")
+			setupContext(req)
+			opts, err := listprocessor.ParseQuery(req, "Pod")
+			assert.Nil(t, err)
+
+			cg.EXPECT().TableAdminClient(req, schema, "", &WarningBuffer{}).Return(ri, nil)
+			cf.EXPECT().CacheFor(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(c, nil)
+			cf.EXPECT().DoneWithCache(c)
+			tb.EXPECT().GetTransformFunc(gomock.Any(), gomock.Any(), gomock.Any()).Return(func(obj interface{}) (interface{}, error) { return obj, nil })
+			if test.redaction == nil {
+				bloi.EXPECT().ListFieldValues(gomock.Cond(isDerivedContext), &opts, partitions, req.Namespace, field).Return(test.values, test.listErr)
+			}
+
+			values, err := s.ListFieldValuesByPartitions(req, schema, partitions, field)
+			if test.wantStatus != 0 {
+				assert.Error(t, err)
+				assert.Equal(t, test.wantStatus, err.(*apierror.APIError).Code.Status)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantValues, values)
+		})
+	}
+}
+
+func TestCheckRedactedFields(t *testing.T) {
+	policy, err := redaction.NewPolicy([]redaction.Rule{{Kind: "Secret", Paths: []string{".data"}}})
+	assert.NoError(t, err)
+	s := &Store{redaction: policy}
+
+	secretSchema := &types.APISchema{Schema: &schemas.Schema{Attributes: map[string]interface{}{}}}
+	attributes.SetGVK(secretSchema, schema2.GroupVersionKind{Version: "v1", Kind: "Secret"})
+	attributes.SetResource(secretSchema, "secrets")
+	podSchema := &types.APISchema{Schema: &schemas.Schema{Attributes: map[string]interface{}{}}}
+	attributes.SetGVK(podSchema, schema2.GroupVersionKind{Version: "v1", Kind: "Pod"})
+
+	filterOn := func(field ...string) *sqltypes.ListOptions {
+		return &sqltypes.ListOptions{Filters: []sqltypes.OrFilter{{Filters: []sqltypes.Filter{{Field: field, Matches: []string{"c2VjcmV0"}}}}}}
+	}
+	sortOn := func(field ...string) *sqltypes.ListOptions {
+		return &sqltypes.ListOptions{SortList: sqltypes.SortList{SortDirectives: []sqltypes.Sort{{Fields: field}}}}
+	}
+	newRequest := func(reveal bool) *types.APIRequest {
+		accessSet := &accesscontrol.AccessSet{}
+		if reveal {
+			accessSet.Add(redaction.RevealVerb, schema2.GroupResource{Resource: "secrets"}, accesscontrol.Access{Namespace: accesscontrol.All, ResourceName: accesscontrol.All})
+		}
+		apiOpSchemas := &types.APISchemas{}
+		accesscontrol.SetAccessSetAttribute(apiOpSchemas, accessSet)
+		return &types.APIRequest{Schemas: apiOpSchemas}
+	}
+
+	tests := []struct {
+		name    string
+		schema  *types.APISchema
+		opts    *sqltypes.ListOptions
+		reveal  bool
+		wantErr bool
+	}{
+		{name: "filter on redacted field", schema: secretSchema, opts: filterOn("data", "password"), wantErr: true},
+		{name: "sort on redacted field", schema: secretSchema, opts: sortOn("data"), wantErr: true},
+		{name: "filter on other field", schema: secretSchema, opts: filterOn("metadata", "name")},
+		{name: "filter on redacted field with reveal", schema: secretSchema, opts: filterOn("data", "password"), reveal: true},
+		{name: "type without rules", schema: podSchema, opts: filterOn("data", "password")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := s.checkRedactedFields(newRequest(test.reveal), test.schema, test.opts)
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestTableColsToCommonCols(t *testing.T) {
 	type testCase struct {
 		description string
@@ -1754,3 +1895,147 @@ func TestUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestByIDByPartitions(t *testing.T) {
+	gvk := schema2.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	newSchema := func() *types.APISchema {
+		s := &types.APISchema{
+			Schema: &schemas.Schema{
+				ID: "configmap",
+				Attributes: map[string]interface{}{
+					"namespaced": true,
+				},
+			},
+		}
+		attributes.SetGVK(s, gvk)
+		return s
+	}
+	newRequest := func(query string) *types.APIRequest {
+		req := &http.Request{
+			URL: &url.URL{RawQuery: query},
+		}
+		return &types.APIRequest{
+			Namespace: "ns1",
+			Schema:    newSchema(),
+			Request:   req.WithContext(context.Background()),
+		}
+	}
+	newObject := func() *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":      "cm1",
+				"namespace": "ns1",
+			},
+		}}
+	}
+	cachedObject := func() *unstructured.Unstructured {
+		obj := newObject()
+		obj.Object["id"] = "ns1/cm1"
+		return obj
+	}
+	allowed := []partition.Partition{
+		{
+			Namespace: "ns1",
+			Names:     sets.New("cm1"),
+		},
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		partitions []partition.Partition
+		setup      func(cg *MockClientGetter, cf *MockCacheFactory, bloi *MockByOptionsLister, ri *MockResourceInterface, c *factory.Cache)
+	}{
+		{
+			name:       "synced cache is used when the object is in the partitions",
+			partitions: allowed,
+			setup: func(cg *MockClientGetter, cf *MockCacheFactory, bloi *MockByOptionsLister, ri *MockResourceInterface, c *factory.Cache) {
+				cf.EXPECT().SyncedCacheFor(gvk).Return(c, true)
+				cf.EXPECT().DoneWithCache(c)
+				bloi.EXPECT().GetByKey("ns1/cm1").Return(cachedObject(), true, nil)
+			},
+		},
+		{
+			name:       "consistent read bypasses the cache",
+			query:      "consistentRead=true",
+			partitions: allowed,
+			setup: func(cg *MockClientGetter, cf *MockCacheFactory, bloi *MockByOptionsLister, ri *MockResourceInterface, c *factory.Cache) {
+				cg.EXPECT().TableClient(gomock.Any(), gomock.Any(), "ns1", gomock.Any()).Return(ri, nil)
+				ri.EXPECT().Get(gomock.Any(), "cm1", metav1.GetOptions{}).Return(newObject(), nil)
+			},
+		},
+		{
+			name:       "specific resourceVersion bypasses the cache",
+			query:      "resourceVersion=42",
+			partitions: allowed,
+			setup: func(cg *MockClientGetter, cf *MockCacheFactory, bloi *MockByOptionsLister, ri *MockResourceInterface, c *factory.Cache) {
+				cg.EXPECT().TableClient(gomock.Any(), gomock.Any(), "ns1", gomock.Any()).Return(ri, nil)
+				ri.EXPECT().Get(gomock.Any(), "cm1", metav1.GetOptions{ResourceVersion: "42"}).Return(newObject(), nil)
+			},
+		},
+		{
+			name:       "unsynced cache falls back to the Kubernetes API",
+			partitions: allowed,
+			setup: func(cg *MockClientGetter, cf *MockCacheFactory, bloi *MockByOptionsLister, ri *MockResourceInterface, c *factory.Cache) {
+				cf.EXPECT().SyncedCacheFor(gvk).Return(nil, false)
+				cg.EXPECT().TableClient(gomock.Any(), gomock.Any(), "ns1", gomock.Any()).Return(ri, nil)
+				ri.EXPECT().Get(gomock.Any(), "cm1", metav1.GetOptions{}).Return(newObject(), nil)
+			},
+		},
+		{
+			name:       "object missing from the cache falls back to the Kubernetes API",
+			partitions: allowed,
+			setup: func(cg *MockClientGetter, cf *MockCacheFactory, bloi *MockByOptionsLister, ri *MockResourceInterface, c *factory.Cache) {
+				cf.EXPECT().SyncedCacheFor(gvk).Return(c, true)
+				cf.EXPECT().DoneWithCache(c)
+				bloi.EXPECT().GetByKey("ns1/cm1").Return(nil, false, nil)
+				cg.EXPECT().TableClient(gomock.Any(), gomock.Any(), "ns1", gomock.Any()).Return(ri, nil)
+				ri.EXPECT().Get(gomock.Any(), "cm1", metav1.GetOptions{}).Return(newObject(), nil)
+			},
+		},
+		{
+			name: "object outside of the partitions is not read from the cache",
+			partitions: []partition.Partition{
+				{
+					Namespace: "ns2",
+					All:       true,
+				},
+			},
+			setup: func(cg *MockClientGetter, cf *MockCacheFactory, bloi *MockByOptionsLister, ri *MockResourceInterface, c *factory.Cache) {
+				cf.EXPECT().SyncedCacheFor(gvk).Return(c, true)
+				cf.EXPECT().DoneWithCache(c)
+				cg.EXPECT().TableClient(gomock.Any(), gomock.Any(), "ns1", gomock.Any()).Return(ri, nil)
+				ri.EXPECT().Get(gomock.Any(), "cm1", metav1.GetOptions{}).Return(newObject(), nil)
+			},
+		},
+		{
+			name: "no partitions always reads from the Kubernetes API",
+			setup: func(cg *MockClientGetter, cf *MockCacheFactory, bloi *MockByOptionsLister, ri *MockResourceInterface, c *factory.Cache) {
+				cg.EXPECT().TableClient(gomock.Any(), gomock.Any(), "ns1", gomock.Any()).Return(ri, nil)
+				ri.EXPECT().Get(gomock.Any(), "cm1", metav1.GetOptions{}).Return(newObject(), nil)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			cg := NewMockClientGetter(ctrl)
+			cf := NewMockCacheFactory(ctrl)
+			bloi := NewMockByOptionsLister(ctrl)
+			ri := NewMockResourceInterface(ctrl)
+			c := &factory.Cache{ByOptionsLister: bloi}
+			test.setup(cg, cf, bloi, ri, c)
+
+			s := &Store{
+				ctx:          context.Background(),
+				clientGetter: cg,
+				cacheFactory: cf,
+			}
+			obj, _, err := s.ByIDByPartitions(newRequest(test.query), newSchema(), "cm1", test.partitions)
+			assert.NoError(t, err)
+			assert.Equal(t, newObject(), obj)
+		})
+	}
+}
@@ -10,11 +10,13 @@ import (
 	"github.com/rancher/steve/pkg/clustercache"
 	"github.com/rancher/steve/pkg/schema"
 	"github.com/rancher/steve/pkg/schema/converter"
+	"github.com/rancher/wrangler/v3/pkg/kv"
 	wranglerSchemas "github.com/rancher/wrangler/v3/pkg/schemas"
 	"github.com/rancher/wrangler/v3/pkg/slice"
 	"github.com/rancher/wrangler/v3/pkg/summary"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/tools/cache"
@@ -201,6 +203,55 @@ func (s *SummaryCache) toRel(ns string, rel *summary.Relationship) Relationship
 	}, obj, s.schemas)
 }
 
+// Related returns the cached objects a relationship points to: the object it refers to by ID or, for relationships
+// using a selector, the objects matching it
+func (s *SummaryCache) Related(rel Relationship) []runtime.Object {
+	schemaID, id := rel.ToType, rel.ToID
+	if rel.FromType != "" {
+		schemaID, id = rel.FromType, rel.FromID
+	}
+	schema := s.schemas.Schema(schemaID)
+	if schema == nil {
+		return nil
+	}
+	gvk := attributes.GVK(schema)
+
+	if id != "" {
+		namespace, name := kv.RSplit(id, "/")
+		obj, ok, err := s.clusterCache.Get(gvk, namespace, name)
+		if err != nil || !ok {
+			return nil
+		}
+		if ro, ok := obj.(runtime.Object); ok {
+			return []runtime.Object{ro}
+		}
+		return nil
+	}
+
+	if rel.Selector == "" {
+		return nil
+	}
+	selector, err := labels.Parse(rel.Selector)
+	if err != nil {
+		return nil
+	}
+	var result []runtime.Object
+	for _, obj := range s.clusterCache.List(gvk) {
+		ro, ok := obj.(runtime.Object)
+		if !ok {
+			continue
+		}
+		m, err := meta.Accessor(ro)
+		if err != nil || (rel.ToNamespace != "" && m.GetNamespace() != rel.ToNamespace) {
+			continue
+		}
+		if selector.Matches(labels.Set(m.GetLabels())) {
+			result = append(result, ro)
+		}
+	}
+	return result
+}
+
 func addObject(rel Relationship, obj interface{}, schemas *schema.Collection) Relationship {
 	if obj == nil {
 		return rel
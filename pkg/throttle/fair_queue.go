@@ -0,0 +1,107 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+)
+
+var errQueueFull = errors.New("queue full")
+
+// fairQueue limits the number of requests served at once. Requests over the limit wait in the queue of their caller,
+// and the queues are served in turn, so that callers sending many requests don't delay the others.
+type fairQueue struct {
+	maxInflight int
+	queueLength int
+
+	lock     sync.Mutex
+	inflight int
+	queues   map[string][]chan struct{}
+	// turns lists the keys of the non-empty queues, in the order they're served
+	turns []string
+}
+
+func newFairQueue(maxInflight, queueLength int) *fairQueue {
+	return &fairQueue{
+		maxInflight: maxInflight,
+		queueLength: queueLength,
+		queues:      map[string][]chan struct{}{},
+	}
+}
+
+// acquire waits for a slot for a request of the key, until the context is done
+func (q *fairQueue) acquire(ctx context.Context, key string) error {
+	q.lock.Lock()
+	if q.inflight < q.maxInflight && len(q.turns) == 0 {
+		q.inflight++
+		q.lock.Unlock()
+		return nil
+	}
+	queue := q.queues[key]
+	if len(queue) >= q.queueLength {
+		q.lock.Unlock()
+		return errQueueFull
+	}
+	ready := make(chan struct{})
+	if len(queue) == 0 {
+		q.turns = append(q.turns, key)
+	}
+	q.queues[key] = append(queue, ready)
+	q.lock.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	select {
+	case <-ready:
+		// the slot was given right as the context was done
+		return nil
+	default:
+	}
+	q.remove(key, ready)
+	return ctx.Err()
+}
+
+// release frees the slot of a request, giving it to the next waiting request
+func (q *fairQueue) release() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if len(q.turns) == 0 {
+		q.inflight--
+		return
+	}
+	key := q.turns[0]
+	queue := q.queues[key]
+	ready := queue[0]
+	q.turns = q.turns[1:]
+	if len(queue) > 1 {
+		q.queues[key] = queue[1:]
+		// the caller waits for the others to be served before getting another turn
+		q.turns = append(q.turns, key)
+	} else {
+		delete(q.queues, key)
+	}
+	close(ready)
+}
+
+// remove takes a waiting request out of the queue of its key
+func (q *fairQueue) remove(key string, ready chan struct{}) {
+	queue := slices.DeleteFunc(q.queues[key], func(c chan struct{}) bool {
+		return c == ready
+	})
+	if len(queue) > 0 {
+		q.queues[key] = queue
+		return
+	}
+	delete(q.queues, key)
+	q.turns = slices.DeleteFunc(q.turns, func(k string) bool {
+		return k == key
+	})
+}
@@ -0,0 +1,83 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFairQueueTurns(t *testing.T) {
+	q := newFairQueue(1, 10)
+	ctx := context.Background()
+	require.NoError(t, q.acquire(ctx, "alice"))
+
+	served := make(chan string, 10)
+	var queuedKeys []string
+	wait := func(key string) {
+		go func() {
+			if err := q.acquire(ctx, key); err == nil {
+				served <- key
+			}
+		}()
+		// queue requests in a known order
+		assert.Eventually(t, func() bool {
+			q.lock.Lock()
+			defer q.lock.Unlock()
+			total := 0
+			for _, queue := range q.queues {
+				total += len(queue)
+			}
+			return total == len(queuedKeys)+1
+		}, time.Second, time.Millisecond)
+		queuedKeys = append(queuedKeys, key)
+	}
+	wait("alice")
+	wait("alice")
+	wait("alice")
+	wait("bob")
+	wait("carol")
+
+	var order []string
+	for range queuedKeys {
+		q.release()
+		order = append(order, <-served)
+	}
+	// alice's lists don't delay bob's and carol's
+	assert.Equal(t, []string{"alice", "bob", "carol", "alice", "alice"}, order)
+
+	q.release()
+	assert.Equal(t, 0, q.inflight)
+	assert.Empty(t, q.turns)
+	assert.Empty(t, q.queues)
+}
+
+func TestFairQueueLimits(t *testing.T) {
+	q := newFairQueue(1, 1)
+	require.NoError(t, q.acquire(context.Background(), "alice"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		errs <- q.acquire(ctx, "alice")
+	}()
+	assert.Eventually(t, func() bool {
+		q.lock.Lock()
+		defer q.lock.Unlock()
+		return len(q.queues["alice"]) == 1
+	}, time.Second, time.Millisecond)
+
+	assert.ErrorIs(t, q.acquire(context.Background(), "alice"), errQueueFull)
+
+	cancel()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	q.lock.Lock()
+	assert.Empty(t, q.queues)
+	assert.Empty(t, q.turns)
+	q.lock.Unlock()
+
+	q.release()
+	assert.Equal(t, 0, q.inflight)
+}
@@ -0,0 +1,256 @@
+/*
+Package throttle limits the rate of the requests of each user to the /v1 API, with separate budgets for lists, gets,
+mutations and watches, and queues concurrent lists fairly between users so that a single caller can't saturate the
+caches and the Kubernetes client.
+*/
+package throttle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/metrics"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+const (
+	// limitersCacheSize is the number of callers whose rate limiters are kept
+	limitersCacheSize = 10000
+	// limitersTTL is how long the rate limiters of an idle caller are kept. It's long enough for their buckets to
+	// refill.
+	limitersTTL = 10 * time.Minute
+
+	defaultListQueueLength  = 10
+	defaultListQueueTimeout = 30 * time.Second
+)
+
+// Category groups requests sharing the same budget
+type Category string
+
+const (
+	CategoryList   Category = "list"
+	CategoryGet    Category = "get"
+	CategoryMutate Category = "mutate"
+	CategoryWatch  Category = "watch"
+)
+
+// Budget is a token bucket: requests are allowed at QPS per second on average, with bursts of up to Burst requests.
+// A zero QPS doesn't limit the requests.
+type Budget struct {
+	QPS   float64
+	Burst int
+}
+
+// Budgets are the budgets of each category of requests
+type Budgets struct {
+	List   Budget
+	Get    Budget
+	Mutate Budget
+	Watch  Budget
+}
+
+func (b Budgets) of(category Category) Budget {
+	switch category {
+	case CategoryList:
+		return b.List
+	case CategoryGet:
+		return b.Get
+	case CategoryMutate:
+		return b.Mutate
+	case CategoryWatch:
+		return b.Watch
+	}
+	return Budget{}
+}
+
+// Options configures a Limiter
+type Options struct {
+	// Budgets apply to each user
+	Budgets Budgets
+	// GroupBudgets are shared by all the members of a group, instead of applying to each of them. Users in several of
+	// these groups use the budget of the first one in their groups.
+	GroupBudgets map[string]Budgets
+	// ExemptGroups aren't limited, eg. system:masters
+	ExemptGroups []string
+
+	// MaxConcurrentLists is the number of lists served at once, 0 for no limit. Lists over the limit are queued, and
+	// the queues of the callers are served in turn.
+	MaxConcurrentLists int
+	// ListQueueLength is the number of lists a caller can have waiting, 10 if zero
+	ListQueueLength int
+	// ListQueueTimeout is how long a list waits in the queue before being rejected, 30 seconds if zero
+	ListQueueTimeout time.Duration
+}
+
+// Reasons for rejecting a request
+const (
+	ReasonRate         = "rate"
+	ReasonQueueFull    = "queue_full"
+	ReasonQueueTimeout = "queue_timeout"
+)
+
+var reasonMessages = map[string]string{
+	ReasonRate:         "rate limit exceeded",
+	ReasonQueueFull:    "too many lists waiting",
+	ReasonQueueTimeout: "timed out waiting for other lists",
+}
+
+// LimitError rejects a request over the limits of its caller
+type LimitError struct {
+	Category Category
+	Reason   string
+	// RetryAfter is how long the caller should wait before retrying
+	RetryAfter time.Duration
+}
+
+func newLimitError(category Category, reason string, retryAfter time.Duration) *LimitError {
+	metrics.IncRateLimitRejected(string(category), reason)
+	return &LimitError{Category: category, Reason: reason, RetryAfter: retryAfter}
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("too many %s requests: %s", e.Category, reasonMessages[e.Reason])
+}
+
+// WriteResponse writes a 429 Too Many Requests response with a Retry-After header
+func (e *LimitError) WriteResponse(rw http.ResponseWriter) {
+	rw.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(e.RetryAfter.Seconds()))))
+	http.Error(rw, e.Error(), http.StatusTooManyRequests)
+}
+
+// Limiter admits the requests of callers within their budgets
+type Limiter struct {
+	opts Options
+
+	lock     sync.Mutex
+	limiters *cache.LRUExpireCache
+
+	lists *fairQueue
+}
+
+// New returns a Limiter for the given options
+func New(opts Options) (*Limiter, error) {
+	budgets := []Budgets{opts.Budgets}
+	for _, groupBudgets := range opts.GroupBudgets {
+		budgets = append(budgets, groupBudgets)
+	}
+	for _, b := range budgets {
+		for _, budget := range []Budget{b.List, b.Get, b.Mutate, b.Watch} {
+			if budget.QPS < 0 || budget.Burst < 0 || (budget.QPS > 0 && budget.Burst == 0) {
+				return nil, fmt.Errorf("invalid rate limit budget %+v, burst must be positive when QPS is", budget)
+			}
+		}
+	}
+	if opts.ListQueueLength <= 0 {
+		opts.ListQueueLength = defaultListQueueLength
+	}
+	if opts.ListQueueTimeout <= 0 {
+		opts.ListQueueTimeout = defaultListQueueTimeout
+	}
+
+	l := &Limiter{
+		opts:     opts,
+		limiters: cache.NewLRUExpireCache(limitersCacheSize),
+	}
+	if opts.MaxConcurrentLists > 0 {
+		l.lists = newFairQueue(opts.MaxConcurrentLists, opts.ListQueueLength)
+	}
+	return l, nil
+}
+
+// RequestCategory returns the category of a request parsed by the handler
+func RequestCategory(apiOp *types.APIRequest) Category {
+	req := apiOp.Request
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		if strings.EqualFold(req.Header.Get("Upgrade"), "websocket") || req.URL.Query().Get("watch") == "true" {
+			return CategoryWatch
+		}
+		if apiOp.Name == "" {
+			return CategoryList
+		}
+		return CategoryGet
+	}
+	return CategoryMutate
+}
+
+// Admit returns whether a request of the user in the given category can be served now, waiting for its turn if it's a
+// list. The release function must be called once the request is served. A *LimitError is returned if the request is
+// rejected. A nil Limiter admits all requests.
+func (l *Limiter) Admit(ctx context.Context, info user.Info, category Category) (release func(), err error) {
+	if l == nil || l.exempt(info) {
+		return func() {}, nil
+	}
+
+	key, budgets := l.keyAndBudgets(info)
+	if limiter := l.limiter(key, category, budgets.of(category)); limiter != nil {
+		now := time.Now()
+		reservation := limiter.ReserveN(now, 1)
+		if delay := reservation.DelayFrom(now); delay > 0 {
+			reservation.CancelAt(now)
+			return nil, newLimitError(category, ReasonRate, delay)
+		}
+	}
+
+	if category != CategoryList || l.lists == nil {
+		return func() {}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, l.opts.ListQueueTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := l.lists.acquire(ctx, key); err != nil {
+		reason := ReasonQueueTimeout
+		if errors.Is(err, errQueueFull) {
+			reason = ReasonQueueFull
+		}
+		return nil, newLimitError(category, reason, time.Second)
+	}
+	metrics.RecordRateLimitListQueueTime(float64(time.Since(start).Milliseconds()))
+	return l.lists.release, nil
+}
+
+func (l *Limiter) exempt(info user.Info) bool {
+	for _, group := range info.GetGroups() {
+		if slices.Contains(l.opts.ExemptGroups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyAndBudgets returns the key identifying the budgets of a user: the user, or the group whose budgets they share
+func (l *Limiter) keyAndBudgets(info user.Info) (string, Budgets) {
+	for _, group := range info.GetGroups() {
+		if budgets, ok := l.opts.GroupBudgets[group]; ok {
+			return "group:" + group, budgets
+		}
+	}
+	return "user:" + info.GetName(), l.opts.Budgets
+}
+
+// limiter returns the rate limiter of a key for a category, nil if it's not limited
+func (l *Limiter) limiter(key string, category Category, budget Budget) *rate.Limiter {
+	if budget.QPS == 0 {
+		return nil
+	}
+	cacheKey := key + "/" + string(category)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if limiter, ok := l.limiters.Get(cacheKey); ok {
+		return limiter.(*rate.Limiter)
+	}
+	limiter := rate.NewLimiter(rate.Limit(budget.QPS), budget.Burst)
+	l.limiters.Add(cacheKey, limiter, limitersTTL)
+	return limiter
+}
@@ -0,0 +1,138 @@
+package throttle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func TestRequestCategory(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		header  http.Header
+		apiName string
+		want    Category
+	}{
+		{name: "list", method: http.MethodGet, url: "/v1/pods?pagesize=1000", want: CategoryList},
+		{name: "get", method: http.MethodGet, url: "/v1/pods/default/web", apiName: "web", want: CategoryGet},
+		{name: "watch", method: http.MethodGet, url: "/v1/pods?watch=true", want: CategoryWatch},
+		{name: "subscribe", method: http.MethodGet, url: "/v1/subscribe", header: http.Header{"Upgrade": {"websocket"}}, want: CategoryWatch},
+		{name: "create", method: http.MethodPost, url: "/v1/pods", want: CategoryMutate},
+		{name: "delete", method: http.MethodDelete, url: "/v1/pods/default/web", apiName: "web", want: CategoryMutate},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.url, nil)
+			for k, v := range test.header {
+				req.Header[k] = v
+			}
+			assert.Equal(t, test.want, RequestCategory(&types.APIRequest{Request: req, Name: test.apiName}))
+		})
+	}
+}
+
+func TestLimiterBudgets(t *testing.T) {
+	limiter, err := New(Options{
+		Budgets: Budgets{
+			List:   Budget{QPS: 0.001, Burst: 2},
+			Mutate: Budget{QPS: 0.001, Burst: 1},
+		},
+		GroupBudgets: map[string]Budgets{
+			"ci": {List: Budget{QPS: 0.001, Burst: 1}},
+		},
+		ExemptGroups: []string{"system:masters"},
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+	alice := &user.DefaultInfo{Name: "alice"}
+	bob := &user.DefaultInfo{Name: "bob"}
+	admin := &user.DefaultInfo{Name: "admin", Groups: []string{"system:masters"}}
+	bot1 := &user.DefaultInfo{Name: "bot1", Groups: []string{"ci"}}
+	bot2 := &user.DefaultInfo{Name: "bot2", Groups: []string{"ci"}}
+
+	admit := func(info user.Info, category Category) error {
+		release, err := limiter.Admit(ctx, info, category)
+		if err == nil {
+			release()
+		}
+		return err
+	}
+
+	assert.NoError(t, admit(alice, CategoryList))
+	assert.NoError(t, admit(alice, CategoryList))
+	err = admit(alice, CategoryList)
+	require.Error(t, err)
+	limitErr, ok := err.(*LimitError)
+	require.True(t, ok)
+	assert.Equal(t, CategoryList, limitErr.Category)
+	assert.Equal(t, ReasonRate, limitErr.Reason)
+	assert.Greater(t, limitErr.RetryAfter, time.Minute)
+
+	// the budgets of other users and categories are separate, gets aren't limited
+	assert.NoError(t, admit(bob, CategoryList))
+	assert.NoError(t, admit(alice, CategoryMutate))
+	assert.Error(t, admit(alice, CategoryMutate))
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, admit(alice, CategoryGet))
+		assert.NoError(t, admit(admin, CategoryList))
+	}
+
+	// members of a group share its budget
+	assert.NoError(t, admit(bot1, CategoryList))
+	assert.Error(t, admit(bot2, CategoryList))
+}
+
+func TestLimiterListQueue(t *testing.T) {
+	limiter, err := New(Options{MaxConcurrentLists: 1, ListQueueLength: 1, ListQueueTimeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+	ctx := context.Background()
+	alice := &user.DefaultInfo{Name: "alice"}
+
+	release, err := limiter.Admit(ctx, alice, CategoryList)
+	require.NoError(t, err)
+
+	// gets don't wait for lists
+	releaseGet, err := limiter.Admit(ctx, alice, CategoryGet)
+	require.NoError(t, err)
+	releaseGet()
+
+	_, err = limiter.Admit(ctx, alice, CategoryList)
+	require.Error(t, err)
+	assert.Equal(t, ReasonQueueTimeout, err.(*LimitError).Reason)
+
+	release()
+	release, err = limiter.Admit(ctx, alice, CategoryList)
+	require.NoError(t, err)
+	release()
+}
+
+func TestLimitErrorWriteResponse(t *testing.T) {
+	rw := httptest.NewRecorder()
+	(&LimitError{Category: CategoryList, Reason: ReasonRate, RetryAfter: 1500 * time.Millisecond}).WriteResponse(rw)
+	assert.Equal(t, http.StatusTooManyRequests, rw.Code)
+	assert.Equal(t, "2", rw.Header().Get("Retry-After"))
+	assert.Contains(t, rw.Body.String(), "too many list requests: rate limit exceeded")
+}
+
+func TestNewInvalidBudget(t *testing.T) {
+	_, err := New(Options{Budgets: Budgets{Get: Budget{QPS: 10}}})
+	assert.Error(t, err)
+	_, err = New(Options{GroupBudgets: map[string]Budgets{"ci": {Watch: Budget{QPS: -1, Burst: 1}}}})
+	assert.Error(t, err)
+}
+
+func TestNilLimiter(t *testing.T) {
+	var limiter *Limiter
+	release, err := limiter.Admit(context.Background(), &user.DefaultInfo{Name: "alice"}, CategoryList)
+	require.NoError(t, err)
+	release()
+}